@@ -1,7 +1,13 @@
 package bodylimit
 
 import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"net"
 	"net/http"
+	"strings"
 )
 
 // Option is body limit option.
@@ -11,6 +17,21 @@ type Option func(*options)
 type options struct {
 	// Limit is the maximum allowed size for a request body in bytes
 	limit int64
+
+	// LimitFunc resolves the limit for each request. A value <= 0 disables
+	// the limit for that request.
+	limitFunc func(r *http.Request) int64
+
+	// ErrorHandler is called when the request body exceeds the limit
+	errorHandler func(w http.ResponseWriter, r *http.Request)
+
+	// Skipper exempts requests from the limit
+	skipper func(r *http.Request) bool
+
+	// Strict checks the unread remainder of the body before the response is
+	// written, so oversized bodies get a 413 even if the handler stops early
+	// Default: false
+	strict bool
 }
 
 // WithLimit sets the body size limit
@@ -20,6 +41,170 @@ func WithLimit(limit int64) Option {
 	}
 }
 
+// WithLimitFunc sets a function that resolves the body size limit per request
+func WithLimitFunc(fn func(r *http.Request) int64) Option {
+	return func(o *options) {
+		o.limitFunc = fn
+	}
+}
+
+// WithErrorHandler sets the handler called when the request body exceeds the limit
+func WithErrorHandler(handler func(w http.ResponseWriter, r *http.Request)) Option {
+	return func(o *options) {
+		o.errorHandler = handler
+	}
+}
+
+// WithSkipper sets a function to skip the middleware for a request
+func WithSkipper(f func(r *http.Request) bool) Option {
+	return func(o *options) {
+		o.skipper = f
+	}
+}
+
+// WithStrict sets whether an oversized body always results in a 413. In strict
+// mode the middleware reads whatever the handler left unread, up to the limit,
+// before the first response write and after the handler returns. The remainder
+// is buffered, so handlers that read only part of the body can still read the
+// rest later; oversized bodies are rejected even if the handler never reads past
+// the limit itself.
+func WithStrict(strict bool) Option {
+	return func(o *options) {
+		o.strict = strict
+	}
+}
+
+// SkipBodilessMethods is a skipper for methods that carry no meaningful body:
+// GET, HEAD, DELETE, OPTIONS and TRACE
+func SkipBodilessMethods(r *http.Request) bool {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodDelete, http.MethodOptions, http.MethodTrace:
+		return true
+	}
+	return false
+}
+
+// defaultErrorHandler writes a 413 JSON error response
+func defaultErrorHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusRequestEntityTooLarge)
+	w.Write([]byte(`{"code":413,"message":"request entity too large"}`))
+}
+
+// limitedBody reports when the wrapped MaxBytesReader hits the limit
+type limitedBody struct {
+	io.ReadCloser
+	onExceeded func()
+}
+
+// Read implements io.Reader
+func (b *limitedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		b.onExceeded()
+	}
+	return n, err
+}
+
+// check reads the unread remainder of the body so the limit is enforced even
+// if the handler stopped reading early. The remainder is buffered and served
+// to any later reads.
+func (b *limitedBody) check() {
+	rest, err := io.ReadAll(b)
+	if err != nil {
+		return
+	}
+	b.ReadCloser = struct {
+		io.Reader
+		io.Closer
+	}{bytes.NewReader(rest), b.ReadCloser}
+}
+
+// limitWriter discards the handler's response once the error handler has
+// responded to an oversized body
+type limitWriter struct {
+	http.ResponseWriter
+	wroteHeader bool
+	rejected    bool
+
+	// body is checked before the first write in strict mode
+	body *limitedBody
+}
+
+// checkBody enforces the limit on the unread body before the response starts
+func (w *limitWriter) checkBody() {
+	if w.body == nil {
+		return
+	}
+	body := w.body
+	w.body = nil
+	body.check()
+}
+
+// Header implements http.ResponseWriter
+func (w *limitWriter) Header() http.Header {
+	if w.rejected {
+		// Keep the handler from altering the error response headers
+		return http.Header{}
+	}
+	return w.ResponseWriter.Header()
+}
+
+// WriteHeader implements http.ResponseWriter
+func (w *limitWriter) WriteHeader(code int) {
+	w.checkBody()
+	if w.rejected {
+		return
+	}
+	w.wroteHeader = true
+	w.ResponseWriter.WriteHeader(code)
+}
+
+// Write implements http.ResponseWriter
+func (w *limitWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.checkBody()
+	}
+	if w.rejected {
+		return len(b), nil
+	}
+	w.wroteHeader = true
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush implements http.Flusher
+func (w *limitWriter) Flush() {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.rejected {
+		return
+	}
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack implements http.Hijacker
+func (w *limitWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if w.rejected {
+		return nil, nil, errors.New("bodylimit: response already written")
+	}
+	h, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("bodylimit: underlying ResponseWriter does not implement http.Hijacker")
+	}
+	// The connection is handed over, so the middleware can't respond anymore
+	w.wroteHeader = true
+	return h.Hijack()
+}
+
+// Unwrap returns the underlying http.ResponseWriter
+func (w *limitWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
 // New returns a BodyLimit middleware with the specified limit
 func New(limit int64, opts ...Option) func(http.Handler) http.Handler {
 	o := &options{
@@ -30,16 +215,66 @@ func New(limit int64, opts ...Option) func(http.Handler) http.Handler {
 		opt(o)
 	}
 
-	if o.limit <= 0 {
-		panic("body limit must be greater than 0")
+	if o.limitFunc == nil {
+		if o.limit <= 0 {
+			panic("body limit must be greater than 0")
+		}
+		limit := o.limit
+		o.limitFunc = func(*http.Request) int64 { return limit }
+	}
+	if o.errorHandler == nil {
+		o.errorHandler = defaultErrorHandler
 	}
 
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			// Limit request body size
-			r.Body = http.MaxBytesReader(w, r.Body, o.limit)
+			if o.skipper != nil && o.skipper(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			limit := o.limitFunc(r)
+			if limit <= 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			// Reject requests that declare an oversized body without
+			// reading any of it. With "Expect: 100-continue" the server only
+			// sends "100 Continue" once the body is read, so responding here
+			// saves the upload entirely; the connection is closed since the
+			// unsent body can't be skipped.
+			if r.ContentLength > limit {
+				if strings.EqualFold(r.Header.Get("Expect"), "100-continue") {
+					w.Header().Set("Connection", "close")
+				}
+				o.errorHandler(w, r)
+				return
+			}
+
+			// Limit request body size and respond from the middleware once
+			// the limit is hit, unless the handler has already responded
+			lw := &limitWriter{ResponseWriter: w}
+			body := &limitedBody{
+				ReadCloser: http.MaxBytesReader(w, r.Body, limit),
+				onExceeded: func() {
+					if lw.rejected || lw.wroteHeader {
+						return
+					}
+					lw.rejected = true
+					o.errorHandler(w, r)
+				},
+			}
+			r.Body = body
+			if o.strict {
+				lw.body = body
+			}
+
+			next.ServeHTTP(lw, r)
 
-			next.ServeHTTP(w, r)
+			// The handler may return without writing or reading the whole
+			// body; check the remainder so an oversized body still gets a 413
+			lw.checkBody()
 		})
 	}
 }
@@ -60,8 +60,8 @@ func TestBodyLimit(t *testing.T) {
 
 		handler.ServeHTTP(rr, req)
 
-		if rr.Code != http.StatusBadRequest {
-			t.Errorf("Expected status 400, got %d", rr.Code)
+		if rr.Code != http.StatusRequestEntityTooLarge {
+			t.Errorf("Expected status 413, got %d", rr.Code)
 		}
 	})
 }
@@ -86,8 +86,8 @@ func TestBodyLimitLargeBody(t *testing.T) {
 
 	handler.ServeHTTP(rr, req)
 
-	if rr.Code != http.StatusBadRequest {
-		t.Errorf("Expected status 400 for large body, got %d", rr.Code)
+	if rr.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("Expected status 413 for large body, got %d", rr.Code)
 	}
 }
 
@@ -126,9 +126,9 @@ func TestBodyLimitDifferentLimits(t *testing.T) {
 	}{
 		{"1KB limit, 500B body", 1024, 500, http.StatusOK},
 		{"1KB limit, 1KB body", 1024, 1024, http.StatusOK},
-		{"1KB limit, 2KB body", 1024, 2048, http.StatusBadRequest},
+		{"1KB limit, 2KB body", 1024, 2048, http.StatusRequestEntityTooLarge},
 		{"10MB limit, 5MB body", 10 * 1024 * 1024, 5 * 1024 * 1024, http.StatusOK},
-		{"10MB limit, 15MB body", 10 * 1024 * 1024, 15 * 1024 * 1024, http.StatusBadRequest},
+		{"10MB limit, 15MB body", 10 * 1024 * 1024, 15 * 1024 * 1024, http.StatusRequestEntityTooLarge},
 	}
 
 	for _, tt := range tests {
@@ -218,6 +218,7 @@ func TestBodyLimitMultipleReads(t *testing.T) {
 
 	body := strings.Repeat("a", 150) // Over limit
 	req := httptest.NewRequest("POST", "/test", strings.NewReader(body))
+	req.ContentLength = -1 // Unknown length, so the body is limited while streaming
 	rr := httptest.NewRecorder()
 
 	handler.ServeHTTP(rr, req)
@@ -227,3 +228,366 @@ func TestBodyLimitMultipleReads(t *testing.T) {
 		t.Errorf("Expected status 200, got %d", rr.Code)
 	}
 }
+
+// failingReader fails the test if the body is read
+type failingReader struct {
+	t *testing.T
+}
+
+func (f failingReader) Read(p []byte) (int, error) {
+	f.t.Error("Body should not be read")
+	return 0, io.EOF
+}
+
+func TestBodyLimitExpectContinue(t *testing.T) {
+	limit := int64(100)
+	middleware := New(limit)
+
+	handler := middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("Handler should not be called")
+	}))
+
+	req := httptest.NewRequest("POST", "/test", failingReader{t})
+	req.ContentLength = 10 * 1024
+	req.Header.Set("Expect", "100-continue")
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("Expected status 413, got %d", rr.Code)
+	}
+	if rr.Body.String() != `{"code":413,"message":"request entity too large"}` {
+		t.Errorf("Expected the default JSON error, got %q", rr.Body.String())
+	}
+	if rr.Header().Get("Connection") != "close" {
+		t.Errorf("Expected Connection: close, got %q", rr.Header().Get("Connection"))
+	}
+
+	// A custom error handler responds here too
+	handler = New(limit, WithErrorHandler(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	}))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("Handler should not be called")
+	}))
+	rr = httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusTeapot {
+		t.Errorf("Expected the error handler's status 418, got %d", rr.Code)
+	}
+}
+
+func TestBodyLimitExpectContinueWithinLimit(t *testing.T) {
+	limit := int64(100)
+	middleware := New(limit)
+
+	handler := middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.Write(body)
+	}))
+
+	body := strings.Repeat("a", 50)
+	req := httptest.NewRequest("POST", "/test", strings.NewReader(body))
+	req.Header.Set("Expect", "100-continue")
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", rr.Code)
+	}
+}
+
+func TestBodyLimitWithLimitFunc(t *testing.T) {
+	middleware := New(0, WithLimitFunc(func(r *http.Request) int64 {
+		switch {
+		case r.URL.Path == "/upload":
+			return 1024
+		case r.URL.Path == "/unlimited":
+			return 0
+		default:
+			return 64
+		}
+	}))
+
+	handler := middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.Write(body)
+	}))
+
+	tests := []struct {
+		name           string
+		path           string
+		size           int
+		expectedStatus int
+	}{
+		{"Default under limit", "/api", 50, http.StatusOK},
+		{"Default over limit", "/api", 100, http.StatusRequestEntityTooLarge},
+		{"Upload under limit", "/upload", 1000, http.StatusOK},
+		{"Upload over limit", "/upload", 2000, http.StatusRequestEntityTooLarge},
+		{"Unlimited", "/unlimited", 10 * 1024, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", tt.path, strings.NewReader(strings.Repeat("a", tt.size)))
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d", tt.expectedStatus, rr.Code)
+			}
+		})
+	}
+}
+
+func TestBodyLimitDefaultErrorResponse(t *testing.T) {
+	middleware := New(100)
+
+	handler := middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := io.ReadAll(r.Body); err != nil {
+			// Ignored once the middleware has responded
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest("POST", "/test", strings.NewReader(strings.Repeat("a", 150)))
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("Expected status 413, got %d", rr.Code)
+	}
+
+	if rr.Header().Get("Content-Type") != "application/json" {
+		t.Errorf("Expected Content-Type application/json, got %s", rr.Header().Get("Content-Type"))
+	}
+
+	expected := `{"code":413,"message":"request entity too large"}`
+	if rr.Body.String() != expected {
+		t.Errorf("Expected body %s, got %s", expected, rr.Body.String())
+	}
+}
+
+func TestBodyLimitWithErrorHandler(t *testing.T) {
+	called := false
+	middleware := New(100, WithErrorHandler(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		w.WriteHeader(http.StatusTeapot)
+	}))
+
+	handler := middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest("POST", "/test", strings.NewReader(strings.Repeat("a", 150)))
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	if !called {
+		t.Error("Expected custom error handler to be called")
+	}
+
+	if rr.Code != http.StatusTeapot {
+		t.Errorf("Expected status 418, got %d", rr.Code)
+	}
+}
+
+func TestBodyLimitContentLengthEarlyRejection(t *testing.T) {
+	middleware := New(100)
+
+	handler := middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("Handler should not be called")
+	}))
+
+	// Declared length exceeds the limit while the actual body is empty
+	req := httptest.NewRequest("POST", "/test", strings.NewReader(""))
+	req.ContentLength = 1 << 30
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("Expected status 413, got %d", rr.Code)
+	}
+
+	expected := `{"code":413,"message":"request entity too large"}`
+	if rr.Body.String() != expected {
+		t.Errorf("Expected body %s, got %s", expected, rr.Body.String())
+	}
+}
+
+func TestBodyLimitUnknownContentLength(t *testing.T) {
+	middleware := New(100)
+
+	handler := middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	// Chunked bodies are still enforced while streaming
+	req := httptest.NewRequest("POST", "/test", io.NopCloser(strings.NewReader(strings.Repeat("a", 150))))
+	req.ContentLength = -1
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("Expected status 413, got %d", rr.Code)
+	}
+}
+
+func TestBodyLimitWithSkipper(t *testing.T) {
+	middleware := New(100, WithSkipper(SkipBodilessMethods))
+
+	handler := middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := io.ReadAll(r.Body); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		method         string
+		expectedStatus int
+	}{
+		{"GET", http.StatusOK},
+		{"HEAD", http.StatusOK},
+		{"DELETE", http.StatusOK},
+		{"POST", http.StatusRequestEntityTooLarge},
+		{"PUT", http.StatusRequestEntityTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/test", strings.NewReader(strings.Repeat("a", 1024)))
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d", tt.expectedStatus, rr.Code)
+			}
+		})
+	}
+}
+
+func TestBodyLimitPartialRead(t *testing.T) {
+	partial := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		buf := make([]byte, 10)
+		io.ReadFull(r.Body, buf)
+		w.WriteHeader(http.StatusOK)
+		w.Write(buf)
+	})
+
+	tests := []struct {
+		name   string
+		strict bool
+		size   int
+		code   int
+	}{
+		{"lenient over limit", false, 150, http.StatusOK},
+		{"strict over limit", true, 150, http.StatusRequestEntityTooLarge},
+		{"strict within limit", true, 50, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := New(100, WithStrict(tt.strict))(partial)
+
+			req := httptest.NewRequest("POST", "/test", io.NopCloser(strings.NewReader(strings.Repeat("a", tt.size))))
+			req.ContentLength = -1
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != tt.code {
+				t.Errorf("Expected status %d, got %d", tt.code, rr.Code)
+			}
+		})
+	}
+}
+
+func TestBodyLimitStrictNoWrite(t *testing.T) {
+	handler := New(100, WithStrict(true))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	req := httptest.NewRequest("POST", "/test", io.NopCloser(strings.NewReader(strings.Repeat("a", 150))))
+	req.ContentLength = -1
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("Expected status 413, got %d", rr.Code)
+	}
+}
+
+func TestBodyLimitStrictRemainderReadable(t *testing.T) {
+	var body []byte
+	handler := New(100, WithStrict(true))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		head := make([]byte, 10)
+		io.ReadFull(r.Body, head)
+		w.WriteHeader(http.StatusOK)
+		rest, _ := io.ReadAll(r.Body)
+		body = append(head, rest...)
+	}))
+
+	req := httptest.NewRequest("POST", "/test", io.NopCloser(strings.NewReader(strings.Repeat("a", 50))))
+	req.ContentLength = -1
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", rr.Code)
+	}
+	if len(body) != 50 {
+		t.Errorf("Expected 50 bytes read, got %d", len(body))
+	}
+}
+
+func TestBodyLimitFlush(t *testing.T) {
+	middleware := New(100)
+
+	handler := middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := w.(http.Hijacker); !ok {
+			t.Error("Expected ResponseWriter to implement http.Hijacker")
+		}
+		f, ok := w.(http.Flusher)
+		if !ok {
+			t.Fatal("Expected ResponseWriter to implement http.Flusher")
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte("data: hello\n\n"))
+		f.Flush()
+	}))
+
+	req := httptest.NewRequest("POST", "/events", strings.NewReader("small"))
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	if !rr.Flushed {
+		t.Error("Expected response to be flushed")
+	}
+	if rr.Body.String() != "data: hello\n\n" {
+		t.Errorf("Expected streamed body, got %q", rr.Body.String())
+	}
+}
@@ -0,0 +1,143 @@
+package budget
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync/atomic"
+)
+
+var ErrBudgetExceeded = errors.New("request budget exceeded")
+
+// Option is budget option.
+type Option func(*options)
+
+// options defines the configuration for budget middleware
+type options struct {
+	// Budget is the number of units a request may charge
+	// Default: 1000
+	budget int64
+
+	// BudgetFunc resolves the budget per request, e.g. per tenant
+	// Optional. Takes precedence over Budget when set
+	budgetFunc func(*http.Request) int64
+
+	// StatusCode is the status written when a request exceeds its budget
+	// Default: 503 Service Unavailable
+	statusCode int
+}
+
+// WithBudget sets the number of units each request may charge
+func WithBudget(units int64) Option {
+	return func(o *options) {
+		o.budget = units
+	}
+}
+
+// WithBudgetFunc sets a function resolving the budget per request
+func WithBudgetFunc(f func(r *http.Request) int64) Option {
+	return func(o *options) {
+		o.budgetFunc = f
+	}
+}
+
+// WithStatusCode sets the status written when the budget is exceeded,
+// typically 429 or 503
+func WithStatusCode(code int) Option {
+	return func(o *options) {
+		o.statusCode = code
+	}
+}
+
+// budget tracks the units charged by a request
+type budget struct {
+	limit  int64
+	used   atomic.Int64
+	cancel context.CancelCauseFunc
+}
+
+// contextKey is the type used for context keys
+type contextKey struct{}
+
+// Charge records units of work against the request budget stored in ctx.
+// Once the budget is exceeded it returns ErrBudgetExceeded and cancels the
+// request context, so the handler and any work it spawned should stop.
+// Charge is safe for concurrent use and is a no-op without a budget.
+func Charge(ctx context.Context, units int64) error {
+	b, ok := ctx.Value(contextKey{}).(*budget)
+	if !ok {
+		return nil
+	}
+
+	if b.used.Add(units) > b.limit {
+		b.cancel(ErrBudgetExceeded)
+		return ErrBudgetExceeded
+	}
+	return nil
+}
+
+// Remaining returns the units left in the request budget stored in ctx
+func Remaining(ctx context.Context) (int64, bool) {
+	b, ok := ctx.Value(contextKey{}).(*budget)
+	if !ok {
+		return 0, false
+	}
+	return b.limit - b.used.Load(), true
+}
+
+// responseWriter tracks whether the handler wrote a response
+type responseWriter struct {
+	http.ResponseWriter
+	wroteHeader bool
+}
+
+// WriteHeader implements http.ResponseWriter
+func (w *responseWriter) WriteHeader(code int) {
+	w.wroteHeader = true
+	w.ResponseWriter.WriteHeader(code)
+}
+
+// Write implements http.ResponseWriter
+func (w *responseWriter) Write(b []byte) (int, error) {
+	w.wroteHeader = true
+	return w.ResponseWriter.Write(b)
+}
+
+// New returns a middleware giving each request a budget of work units that
+// handlers charge cooperatively with Charge. Requests exceeding the budget
+// have their context canceled and, if the handler has not written a
+// response yet, get the configured error status.
+func New(opts ...Option) func(http.Handler) http.Handler {
+	o := &options{
+		budget:     1000,
+		statusCode: http.StatusServiceUnavailable,
+	}
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			limit := o.budget
+			if o.budgetFunc != nil {
+				limit = o.budgetFunc(r)
+			}
+
+			ctx, cancel := context.WithCancelCause(r.Context())
+			defer cancel(nil)
+
+			b := &budget{limit: limit, cancel: cancel}
+			ctx = context.WithValue(ctx, contextKey{}, b)
+
+			rw := &responseWriter{ResponseWriter: w}
+			next.ServeHTTP(rw, r.WithContext(ctx))
+
+			if b.used.Load() > b.limit && !rw.wroteHeader {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(o.statusCode)
+				w.Write([]byte(`{"error":"` + ErrBudgetExceeded.Error() + `"}`))
+			}
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package budget
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestBudgetExceeded(t *testing.T) {
+	var ctxErr error
+
+	handler := New(WithBudget(10))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for i := 0; i < 5; i++ {
+			if err := Charge(r.Context(), 3); err != nil {
+				ctxErr = context.Cause(r.Context())
+				return
+			}
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected status 503, got %d", rr.Code)
+	}
+
+	if !errors.Is(ctxErr, ErrBudgetExceeded) {
+		t.Errorf("Expected request context to be canceled with ErrBudgetExceeded, got %v", ctxErr)
+	}
+}
+
+func TestBudgetUnderLimit(t *testing.T) {
+	var remaining int64
+
+	handler := New(WithBudget(10))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := Charge(r.Context(), 4); err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+		remaining, _ = Remaining(r.Context())
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", rr.Code)
+	}
+
+	if remaining != 6 {
+		t.Errorf("Expected 6 remaining units, got %d", remaining)
+	}
+}
+
+func TestBudgetStatusCodeAndBudgetFunc(t *testing.T) {
+	handler := New(
+		WithStatusCode(http.StatusTooManyRequests),
+		WithBudgetFunc(func(r *http.Request) int64 {
+			if r.Header.Get("X-Tenant") == "premium" {
+				return 100
+			}
+			return 5
+		}),
+	)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := Charge(r.Context(), 50); err != nil {
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	for tenant, expected := range map[string]int{"premium": http.StatusOK, "free": http.StatusTooManyRequests} {
+		req := httptest.NewRequest("GET", "/test", nil)
+		req.Header.Set("X-Tenant", tenant)
+		rr := httptest.NewRecorder()
+
+		handler.ServeHTTP(rr, req)
+
+		if rr.Code != expected {
+			t.Errorf("Tenant %s: Expected status %d, got %d", tenant, expected, rr.Code)
+		}
+	}
+}
+
+func TestChargeWithoutBudget(t *testing.T) {
+	if err := Charge(context.Background(), 1000); err != nil {
+		t.Errorf("Expected no error without budget, got %v", err)
+	}
+}
@@ -0,0 +1,358 @@
+package cache
+
+import (
+	"bytes"
+	"container/list"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Option is cache option.
+type Option func(*options)
+
+// options defines the configuration for the cache middleware
+type options struct {
+	// KeyFunc returns the base cache key for a request. Values of the
+	// headers named in the cached response's Vary header are appended.
+	// Default: method + host + request URI
+	keyFunc func(r *http.Request) string
+
+	// MaxBytes caps the total size of cached bodies. Least recently used
+	// entries are evicted to stay under it.
+	// Default: 32MB
+	maxBytes int64
+}
+
+// WithKeyFunc sets the function returning the base cache key for a request
+func WithKeyFunc(f func(r *http.Request) string) Option {
+	return func(o *options) {
+		o.keyFunc = f
+	}
+}
+
+// WithMaxBytes sets the maximum total size of cached bodies
+func WithMaxBytes(n int64) Option {
+	return func(o *options) {
+		o.maxBytes = n
+	}
+}
+
+// defaultKeyFunc keys requests by method, host and request URI, so virtual
+// hosts served by one handler don't share entries
+func defaultKeyFunc(r *http.Request) string {
+	return r.Method + " " + r.Host + r.URL.RequestURI()
+}
+
+// entry is a cached response
+type entry struct {
+	key     string
+	base    string
+	status  int
+	header  http.Header
+	body    []byte
+	expires time.Time
+	element *list.Element
+}
+
+// varyNames holds the header names from the Vary header of the responses
+// cached under a base key
+type varyNames struct {
+	names   []string
+	entries int
+}
+
+// store is an LRU cache of responses bounded by total body size
+type store struct {
+	mu       sync.Mutex
+	entries  map[string]*entry
+	vary     map[string]*varyNames
+	order    *list.List // front is most recently used
+	size     int64
+	maxBytes int64
+}
+
+// varyKey returns the full cache key for r given the base key
+func (s *store) varyKey(base string, r *http.Request) string {
+	v, ok := s.vary[base]
+	if !ok || len(v.names) == 0 {
+		return base
+	}
+	var b strings.Builder
+	b.WriteString(base)
+	for _, name := range v.names {
+		b.WriteString("\n")
+		b.WriteString(name)
+		b.WriteString("=")
+		b.WriteString(strings.Join(r.Header.Values(name), ","))
+	}
+	return b.String()
+}
+
+// get returns the live entry for r, removing it if expired
+func (s *store) get(base string, r *http.Request) *entry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.entries[s.varyKey(base, r)]
+	if !ok {
+		return nil
+	}
+	if time.Now().After(e.expires) {
+		s.remove(e)
+		return nil
+	}
+	s.order.MoveToFront(e.element)
+	return e
+}
+
+// set stores a response for r, evicting least recently used entries to stay
+// within maxBytes
+func (s *store) set(base string, r *http.Request, e *entry, vary []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e.base = base
+	e.key = s.varyKey(base, r)
+	if old, ok := s.entries[e.key]; ok {
+		s.remove(old)
+	}
+	v, ok := s.vary[base]
+	if !ok {
+		v = &varyNames{}
+		s.vary[base] = v
+	}
+	// Counting e first keeps v alive while evicting other entries under base.
+	// A changed Vary header leaves old variants unreachable until evicted.
+	v.names = vary
+	v.entries++
+	e.key = s.varyKey(base, r)
+
+	for s.size+int64(len(e.body)) > s.maxBytes && s.order.Len() > 0 {
+		s.remove(s.order.Back().Value.(*entry))
+	}
+
+	e.element = s.order.PushFront(e)
+	s.entries[e.key] = e
+	s.size += int64(len(e.body))
+}
+
+// remove deletes e from the store. The caller must hold the lock.
+func (s *store) remove(e *entry) {
+	s.order.Remove(e.element)
+	delete(s.entries, e.key)
+	s.size -= int64(len(e.body))
+	if v, ok := s.vary[e.base]; ok {
+		v.entries--
+		if v.entries <= 0 {
+			delete(s.vary, e.base)
+		}
+	}
+}
+
+// cacheWriter passes the response through while buffering it for the cache
+type cacheWriter struct {
+	http.ResponseWriter
+	status      int
+	header      http.Header
+	body        bytes.Buffer
+	limit       int64
+	overflow    bool
+	wroteHeader bool
+}
+
+// WriteHeader implements http.ResponseWriter
+func (w *cacheWriter) WriteHeader(code int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+	w.status = code
+	w.header = w.ResponseWriter.Header().Clone()
+	w.ResponseWriter.WriteHeader(code)
+}
+
+// Write implements http.ResponseWriter
+func (w *cacheWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if !w.overflow {
+		if int64(w.body.Len()+len(b)) > w.limit {
+			w.overflow = true
+			w.body.Reset()
+		} else {
+			w.body.Write(b)
+		}
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Unwrap returns the underlying http.ResponseWriter
+func (w *cacheWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// cacheable reports whether the buffered response to r may be stored in
+// the cache shared by all clients
+func (w *cacheWriter) cacheable(r *http.Request) bool {
+	if w.status != http.StatusOK || w.overflow {
+		return false
+	}
+
+	directives := cacheControl(w.header)
+	for _, name := range []string{"no-store", "no-cache", "private"} {
+		if _, ok := directives[name]; ok {
+			return false
+		}
+	}
+	// s-maxage overrides max-age for shared caches
+	maxAge, ok := directives["s-maxage"]
+	if !ok {
+		maxAge, ok = directives["max-age"]
+	}
+	if ok && maxAge == "0" {
+		return false
+	}
+
+	// Responses to authenticated requests are per-client unless marked public
+	if _, public := directives["public"]; !public && r.Header.Get("Authorization") != "" {
+		return false
+	}
+	// Responses setting cookies are per-client
+	if w.header.Get("Set-Cookie") != "" {
+		return false
+	}
+	return true
+}
+
+// cacheControl parses the Cache-Control directives of header into a map of
+// lowercased names to unquoted values, "" for directives without a value
+func cacheControl(header http.Header) map[string]string {
+	directives := make(map[string]string)
+	for _, value := range header.Values("Cache-Control") {
+		for value != "" {
+			var directive string
+			directive, value = nextDirective(value)
+			name, arg, _ := strings.Cut(directive, "=")
+			name = strings.ToLower(strings.TrimSpace(name))
+			if name == "" {
+				continue
+			}
+			arg = strings.TrimSpace(arg)
+			if len(arg) >= 2 && arg[0] == '"' && arg[len(arg)-1] == '"' {
+				arg = arg[1 : len(arg)-1]
+			}
+			directives[name] = arg
+		}
+	}
+	return directives
+}
+
+// nextDirective splits the first directive off a Cache-Control value,
+// ignoring commas inside quoted strings such as no-cache="Set-Cookie, Foo"
+func nextDirective(value string) (directive, rest string) {
+	quoted := false
+	for i := 0; i < len(value); i++ {
+		switch value[i] {
+		case '"':
+			quoted = !quoted
+		case ',':
+			if !quoted {
+				return value[:i], value[i+1:]
+			}
+		}
+	}
+	return value, ""
+}
+
+// varyHeaders returns the canonical header names listed in the Vary header,
+// or false for Vary: *
+func varyHeaders(header http.Header) ([]string, bool) {
+	var names []string
+	for _, value := range header.Values("Vary") {
+		for _, name := range strings.Split(value, ",") {
+			name = strings.TrimSpace(name)
+			if name == "*" {
+				return nil, false
+			}
+			if name != "" {
+				names = append(names, http.CanonicalHeaderKey(name))
+			}
+		}
+	}
+	return names, true
+}
+
+// New returns a cache middleware storing successful GET responses for ttl
+func New(ttl time.Duration, opts ...Option) func(http.Handler) http.Handler {
+	o := &options{
+		keyFunc:  defaultKeyFunc,
+		maxBytes: 32 << 20, // 32MB
+	}
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	if ttl <= 0 {
+		panic("cache ttl must be greater than 0")
+	}
+	if o.maxBytes <= 0 {
+		panic("cache max bytes must be greater than 0")
+	}
+
+	s := &store{
+		entries:  make(map[string]*entry),
+		vary:     make(map[string]*varyNames),
+		order:    list.New(),
+		maxBytes: o.maxBytes,
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodGet {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			base := o.keyFunc(r)
+
+			if e := s.get(base, r); e != nil {
+				header := w.Header()
+				for k, v := range e.header {
+					header[k] = append([]string(nil), v...)
+				}
+				header.Set("X-Cache", "HIT")
+				w.WriteHeader(e.status)
+				w.Write(e.body)
+				return
+			}
+
+			w.Header().Set("X-Cache", "MISS")
+			cw := &cacheWriter{ResponseWriter: w, limit: o.maxBytes}
+
+			next.ServeHTTP(cw, r)
+
+			if !cw.wroteHeader {
+				cw.WriteHeader(http.StatusOK)
+			}
+			if !cw.cacheable(r) {
+				return
+			}
+			vary, ok := varyHeaders(cw.header)
+			if !ok {
+				return
+			}
+
+			cw.header.Del("X-Cache")
+			s.set(base, r, &entry{
+				status:  cw.status,
+				header:  cw.header,
+				body:    bytes.Clone(cw.body.Bytes()),
+				expires: time.Now().Add(ttl),
+			}, vary)
+		})
+	}
+}
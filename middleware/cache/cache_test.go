@@ -0,0 +1,259 @@
+package cache
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// countingHandler returns a handler writing body and counting its calls
+func countingHandler(calls *atomic.Int32, body string, header map[string]string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		for k, v := range header {
+			w.Header().Set(k, v)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(body))
+	})
+}
+
+func TestCacheHitAfterMiss(t *testing.T) {
+	var calls atomic.Int32
+	handler := New(time.Minute)(countingHandler(&calls, `{"ok":true}`, nil))
+
+	for i, expected := range []string{"MISS", "HIT", "HIT"} {
+		req := httptest.NewRequest("GET", "/config", nil)
+		rr := httptest.NewRecorder()
+
+		handler.ServeHTTP(rr, req)
+
+		if rr.Code != http.StatusOK {
+			t.Errorf("Request %d: expected status 200, got %d", i, rr.Code)
+		}
+		if rr.Header().Get("X-Cache") != expected {
+			t.Errorf("Request %d: expected X-Cache %s, got %s", i, expected, rr.Header().Get("X-Cache"))
+		}
+		if rr.Header().Get("Content-Type") != "application/json" {
+			t.Errorf("Request %d: expected cached Content-Type, got %s", i, rr.Header().Get("Content-Type"))
+		}
+		if rr.Body.String() != `{"ok":true}` {
+			t.Errorf("Request %d: unexpected body %s", i, rr.Body.String())
+		}
+	}
+
+	if calls.Load() != 1 {
+		t.Errorf("Expected handler to be called once, got %d", calls.Load())
+	}
+}
+
+func TestCacheTTLExpiry(t *testing.T) {
+	var calls atomic.Int32
+	handler := New(50 * time.Millisecond)(countingHandler(&calls, "data", nil))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/test", nil))
+
+	time.Sleep(100 * time.Millisecond)
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("GET", "/test", nil))
+
+	if rr.Header().Get("X-Cache") != "MISS" {
+		t.Errorf("Expected MISS after TTL expiry, got %s", rr.Header().Get("X-Cache"))
+	}
+	if calls.Load() != 2 {
+		t.Errorf("Expected handler to be called twice, got %d", calls.Load())
+	}
+}
+
+func TestCacheNotCacheable(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		status int
+		header map[string]string
+	}{
+		{"POST request", "POST", http.StatusOK, nil},
+		{"Non-200 status", "GET", http.StatusNotFound, nil},
+		{"No-store", "GET", http.StatusOK, map[string]string{"Cache-Control": "no-store"}},
+		{"No-cache", "GET", http.StatusOK, map[string]string{"Cache-Control": "private, no-cache"}},
+		{"Set-Cookie", "GET", http.StatusOK, map[string]string{"Set-Cookie": "session=1"}},
+		{"Vary all", "GET", http.StatusOK, map[string]string{"Vary": "*"}},
+		{"Private", "GET", http.StatusOK, map[string]string{"Cache-Control": "private, max-age=60"}},
+		{"Max-age zero", "GET", http.StatusOK, map[string]string{"Cache-Control": "public, max-age=0"}},
+		{"S-maxage zero", "GET", http.StatusOK, map[string]string{"Cache-Control": "max-age=60, s-maxage=0"}},
+		{"Quoted no-cache", "GET", http.StatusOK, map[string]string{"Cache-Control": `no-cache="Set-Cookie, X-User", max-age=60`}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls atomic.Int32
+			handler := New(time.Minute)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls.Add(1)
+				for k, v := range tt.header {
+					w.Header().Set(k, v)
+				}
+				w.WriteHeader(tt.status)
+			}))
+
+			for i := 0; i < 2; i++ {
+				handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(tt.method, "/test", nil))
+			}
+
+			if calls.Load() != 2 {
+				t.Errorf("Expected response not to be cached, handler called %d times", calls.Load())
+			}
+		})
+	}
+}
+
+func TestCacheDirectives(t *testing.T) {
+	tests := []struct {
+		name          string
+		authorization string
+		cacheControl  string
+		cached        bool
+	}{
+		{"Authorization", "Bearer token", "", false},
+		{"Authorization with max-age", "Bearer token", "max-age=60", false},
+		{"Authorization with public", "Bearer token", "public, max-age=60", true},
+		{"Anonymous", "", "max-age=60", true},
+		{"S-maxage overrides max-age", "", "max-age=0, s-maxage=60", true},
+		{"Directive name not substring matched", "", "x-no-store-hint", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls atomic.Int32
+			header := map[string]string{}
+			if tt.cacheControl != "" {
+				header["Cache-Control"] = tt.cacheControl
+			}
+			handler := New(time.Minute)(countingHandler(&calls, `{"user":"alice"}`, header))
+
+			req := httptest.NewRequest("GET", "/me", nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+
+			// An anonymous client must not get the first response unless cached
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, httptest.NewRequest("GET", "/me", nil))
+
+			expected := int32(2)
+			if tt.cached {
+				expected = 1
+			}
+			if calls.Load() != expected {
+				t.Errorf("Expected handler to be called %d times, got %d", expected, calls.Load())
+			}
+		})
+	}
+}
+
+func TestCacheVary(t *testing.T) {
+	var calls atomic.Int32
+	handler := New(time.Minute)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Header().Set("Vary", "Accept-Language")
+		w.Write([]byte(r.Header.Get("Accept-Language")))
+	}))
+
+	for _, lang := range []string{"en", "fr", "en", "fr"} {
+		req := httptest.NewRequest("GET", "/test", nil)
+		req.Header.Set("Accept-Language", lang)
+		rr := httptest.NewRecorder()
+
+		handler.ServeHTTP(rr, req)
+
+		if rr.Body.String() != lang {
+			t.Errorf("Expected body %s, got %s", lang, rr.Body.String())
+		}
+	}
+
+	// The first request learns the Vary header, then each language is cached once
+	if calls.Load() != 2 {
+		t.Errorf("Expected handler to be called twice, got %d", calls.Load())
+	}
+}
+
+func TestCacheKeyIncludesHost(t *testing.T) {
+	var calls atomic.Int32
+	handler := New(time.Minute)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Write([]byte(r.Host))
+	}))
+
+	for i, host := range []string{"a.example.com", "b.example.com", "a.example.com"} {
+		req := httptest.NewRequest("GET", "/config", nil)
+		req.Host = host
+		rr := httptest.NewRecorder()
+
+		handler.ServeHTTP(rr, req)
+
+		if rr.Body.String() != host {
+			t.Errorf("Request %d: expected body %s, got %s", i, host, rr.Body.String())
+		}
+	}
+
+	if calls.Load() != 2 {
+		t.Errorf("Expected handler to be called once per host, got %d", calls.Load())
+	}
+}
+
+func TestCacheWithKeyFunc(t *testing.T) {
+	var calls atomic.Int32
+	handler := New(time.Minute, WithKeyFunc(func(r *http.Request) string {
+		return r.URL.Path // Ignore the query string
+	}))(countingHandler(&calls, "data", nil))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/test?a=1", nil))
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("GET", "/test?a=2", nil))
+
+	if rr.Header().Get("X-Cache") != "HIT" {
+		t.Errorf("Expected HIT with custom key, got %s", rr.Header().Get("X-Cache"))
+	}
+}
+
+func TestCacheWithMaxBytes(t *testing.T) {
+	var calls atomic.Int32
+	handler := New(time.Minute, WithMaxBytes(100))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Write([]byte(strings.Repeat("a", 60)))
+	}))
+
+	// Two 60 byte bodies don't fit, so /a is evicted when /b is stored
+	for _, path := range []string{"/a", "/b", "/b", "/a"} {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+	}
+
+	if calls.Load() != 3 {
+		t.Errorf("Expected handler to be called 3 times, got %d", calls.Load())
+	}
+
+	// Bodies larger than MaxBytes are never cached
+	calls.Store(0)
+	handler = New(time.Minute, WithMaxBytes(10))(countingHandler(&calls, strings.Repeat("a", 20), nil))
+	for i := 0; i < 2; i++ {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/big", nil))
+	}
+
+	if calls.Load() != 2 {
+		t.Errorf("Expected oversized response not to be cached, handler called %d times", calls.Load())
+	}
+}
+
+func TestCachePanic(t *testing.T) {
+	defer func() {
+		if r := recover(); r == nil {
+			t.Error("Expected panic for zero ttl")
+		}
+	}()
+
+	New(0)
+}
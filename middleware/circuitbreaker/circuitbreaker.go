@@ -0,0 +1,229 @@
+package circuitbreaker
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Option is circuit breaker option.
+type Option func(*options)
+
+// options defines the configuration for the circuit breaker middleware
+type options struct {
+	// FailureThreshold is the number of failures within Window that opens
+	// the breaker
+	// Default: 5
+	failureThreshold int
+
+	// Window is the rolling period in which failures are counted
+	// Default: 10s
+	window time.Duration
+
+	// Cooldown is how long the breaker stays open before allowing a trial
+	// request
+	// Default: 30s
+	cooldown time.Duration
+
+	// IsFailure reports whether a response status counts as a failure.
+	// Panics always count.
+	// Default: status >= 500
+	isFailure func(status int) bool
+}
+
+// WithFailureThreshold sets the number of failures within the window that opens the breaker
+func WithFailureThreshold(n int) Option {
+	return func(o *options) {
+		o.failureThreshold = n
+	}
+}
+
+// WithWindow sets the rolling period in which failures are counted
+func WithWindow(d time.Duration) Option {
+	return func(o *options) {
+		o.window = d
+	}
+}
+
+// WithCooldown sets how long the breaker stays open before a trial request
+func WithCooldown(d time.Duration) Option {
+	return func(o *options) {
+		o.cooldown = d
+	}
+}
+
+// WithIsFailure sets the function reporting whether a status counts as a failure
+func WithIsFailure(f func(status int) bool) Option {
+	return func(o *options) {
+		o.isFailure = f
+	}
+}
+
+// state is the circuit breaker state
+type state int
+
+const (
+	stateClosed state = iota
+	stateOpen
+	stateHalfOpen
+)
+
+// breaker tracks failures and the circuit state
+type breaker struct {
+	mu       sync.Mutex
+	opts     *options
+	state    state
+	failures []time.Time // failure times within the window, oldest first
+	openedAt time.Time
+	trial    bool // a half-open trial request is in flight
+}
+
+// allow reports whether a request may proceed, or how long until the next
+// trial if not
+func (b *breaker) allow() (bool, time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case stateOpen:
+		remaining := b.opts.cooldown - time.Since(b.openedAt)
+		if remaining > 0 {
+			return false, remaining
+		}
+		b.state = stateHalfOpen
+		b.trial = true
+		return true, 0
+	case stateHalfOpen:
+		// Only one trial request at a time
+		if b.trial {
+			return false, 0
+		}
+		b.trial = true
+		return true, 0
+	}
+	return true, 0
+}
+
+// record updates the circuit with the outcome of an allowed request
+func (b *breaker) record(failed bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+
+	if b.state == stateHalfOpen {
+		b.trial = false
+		if failed {
+			b.state = stateOpen
+			b.openedAt = now
+		} else {
+			b.state = stateClosed
+			b.failures = nil
+		}
+		return
+	}
+
+	if !failed {
+		return
+	}
+
+	// Drop failures that fell out of the window
+	cutoff := now.Add(-b.opts.window)
+	i := 0
+	for i < len(b.failures) && b.failures[i].Before(cutoff) {
+		i++
+	}
+	b.failures = append(b.failures[i:], now)
+
+	if len(b.failures) >= b.opts.failureThreshold {
+		b.state = stateOpen
+		b.openedAt = now
+		b.failures = nil
+	}
+}
+
+// statusWriter captures the response status code
+type statusWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+// WriteHeader implements http.ResponseWriter
+func (w *statusWriter) WriteHeader(code int) {
+	if !w.wroteHeader {
+		w.status = code
+		w.wroteHeader = true
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+// Write implements http.ResponseWriter
+func (w *statusWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Unwrap returns the underlying http.ResponseWriter
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// New returns a circuit breaker middleware that fails fast with 503 once the
+// wrapped handler fails too often, then lets a trial request through after
+// the cooldown to decide whether to close again. Each handler wrapped by the
+// returned middleware gets its own breaker, so one failing route does not
+// open the circuit for the others.
+func New(opts ...Option) func(http.Handler) http.Handler {
+	o := &options{
+		failureThreshold: 5,
+		window:           10 * time.Second,
+		cooldown:         30 * time.Second,
+		isFailure: func(status int) bool {
+			return status >= http.StatusInternalServerError
+		},
+	}
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	if o.failureThreshold <= 0 {
+		panic("circuit breaker failure threshold must be greater than 0")
+	}
+	if o.window <= 0 || o.cooldown <= 0 {
+		panic("circuit breaker window and cooldown must be greater than 0")
+	}
+
+	return func(next http.Handler) http.Handler {
+		b := &breaker{opts: o}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ok, retryAfter := b.allow()
+			if !ok {
+				if retryAfter > 0 {
+					// Round up so clients don't retry before the cooldown ends
+					w.Header().Set("Retry-After", strconv.Itoa(int((retryAfter+time.Second-1)/time.Second)))
+				}
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusServiceUnavailable)
+				w.Write([]byte(`{"error":"service unavailable"}`))
+				return
+			}
+
+			sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+			defer func() {
+				if rec := recover(); rec != nil {
+					b.record(true)
+					panic(rec)
+				}
+				b.record(o.isFailure(sw.status))
+			}()
+
+			next.ServeHTTP(sw, r)
+		})
+	}
+}
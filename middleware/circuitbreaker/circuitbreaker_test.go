@@ -0,0 +1,167 @@
+package circuitbreaker
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newTestHandler returns a breaker around a handler answering with *status
+func newTestHandler(status *atomic.Int32, opts ...Option) http.Handler {
+	return New(opts...)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(int(status.Load()))
+	}))
+}
+
+// serve sends a request and returns the response status
+func serve(handler http.Handler) int {
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("GET", "/test", nil))
+	return rr.Code
+}
+
+func TestCircuitBreakerOpens(t *testing.T) {
+	var status atomic.Int32
+	status.Store(http.StatusInternalServerError)
+
+	handler := newTestHandler(&status, WithFailureThreshold(3), WithCooldown(time.Minute))
+
+	for i := 0; i < 3; i++ {
+		if code := serve(handler); code != http.StatusInternalServerError {
+			t.Errorf("Request %d: expected handler status 500, got %d", i, code)
+		}
+	}
+
+	// Open: the handler is no longer called
+	status.Store(http.StatusOK)
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("GET", "/test", nil))
+
+	if rr.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected status 503 once open, got %d", rr.Code)
+	}
+	if rr.Header().Get("Retry-After") != "60" {
+		t.Errorf("Expected Retry-After 60, got %s", rr.Header().Get("Retry-After"))
+	}
+}
+
+func TestCircuitBreakerRecovers(t *testing.T) {
+	var status atomic.Int32
+	status.Store(http.StatusInternalServerError)
+
+	handler := newTestHandler(&status, WithFailureThreshold(2), WithCooldown(50*time.Millisecond))
+
+	serve(handler)
+	serve(handler)
+
+	if code := serve(handler); code != http.StatusServiceUnavailable {
+		t.Fatalf("Expected breaker to be open, got %d", code)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+
+	// Half-open: a successful trial closes the breaker
+	status.Store(http.StatusOK)
+
+	for i := 0; i < 3; i++ {
+		if code := serve(handler); code != http.StatusOK {
+			t.Errorf("Request %d: expected status 200 after recovery, got %d", i, code)
+		}
+	}
+}
+
+func TestCircuitBreakerFailedTrial(t *testing.T) {
+	var status atomic.Int32
+	status.Store(http.StatusInternalServerError)
+
+	handler := newTestHandler(&status, WithFailureThreshold(1), WithCooldown(50*time.Millisecond))
+
+	serve(handler)
+	time.Sleep(100 * time.Millisecond)
+
+	// The trial fails, so the breaker opens again
+	if code := serve(handler); code != http.StatusInternalServerError {
+		t.Errorf("Expected trial request to reach handler, got %d", code)
+	}
+	if code := serve(handler); code != http.StatusServiceUnavailable {
+		t.Errorf("Expected breaker to reopen after failed trial, got %d", code)
+	}
+}
+
+func TestCircuitBreakerWindow(t *testing.T) {
+	var status atomic.Int32
+	status.Store(http.StatusInternalServerError)
+
+	handler := newTestHandler(&status, WithFailureThreshold(2), WithWindow(50*time.Millisecond))
+
+	serve(handler)
+	time.Sleep(100 * time.Millisecond)
+
+	// The first failure fell out of the window
+	serve(handler)
+
+	if code := serve(handler); code == http.StatusServiceUnavailable {
+		t.Error("Expected failures outside the window not to open the breaker")
+	}
+}
+
+func TestCircuitBreakerWithIsFailure(t *testing.T) {
+	var status atomic.Int32
+	status.Store(http.StatusTooManyRequests)
+
+	handler := newTestHandler(&status,
+		WithFailureThreshold(1),
+		WithIsFailure(func(status int) bool {
+			return status == http.StatusTooManyRequests
+		}),
+	)
+
+	serve(handler)
+
+	if code := serve(handler); code != http.StatusServiceUnavailable {
+		t.Errorf("Expected custom failure to open the breaker, got %d", code)
+	}
+}
+
+func TestCircuitBreakerPerHandler(t *testing.T) {
+	middleware := New(WithFailureThreshold(2), WithCooldown(time.Minute))
+	failing := middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	healthy := middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	for i := 0; i < 2; i++ {
+		serve(failing)
+	}
+
+	if code := serve(failing); code != http.StatusServiceUnavailable {
+		t.Errorf("Expected failing handler's circuit to be open, got %d", code)
+	}
+	if code := serve(healthy); code != http.StatusOK {
+		t.Errorf("Expected healthy handler to be unaffected, got %d", code)
+	}
+}
+
+func TestCircuitBreakerPanic(t *testing.T) {
+	handler := New(WithFailureThreshold(1))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	}))
+
+	func() {
+		defer func() {
+			if r := recover(); r != "boom" {
+				t.Errorf("Expected panic to propagate, got %v", r)
+			}
+		}()
+		serve(handler)
+	}()
+
+	if code := serve(handler); code != http.StatusServiceUnavailable {
+		t.Errorf("Expected panic to count as a failure, got %d", code)
+	}
+}
@@ -34,6 +34,17 @@ type options struct {
 	// MaxAge indicates how long (in seconds) the results of a preflight request can be cached
 	// Default value is 0
 	maxAge int
+
+	// PreflightCacheControl is the Cache-Control header set on preflight
+	// responses so CDNs can cache them. Preflight responses then also vary
+	// on the Origin and Access-Control-Request-* headers
+	// Default value is "" (not set)
+	preflightCacheControl string
+
+	// OptionsPassthrough passes preflight requests on to the next handler
+	// after setting the CORS headers instead of responding with 204
+	// Default value is false
+	optionsPassthrough bool
 }
 
 // WithAllowedOrigins sets the allowed origins
@@ -43,6 +54,14 @@ func WithAllowedOrigins(origins []string) Option {
 	}
 }
 
+// WithAllowedOriginsCSV sets the allowed origins from a comma-separated
+// list, e.g. "https://a.com, https://b.com" read from an environment variable
+func WithAllowedOriginsCSV(origins string) Option {
+	return func(o *options) {
+		o.allowedOrigins = strings.Split(origins, ",")
+	}
+}
+
 // WithAllowedMethods sets the allowed methods
 func WithAllowedMethods(methods []string) Option {
 	return func(o *options) {
@@ -78,14 +97,104 @@ func WithMaxAge(age int) Option {
 	}
 }
 
-// isOriginAllowed checks if the given origin is in the allowed list
-func isOriginAllowed(origin string, allowedOrigins []string) bool {
+// WithPreflightCacheControl sets the Cache-Control header for preflight responses
+func WithPreflightCacheControl(value string) Option {
+	return func(o *options) {
+		o.preflightCacheControl = value
+	}
+}
+
+// WithOptionsPassthrough sets whether preflight requests are passed on to the next handler
+func WithOptionsPassthrough(passthrough bool) Option {
+	return func(o *options) {
+		o.optionsPassthrough = passthrough
+	}
+}
+
+// setPreflightCacheHeaders makes a preflight response cacheable by CDNs
+func (o *options) setPreflightCacheHeaders(w http.ResponseWriter) {
+	if o.preflightCacheControl == "" {
+		return
+	}
+	w.Header().Set("Cache-Control", o.preflightCacheControl)
+	addVary(w.Header(), "Origin", "Access-Control-Request-Method", "Access-Control-Request-Headers")
+}
+
+// addVary merges tokens into a single Vary header value, skipping tokens
+// already present (case-insensitive) so middleware that also set Vary, such
+// as gzip, don't produce duplicates
+func addVary(h http.Header, tokens ...string) {
+	values := h.Values("Vary")
+	if len(values) == 0 {
+		h.Set("Vary", strings.Join(tokens, ", "))
+		return
+	}
+
+	var merged []string
+	seen := make(map[string]bool)
+	for _, value := range values {
+		for _, token := range strings.Split(value, ",") {
+			token = strings.TrimSpace(token)
+			if token == "" || seen[strings.ToLower(token)] {
+				continue
+			}
+			seen[strings.ToLower(token)] = true
+			merged = append(merged, token)
+		}
+	}
+	if seen["*"] {
+		// Vary: * already covers every header
+		return
+	}
+	for _, token := range tokens {
+		if !seen[strings.ToLower(token)] {
+			seen[strings.ToLower(token)] = true
+			merged = append(merged, token)
+		}
+	}
+	h.Set("Vary", strings.Join(merged, ", "))
+}
+
+// normalizeOrigins trims whitespace and trailing slashes from origins and
+// drops empty entries, so "https://a.com/" matches the Origin "https://a.com"
+func normalizeOrigins(origins []string) []string {
+	normalized := make([]string, 0, len(origins))
+	for _, origin := range origins {
+		origin = strings.TrimRight(strings.TrimSpace(origin), "/")
+		if origin != "" {
+			normalized = append(normalized, origin)
+		}
+	}
+	return normalized
+}
+
+// originSet matches request origins against the allowed origins in
+// constant time
+type originSet struct {
+	any   bool
+	exact map[string]struct{}
+}
+
+// newOriginSet compiles the allowed origins list
+func newOriginSet(allowedOrigins []string) originSet {
+	s := originSet{exact: make(map[string]struct{}, len(allowedOrigins))}
 	for _, allowed := range allowedOrigins {
-		if allowed == "*" || allowed == origin {
-			return true
+		if allowed == "*" {
+			s.any = true
+			continue
 		}
+		s.exact[allowed] = struct{}{}
+	}
+	return s
+}
+
+// allows checks if the given origin is in the allowed list
+func (s originSet) allows(origin string) bool {
+	if s.any {
+		return true
 	}
-	return false
+	_, ok := s.exact[origin]
+	return ok
 }
 
 // CORS returns a CORS middleware with optional configuration
@@ -101,65 +210,104 @@ func New(opts ...Option) func(http.Handler) http.Handler {
 		opt(o)
 	}
 
-	allowedMethods := strings.Join(o.allowedMethods, ", ")
-	allowedHeaders := strings.Join(o.allowedHeaders, ", ")
-	exposedHeaders := strings.Join(o.exposedHeaders, ", ")
+	o.allowedOrigins = normalizeOrigins(o.allowedOrigins)
+	h := &handler{
+		options:        o,
+		origins:        newOriginSet(o.allowedOrigins),
+		wildcard:       len(o.allowedOrigins) == 1 && o.allowedOrigins[0] == "*",
+		allowedMethods: strings.Join(o.allowedMethods, ", "),
+		allowedHeaders: strings.Join(o.allowedHeaders, ", "),
+		exposedHeaders: strings.Join(o.exposedHeaders, ", "),
+	}
+	if o.maxAge > 0 {
+		h.maxAge = strconv.Itoa(o.maxAge)
+	}
 
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			origin := r.Header.Get("Origin")
-
-			// Determine allowed origin
-			var allowedOrigin string
-			if len(o.allowedOrigins) == 1 && o.allowedOrigins[0] == "*" {
-				allowedOrigin = "*"
-			} else if isOriginAllowed(origin, o.allowedOrigins) {
-				allowedOrigin = origin
-			} else {
-				// Origin not allowed, still set other headers but not Access-Control-Allow-Origin
-				w.Header().Set("Access-Control-Allow-Methods", allowedMethods)
-				w.Header().Set("Access-Control-Allow-Headers", allowedHeaders)
-
-				// Handle preflight requests
-				if r.Method == http.MethodOptions {
-					w.WriteHeader(http.StatusNoContent)
-					return
-				}
-
-				next.ServeHTTP(w, r)
+			if r.Method == http.MethodOptions {
+				h.preflight(w, r, next)
 				return
 			}
+			h.actual(w, r, next)
+		})
+	}
+}
 
-			// Set CORS headers
-			w.Header().Set("Access-Control-Allow-Origin", allowedOrigin)
-			w.Header().Set("Access-Control-Allow-Methods", allowedMethods)
-			w.Header().Set("Access-Control-Allow-Headers", allowedHeaders)
+// handler holds the header values New precomputes from the options
+type handler struct {
+	*options
+	origins        originSet
+	wildcard       bool
+	allowedMethods string
+	allowedHeaders string
+	exposedHeaders string
+	maxAge         string
+}
 
-			// Only add Vary header when not using wildcard
-			if allowedOrigin != "*" {
-				w.Header().Add("Vary", "Origin")
-			}
+// allowOrigin returns the Access-Control-Allow-Origin value for a request,
+// or false if its origin is not allowed
+func (h *handler) allowOrigin(w http.ResponseWriter, r *http.Request) (string, bool) {
+	if h.wildcard {
+		return "*", true
+	}
 
-			if len(exposedHeaders) > 0 {
-				w.Header().Set("Access-Control-Expose-Headers", exposedHeaders)
-			}
+	// Unless every origin gets the wildcard, the response depends on the
+	// request Origin, whether or not it is allowed. Vary on it so shared
+	// caches don't serve one origin's (possibly credentialed) response to
+	// another.
+	addVary(w.Header(), "Origin")
 
-			// Only set credentials header if origin is not wildcard
-			if o.allowCredentials && allowedOrigin != "*" {
-				w.Header().Set("Access-Control-Allow-Credentials", "true")
-			}
+	origin := r.Header.Get("Origin")
+	return origin, h.origins.allows(origin)
+}
 
-			if o.maxAge > 0 {
-				w.Header().Set("Access-Control-Max-Age", strconv.Itoa(o.maxAge))
-			}
+// setAllowedHeaders sets the headers shared by preflight and actual
+// responses to an allowed origin
+func (h *handler) setAllowedHeaders(w http.ResponseWriter, allowedOrigin string) {
+	w.Header().Set("Access-Control-Allow-Origin", allowedOrigin)
+	w.Header().Set("Access-Control-Allow-Methods", h.allowedMethods)
 
-			// Handle preflight requests
-			if r.Method == http.MethodOptions {
-				w.WriteHeader(http.StatusNoContent)
-				return
-			}
+	if len(h.exposedHeaders) > 0 {
+		w.Header().Set("Access-Control-Expose-Headers", h.exposedHeaders)
+	}
 
-			next.ServeHTTP(w, r)
-		})
+	// Only set credentials header if origin is not wildcard
+	if h.allowCredentials && allowedOrigin != "*" {
+		w.Header().Set("Access-Control-Allow-Credentials", "true")
+	}
+}
+
+// preflight handles OPTIONS requests. Allow-Headers and Max-Age only apply
+// to preflight responses, so actual responses omit them.
+func (h *handler) preflight(w http.ResponseWriter, r *http.Request, next http.Handler) {
+	if allowedOrigin, ok := h.allowOrigin(w, r); ok {
+		h.setAllowedHeaders(w, allowedOrigin)
+		if h.maxAge != "" {
+			w.Header().Set("Access-Control-Max-Age", h.maxAge)
+		}
+	} else {
+		// Origin not allowed, still set other headers but not Access-Control-Allow-Origin
+		w.Header().Set("Access-Control-Allow-Methods", h.allowedMethods)
+	}
+	w.Header().Set("Access-Control-Allow-Headers", h.allowedHeaders)
+	h.setPreflightCacheHeaders(w)
+
+	if h.optionsPassthrough {
+		next.ServeHTTP(w, r)
+		return
 	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// actual handles non-preflight requests
+func (h *handler) actual(w http.ResponseWriter, r *http.Request, next http.Handler) {
+	if allowedOrigin, ok := h.allowOrigin(w, r); ok {
+		h.setAllowedHeaders(w, allowedOrigin)
+	} else {
+		// Origin not allowed, still set other headers but not Access-Control-Allow-Origin
+		w.Header().Set("Access-Control-Allow-Methods", h.allowedMethods)
+	}
+
+	next.ServeHTTP(w, r)
 }
@@ -1,8 +1,10 @@
 package cors
 
 import (
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 )
 
@@ -26,8 +28,9 @@ func TestCORS(t *testing.T) {
 		t.Error("Expected Access-Control-Allow-Methods header")
 	}
 
-	if rr.Header().Get("Access-Control-Allow-Headers") == "" {
-		t.Error("Expected Access-Control-Allow-Headers header")
+	// Allow-Headers only applies to preflight responses
+	if rr.Header().Get("Access-Control-Allow-Headers") != "" {
+		t.Error("Expected no Access-Control-Allow-Headers header on actual response")
 	}
 }
 
@@ -110,7 +113,7 @@ func TestCORSWithAllowedHeaders(t *testing.T) {
 		w.WriteHeader(http.StatusOK)
 	}))
 
-	req := httptest.NewRequest("GET", "/test", nil)
+	req := httptest.NewRequest("OPTIONS", "/test", nil)
 	rr := httptest.NewRecorder()
 
 	handler.ServeHTTP(rr, req)
@@ -186,7 +189,7 @@ func TestCORSWithMaxAge(t *testing.T) {
 		w.WriteHeader(http.StatusOK)
 	}))
 
-	req := httptest.NewRequest("GET", "/test", nil)
+	req := httptest.NewRequest("OPTIONS", "/test", nil)
 	rr := httptest.NewRecorder()
 
 	handler.ServeHTTP(rr, req)
@@ -228,12 +231,13 @@ func TestCORSMultipleOptions(t *testing.T) {
 		t.Errorf("Credentials not set correctly, got '%s'", rr.Header().Get("Access-Control-Allow-Credentials"))
 	}
 
-	if rr.Header().Get("Access-Control-Allow-Headers") != "Authorization" {
-		t.Errorf("Headers not set correctly, got '%s'", rr.Header().Get("Access-Control-Allow-Headers"))
+	// Preflight-only headers are left off actual responses
+	if rr.Header().Get("Access-Control-Allow-Headers") != "" {
+		t.Errorf("Expected no Allow-Headers on actual response, got '%s'", rr.Header().Get("Access-Control-Allow-Headers"))
 	}
 
-	if rr.Header().Get("Access-Control-Max-Age") != "3600" {
-		t.Errorf("Max-Age not set correctly, got '%s'", rr.Header().Get("Access-Control-Max-Age"))
+	if rr.Header().Get("Access-Control-Max-Age") != "" {
+		t.Errorf("Expected no Max-Age on actual response, got '%s'", rr.Header().Get("Access-Control-Max-Age"))
 	}
 
 	if rr.Header().Get("Vary") == "" {
@@ -328,7 +332,7 @@ func TestCORSDefaultConfiguration(t *testing.T) {
 		w.WriteHeader(http.StatusOK)
 	}))
 
-	req := httptest.NewRequest("GET", "/test", nil)
+	req := httptest.NewRequest("OPTIONS", "/test", nil)
 	rr := httptest.NewRecorder()
 
 	handler.ServeHTTP(rr, req)
@@ -393,3 +397,306 @@ func TestCORSMultipleOrigins(t *testing.T) {
 		}
 	}
 }
+
+func TestCORSPreflightCacheControl(t *testing.T) {
+	middleware := New(
+		WithAllowedOrigins([]string{"https://example.com"}),
+		WithPreflightCacheControl("public, max-age=3600"),
+	)
+
+	handler := middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	// Preflight response is cacheable and varies on the request headers
+	req := httptest.NewRequest("OPTIONS", "/test", nil)
+	req.Header.Set("Origin", "https://example.com")
+	req.Header.Set("Access-Control-Request-Method", "PUT")
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	if rr.Header().Get("Cache-Control") != "public, max-age=3600" {
+		t.Errorf("Expected Cache-Control on preflight, got %q", rr.Header().Get("Cache-Control"))
+	}
+
+	vary := strings.Join(rr.Header().Values("Vary"), ", ")
+	if vary != "Origin, Access-Control-Request-Method, Access-Control-Request-Headers" {
+		t.Errorf("Unexpected Vary on preflight: %q", vary)
+	}
+
+	// Actual response is not affected
+	req = httptest.NewRequest("GET", "/test", nil)
+	req.Header.Set("Origin", "https://example.com")
+	rr = httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	if rr.Header().Get("Cache-Control") != "" {
+		t.Errorf("Expected no Cache-Control on actual response, got %q", rr.Header().Get("Cache-Control"))
+	}
+
+	if vary := strings.Join(rr.Header().Values("Vary"), ", "); vary != "Origin" {
+		t.Errorf("Expected Vary 'Origin' on actual response, got %q", vary)
+	}
+}
+
+func TestCORSCredentialsVary(t *testing.T) {
+	middleware := New(
+		WithAllowedOrigins([]string{"https://tenant-a.com"}),
+		WithAllowCredentials(true),
+	)
+
+	handler := middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		name   string
+		origin string
+		acao   string
+	}{
+		{"Allowed origin", "https://tenant-a.com", "https://tenant-a.com"},
+		{"Disallowed origin", "https://tenant-b.com", ""},
+		{"No origin", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/test", nil)
+			if tt.origin != "" {
+				req.Header.Set("Origin", tt.origin)
+			}
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			if rr.Header().Get("Access-Control-Allow-Origin") != tt.acao {
+				t.Errorf("Expected Access-Control-Allow-Origin %q, got %q", tt.acao, rr.Header().Get("Access-Control-Allow-Origin"))
+			}
+
+			// Every variant must vary on Origin so caches keep them apart
+			if vary := strings.Join(rr.Header().Values("Vary"), ", "); vary != "Origin" {
+				t.Errorf("Expected Vary 'Origin', got %q", vary)
+			}
+		})
+	}
+}
+
+func TestCORSMergesVary(t *testing.T) {
+	// presetVary stands in for middleware like gzip that also set Vary
+	presetVary := func(values ...string) func(http.Handler) http.Handler {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				for _, v := range values {
+					w.Header().Add("Vary", v)
+				}
+				next.ServeHTTP(w, r)
+			})
+		}
+	}
+
+	tests := []struct {
+		name     string
+		preset   []string
+		expected string
+	}{
+		{"Merged", []string{"Accept-Encoding"}, "Accept-Encoding, Origin"},
+		{"Already present", []string{"Accept-Encoding, origin"}, "Accept-Encoding, origin"},
+		{"Multiple values", []string{"Accept-Encoding", "Accept-Language"}, "Accept-Encoding, Accept-Language, Origin"},
+		{"Wildcard", []string{"*"}, "*"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := presetVary(tt.preset...)(
+				New(WithAllowedOrigins([]string{"https://example.com"}))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					w.WriteHeader(http.StatusOK)
+				})),
+			)
+
+			req := httptest.NewRequest("GET", "/test", nil)
+			req.Header.Set("Origin", "https://example.com")
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			if values := rr.Header().Values("Vary"); len(values) != 1 || values[0] != tt.expected {
+				t.Errorf("Expected single Vary %q, got %q", tt.expected, values)
+			}
+		})
+	}
+}
+
+func TestCORSOptionsPassthrough(t *testing.T) {
+	called := false
+	handler := New(WithOptionsPassthrough(true))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest("OPTIONS", "/test", nil)
+	req.Header.Set("Origin", "https://example.com")
+	req.Header.Set("Access-Control-Request-Method", "POST")
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	if !called {
+		t.Error("Expected handler to be called for OPTIONS request")
+	}
+	if rr.Code != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", rr.Code)
+	}
+	if rr.Header().Get("Access-Control-Allow-Origin") != "*" {
+		t.Errorf("Expected Access-Control-Allow-Origin '*', got %q", rr.Header().Get("Access-Control-Allow-Origin"))
+	}
+	if rr.Header().Get("Access-Control-Allow-Methods") == "" {
+		t.Error("Expected Access-Control-Allow-Methods to be set")
+	}
+}
+
+func TestCORSAllowedOriginsCSV(t *testing.T) {
+	tests := []struct {
+		name   string
+		opt    Option
+		origin string
+		acao   string
+	}{
+		{"First CSV origin", WithAllowedOriginsCSV("https://a.com, https://b.com"), "https://a.com", "https://a.com"},
+		{"Trimmed CSV origin", WithAllowedOriginsCSV("https://a.com,   https://b.com  "), "https://b.com", "https://b.com"},
+		{"Unlisted CSV origin", WithAllowedOriginsCSV("https://a.com, https://b.com"), "https://c.com", ""},
+		{"Empty CSV entries", WithAllowedOriginsCSV("https://a.com,, "), "https://a.com", "https://a.com"},
+		{"CSV wildcard", WithAllowedOriginsCSV(" * "), "https://c.com", "*"},
+		{"Trailing slash CSV", WithAllowedOriginsCSV("https://a.com/"), "https://a.com", "https://a.com"},
+		{"Trailing slash slice", WithAllowedOrigins([]string{"https://a.com/"}), "https://a.com", "https://a.com"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := New(tt.opt)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			req := httptest.NewRequest("GET", "/test", nil)
+			req.Header.Set("Origin", tt.origin)
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			if rr.Header().Get("Access-Control-Allow-Origin") != tt.acao {
+				t.Errorf("Expected Access-Control-Allow-Origin %q, got %q", tt.acao, rr.Header().Get("Access-Control-Allow-Origin"))
+			}
+		})
+	}
+}
+
+func TestCORSPreflightOnlyHeaders(t *testing.T) {
+	handler := New(
+		WithAllowedOrigins([]string{"https://example.com"}),
+		WithExposedHeaders([]string{"X-Total-Count"}),
+		WithAllowCredentials(true),
+		WithMaxAge(600),
+	)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		method    string
+		preflight bool
+	}{
+		{"OPTIONS", true},
+		{"GET", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/test", nil)
+			req.Header.Set("Origin", "https://example.com")
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			// Sent on both preflight and actual responses
+			for _, h := range []string{"Access-Control-Allow-Origin", "Access-Control-Expose-Headers", "Access-Control-Allow-Credentials"} {
+				if rr.Header().Get(h) == "" {
+					t.Errorf("Expected %s header", h)
+				}
+			}
+
+			// Only meaningful on preflight responses
+			for _, h := range []string{"Access-Control-Allow-Headers", "Access-Control-Max-Age"} {
+				if got := rr.Header().Get(h) != ""; got != tt.preflight {
+					t.Errorf("Expected %s present=%v, got %v", h, tt.preflight, got)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkCORSOriginMatch(b *testing.B) {
+	origins := make([]string, 100)
+	for i := range origins {
+		origins[i] = fmt.Sprintf("https://tenant-%d.example.com", i)
+	}
+	origin := origins[len(origins)-1]
+
+	// linear is the per-request scan the origin set replaces
+	b.Run("linear", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			for _, allowed := range origins {
+				if allowed == "*" || allowed == origin {
+					break
+				}
+			}
+		}
+	})
+
+	b.Run("set", func(b *testing.B) {
+		set := newOriginSet(origins)
+		for i := 0; i < b.N; i++ {
+			set.allows(origin)
+		}
+	})
+
+	b.Run("middleware", func(b *testing.B) {
+		handler := New(WithAllowedOrigins(origins))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		req := httptest.NewRequest("GET", "/test", nil)
+		req.Header.Set("Origin", origin)
+
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+		}
+	})
+}
+
+func BenchmarkCORSPreflight(b *testing.B) {
+	handler := New(
+		WithAllowedOrigins([]string{"https://a.example.com", "https://b.example.com"}),
+		WithAllowCredentials(true),
+		WithExposedHeaders([]string{"X-Request-ID"}),
+	)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	preflight := httptest.NewRequest("OPTIONS", "/test", nil)
+	preflight.Header.Set("Origin", "https://b.example.com")
+	preflight.Header.Set("Access-Control-Request-Method", "PUT")
+
+	actual := httptest.NewRequest("GET", "/test", nil)
+	actual.Header.Set("Origin", "https://b.example.com")
+
+	for _, bm := range []struct {
+		name string
+		req  *http.Request
+	}{
+		{"preflight", preflight},
+		{"actual", actual},
+	} {
+		b.Run(bm.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				handler.ServeHTTP(httptest.NewRecorder(), bm.req)
+			}
+		})
+	}
+}
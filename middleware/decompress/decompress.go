@@ -0,0 +1,262 @@
+package decompress
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Option is decompress option.
+type Option func(*options)
+
+// options defines the configuration for the request decompression middleware
+type options struct {
+	// MaxDecompressedBytes caps the decompressed body size, protecting
+	// against decompression bombs. Reading past it responds with 413.
+	// Default: 10MB
+	maxDecompressedBytes int64
+
+	// Strict rejects requests with an unsupported Content-Encoding with 415
+	// Default: false (such requests are passed through unchanged)
+	strict bool
+
+	// AllowZstd enables zstd request bodies
+	// Default: false
+	allowZstd bool
+}
+
+// WithMaxDecompressedBytes sets the maximum decompressed request body size
+func WithMaxDecompressedBytes(n int64) Option {
+	return func(o *options) {
+		o.maxDecompressedBytes = n
+	}
+}
+
+// WithStrict sets whether unsupported encodings are rejected with 415
+func WithStrict(strict bool) Option {
+	return func(o *options) {
+		o.strict = strict
+	}
+}
+
+// WithAllowZstd enables zstd request bodies
+func WithAllowZstd(allow bool) Option {
+	return func(o *options) {
+		o.allowZstd = allow
+	}
+}
+
+// errUnsupported is returned for content codings the middleware can't decode
+var errUnsupported = errors.New("unsupported content encoding")
+
+// supports reports whether the middleware can decode a content coding
+func (o *options) supports(coding string) bool {
+	switch coding {
+	case "identity", "gzip", "x-gzip", "deflate":
+		return true
+	case "zstd":
+		return o.allowZstd
+	}
+	return false
+}
+
+// decoder wraps r with a decompressing reader for a supported coding
+func decoder(coding string, r io.Reader) (io.ReadCloser, error) {
+	switch coding {
+	case "gzip", "x-gzip":
+		return gzip.NewReader(r)
+	case "deflate":
+		return zlib.NewReader(r)
+	case "zstd":
+		d, err := zstd.NewReader(r, zstd.WithDecoderConcurrency(1))
+		if err != nil {
+			return nil, err
+		}
+		return d.IOReadCloser(), nil
+	}
+	return nil, errUnsupported
+}
+
+// decode wraps body with decoders for the codings in a Content-Encoding
+// header value, undoing them in reverse order of application
+func (o *options) decode(header string, body io.ReadCloser) (io.ReadCloser, error) {
+	codings := strings.Split(header, ",")
+	for i, coding := range codings {
+		codings[i] = strings.ToLower(strings.TrimSpace(coding))
+	}
+
+	// Check every coding first so an unsupported one doesn't consume the body
+	for _, coding := range codings {
+		if !o.supports(coding) {
+			return nil, errUnsupported
+		}
+	}
+
+	closers := []io.Closer{body}
+	var r io.Reader = body
+	for i := len(codings) - 1; i >= 0; i-- {
+		if codings[i] == "identity" {
+			continue
+		}
+		rc, err := decoder(codings[i], r)
+		if err != nil {
+			closeAll(closers)
+			return nil, err
+		}
+		closers = append(closers, rc)
+		r = rc
+	}
+	return &decodedBody{Reader: r, closers: closers}, nil
+}
+
+// decodedBody reads the decompressed body and closes every decoder along
+// with the original body
+type decodedBody struct {
+	io.Reader
+	closers []io.Closer
+}
+
+// Close implements io.Closer
+func (b *decodedBody) Close() error {
+	return closeAll(b.closers)
+}
+
+// closeAll closes closers, innermost last, returning the first error
+func closeAll(closers []io.Closer) error {
+	var first error
+	for i := len(closers) - 1; i >= 0; i-- {
+		if err := closers[i].Close(); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// limitedBody reports when the wrapped MaxBytesReader hits the limit
+type limitedBody struct {
+	io.ReadCloser
+	onExceeded func()
+}
+
+// Read implements io.Reader
+func (b *limitedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		b.onExceeded()
+	}
+	return n, err
+}
+
+// limitWriter discards the handler's response once the middleware has
+// responded to an oversized body
+type limitWriter struct {
+	http.ResponseWriter
+	wroteHeader bool
+	rejected    bool
+}
+
+// Header implements http.ResponseWriter
+func (w *limitWriter) Header() http.Header {
+	if w.rejected {
+		// Keep the handler from altering the error response headers
+		return http.Header{}
+	}
+	return w.ResponseWriter.Header()
+}
+
+// WriteHeader implements http.ResponseWriter
+func (w *limitWriter) WriteHeader(code int) {
+	if w.rejected {
+		return
+	}
+	w.wroteHeader = true
+	w.ResponseWriter.WriteHeader(code)
+}
+
+// Write implements http.ResponseWriter
+func (w *limitWriter) Write(b []byte) (int, error) {
+	if w.rejected {
+		return len(b), nil
+	}
+	w.wroteHeader = true
+	return w.ResponseWriter.Write(b)
+}
+
+// Unwrap returns the underlying http.ResponseWriter
+func (w *limitWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// jsonError writes a JSON error response
+func jsonError(w http.ResponseWriter, code int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	w.Write([]byte(`{"error":"` + message + `"}`))
+}
+
+// New returns a middleware that transparently decompresses gzip and deflate
+// (and optionally zstd) request bodies, removing the Content-Encoding header
+// so handlers read plaintext
+func New(opts ...Option) func(http.Handler) http.Handler {
+	o := &options{
+		maxDecompressedBytes: 10 << 20, // 10MB
+	}
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	if o.maxDecompressedBytes <= 0 {
+		panic("max decompressed bytes must be greater than 0")
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			encoding := r.Header.Get("Content-Encoding")
+			if encoding == "" || r.Body == nil || r.Body == http.NoBody {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			body, err := o.decode(encoding, r.Body)
+			if errors.Is(err, errUnsupported) {
+				if o.strict {
+					jsonError(w, http.StatusUnsupportedMediaType, "unsupported content encoding")
+					return
+				}
+				next.ServeHTTP(w, r)
+				return
+			}
+			if err != nil {
+				jsonError(w, http.StatusBadRequest, "invalid compressed body")
+				return
+			}
+
+			r.Header.Del("Content-Encoding")
+			r.Header.Del("Content-Length")
+			r.ContentLength = -1
+
+			// Cap the decompressed stream and respond from the middleware once
+			// the cap is hit, unless the handler has already responded
+			lw := &limitWriter{ResponseWriter: w}
+			r.Body = &limitedBody{
+				ReadCloser: http.MaxBytesReader(w, body, o.maxDecompressedBytes),
+				onExceeded: func() {
+					if lw.rejected || lw.wroteHeader {
+						return
+					}
+					lw.rejected = true
+					jsonError(w, http.StatusRequestEntityTooLarge, "decompressed request body too large")
+				},
+			}
+
+			next.ServeHTTP(lw, r)
+		})
+	}
+}
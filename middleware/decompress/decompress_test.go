@@ -0,0 +1,210 @@
+package decompress
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+func gzipBytes(t *testing.T, data []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	zw.Write(data)
+	if err := zw.Close(); err != nil {
+		t.Fatalf("Failed to gzip: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func deflateBytes(t *testing.T, data []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zlib.NewWriter(&buf)
+	zw.Write(data)
+	if err := zw.Close(); err != nil {
+		t.Fatalf("Failed to deflate: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func zstdBytes(t *testing.T, data []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw, err := zstd.NewWriter(&buf)
+	if err != nil {
+		t.Fatalf("Failed to create zstd writer: %v", err)
+	}
+	zw.Write(data)
+	if err := zw.Close(); err != nil {
+		t.Fatalf("Failed to zstd: %v", err)
+	}
+	return buf.Bytes()
+}
+
+// echoHandler responds with the request body and the Content-Encoding it saw
+func echoHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			return
+		}
+		w.Header().Set("X-Seen-Encoding", r.Header.Get("Content-Encoding"))
+		w.WriteHeader(http.StatusOK)
+		w.Write(body)
+	})
+}
+
+func TestDecompress(t *testing.T) {
+	plain := []byte(`{"name":"widget"}`)
+
+	tests := []struct {
+		name     string
+		opts     []Option
+		encoding string
+		body     []byte
+	}{
+		{"gzip", nil, "gzip", gzipBytes(t, plain)},
+		{"x-gzip", nil, "x-gzip", gzipBytes(t, plain)},
+		{"deflate", nil, "deflate", deflateBytes(t, plain)},
+		{"zstd", []Option{WithAllowZstd(true)}, "zstd", zstdBytes(t, plain)},
+		{"Case insensitive", nil, "GZIP", gzipBytes(t, plain)},
+		{"Stacked codings", nil, "deflate, gzip", gzipBytes(t, deflateBytes(t, plain))},
+		{"Identity", nil, "identity", plain},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := New(tt.opts...)(echoHandler())
+
+			req := httptest.NewRequest("POST", "/test", bytes.NewReader(tt.body))
+			req.Header.Set("Content-Encoding", tt.encoding)
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != http.StatusOK {
+				t.Fatalf("Expected status 200, got %d", rr.Code)
+			}
+			if rr.Body.String() != string(plain) {
+				t.Errorf("Expected body %q, got %q", plain, rr.Body.String())
+			}
+			if rr.Header().Get("X-Seen-Encoding") != "" {
+				t.Errorf("Expected Content-Encoding removed, got %q", rr.Header().Get("X-Seen-Encoding"))
+			}
+		})
+	}
+}
+
+func TestDecompressUncompressed(t *testing.T) {
+	handler := New()(echoHandler())
+
+	req := httptest.NewRequest("POST", "/test", strings.NewReader("plain"))
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	if rr.Body.String() != "plain" {
+		t.Errorf("Expected body 'plain', got %q", rr.Body.String())
+	}
+}
+
+func TestDecompressUnsupported(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     []Option
+		encoding string
+		code     int
+		seen     string
+	}{
+		{"Passthrough", nil, "br", http.StatusOK, "br"},
+		{"Strict", []Option{WithStrict(true)}, "br", http.StatusUnsupportedMediaType, ""},
+		{"Zstd disabled", []Option{WithStrict(true)}, "zstd", http.StatusUnsupportedMediaType, ""},
+		{"Stacked with unsupported", []Option{WithStrict(true)}, "br, gzip", http.StatusUnsupportedMediaType, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := New(tt.opts...)(echoHandler())
+
+			req := httptest.NewRequest("POST", "/test", strings.NewReader("opaque"))
+			req.Header.Set("Content-Encoding", tt.encoding)
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != tt.code {
+				t.Errorf("Expected status %d, got %d", tt.code, rr.Code)
+			}
+			if rr.Header().Get("X-Seen-Encoding") != tt.seen {
+				t.Errorf("Expected handler to see Content-Encoding %q, got %q", tt.seen, rr.Header().Get("X-Seen-Encoding"))
+			}
+		})
+	}
+}
+
+func TestDecompressInvalidBody(t *testing.T) {
+	called := false
+	handler := New()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+
+	req := httptest.NewRequest("POST", "/test", strings.NewReader("not gzip"))
+	req.Header.Set("Content-Encoding", "gzip")
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400, got %d", rr.Code)
+	}
+	if called {
+		t.Error("Expected handler not to be called")
+	}
+}
+
+func TestDecompressBomb(t *testing.T) {
+	// 10MB of zeros compresses to about 10KB
+	bomb := gzipBytes(t, make([]byte, 10<<20))
+	if len(bomb) > 64<<10 {
+		t.Fatalf("Expected a small compressed payload, got %d bytes", len(bomb))
+	}
+
+	handler := New(WithMaxDecompressedBytes(1 << 20))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := io.ReadAll(r.Body); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest("POST", "/test", bytes.NewReader(bomb))
+	req.Header.Set("Content-Encoding", "gzip")
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("Expected status 413, got %d", rr.Code)
+	}
+	if rr.Body.String() != `{"error":"decompressed request body too large"}` {
+		t.Errorf("Expected JSON error body, got %q", rr.Body.String())
+	}
+}
+
+func TestDecompressInvalidMaxPanic(t *testing.T) {
+	defer func() {
+		if r := recover(); r == nil {
+			t.Error("Expected panic for zero max decompressed bytes")
+		}
+	}()
+
+	New(WithMaxDecompressedBytes(0))
+}
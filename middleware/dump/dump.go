@@ -0,0 +1,195 @@
+package dump
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// Option is dump option.
+type Option func(*options)
+
+// options defines the configuration for the dump middleware
+type options struct {
+	// Skipper skips dumping for a request
+	// Default: skips every request, so nothing is dumped unless enabled
+	skipper func(r *http.Request) bool
+
+	// Sink receives the request and response dumps
+	sink func(reqDump, respDump []byte, r *http.Request)
+
+	// MaxBytes caps the captured request and response bodies. Longer bodies
+	// are truncated in the dump but reach the handler and client unchanged.
+	// Default: 64KB
+	maxBytes int
+
+	// RedactHeaders are dumped with their values replaced
+	// Default: Authorization, Proxy-Authorization, Cookie, Set-Cookie
+	redactHeaders []string
+}
+
+// WithSkipper sets a function to skip the middleware for a request. Requests
+// are only dumped when it returns false.
+func WithSkipper(f func(r *http.Request) bool) Option {
+	return func(o *options) {
+		o.skipper = f
+	}
+}
+
+// WithSink sets the function receiving the request and response dumps
+func WithSink(f func(reqDump, respDump []byte, r *http.Request)) Option {
+	return func(o *options) {
+		o.sink = f
+	}
+}
+
+// WithMaxBytes sets the maximum number of body bytes captured per dump
+func WithMaxBytes(n int) Option {
+	return func(o *options) {
+		o.maxBytes = n
+	}
+}
+
+// WithRedactHeaders sets the headers whose values are redacted in dumps
+func WithRedactHeaders(headers []string) Option {
+	return func(o *options) {
+		o.redactHeaders = headers
+	}
+}
+
+// redacted replaces the values of redacted headers
+const redacted = "[REDACTED]"
+
+// redact returns a copy of h with the configured headers redacted
+func (o *options) redact(h http.Header) http.Header {
+	h = h.Clone()
+	for _, name := range o.redactHeaders {
+		if _, ok := h[http.CanonicalHeaderKey(name)]; ok {
+			h.Set(name, redacted)
+		}
+	}
+	return h
+}
+
+// dumpRequest formats the request line, redacted headers and captured body
+func (o *options) dumpRequest(r *http.Request, body []byte) []byte {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "%s %s %s\r\n", r.Method, r.URL.RequestURI(), r.Proto)
+	fmt.Fprintf(&buf, "Host: %s\r\n", r.Host)
+	o.redact(r.Header).Write(&buf)
+	buf.WriteString("\r\n")
+	buf.Write(body)
+	return buf.Bytes()
+}
+
+// dumpResponse formats the status line, redacted headers and captured body
+func (o *options) dumpResponse(w *dumpWriter) []byte {
+	header := w.header
+	if header == nil {
+		header = w.Header()
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "HTTP/1.1 %03d %s\r\n", w.status, http.StatusText(w.status))
+	o.redact(header).Write(&buf)
+	buf.WriteString("\r\n")
+	buf.Write(w.body.Bytes())
+	return buf.Bytes()
+}
+
+// dumpWriter passes the response through while capturing its head and body
+type dumpWriter struct {
+	http.ResponseWriter
+	status      int
+	header      http.Header
+	body        bytes.Buffer
+	maxBytes    int
+	wroteHeader bool
+}
+
+// WriteHeader implements http.ResponseWriter
+func (w *dumpWriter) WriteHeader(code int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+	w.status = code
+	w.header = w.Header().Clone()
+	w.ResponseWriter.WriteHeader(code)
+}
+
+// Write implements http.ResponseWriter
+func (w *dumpWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if room := w.maxBytes - w.body.Len(); room > 0 {
+		w.body.Write(b[:min(len(b), room)])
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush implements http.Flusher
+func (w *dumpWriter) Flush() {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap returns the underlying http.ResponseWriter
+func (w *dumpWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// New returns a middleware that dumps request and response bodies to a sink
+// for debugging. Nothing is dumped until WithSkipper selects requests.
+func New(opts ...Option) func(http.Handler) http.Handler {
+	o := &options{
+		skipper:       func(*http.Request) bool { return true },
+		maxBytes:      64 << 10, // 64KB
+		redactHeaders: []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie"},
+	}
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	if o.sink == nil {
+		panic("dump sink is nil")
+	}
+	if o.maxBytes <= 0 {
+		panic("dump max bytes must be greater than 0")
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if o.skipper(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			// Capture the start of the body and put it back in front of the
+			// rest so the handler still reads the full body
+			var reqBody []byte
+			if r.Body != nil && r.Body != http.NoBody {
+				// A read error surfaces again when the handler reads the rest
+				reqBody, _ = io.ReadAll(io.LimitReader(r.Body, int64(o.maxBytes)))
+				r.Body = struct {
+					io.Reader
+					io.Closer
+				}{io.MultiReader(bytes.NewReader(reqBody), r.Body), r.Body}
+			}
+			reqDump := o.dumpRequest(r, reqBody)
+
+			dw := &dumpWriter{ResponseWriter: w, status: http.StatusOK, maxBytes: o.maxBytes}
+
+			next.ServeHTTP(dw, r)
+
+			o.sink(reqDump, o.dumpResponse(dw), r)
+		})
+	}
+}
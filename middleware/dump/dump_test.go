@@ -0,0 +1,164 @@
+package dump
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func dumpAll(*http.Request) bool { return false }
+
+func TestDump(t *testing.T) {
+	var reqDump, respDump []byte
+	middleware := New(
+		WithSkipper(dumpAll),
+		WithSink(func(req, resp []byte, r *http.Request) {
+			reqDump, respDump = req, resp
+		}),
+	)
+
+	var seen string
+	handler := middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		seen = string(body)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"id":1}`))
+	}))
+
+	req := httptest.NewRequest("POST", "/items?debug=1", strings.NewReader(`{"name":"widget"}`))
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	if seen != `{"name":"widget"}` {
+		t.Errorf("Expected handler to read full body, got %q", seen)
+	}
+	if rr.Code != http.StatusCreated || rr.Body.String() != `{"id":1}` {
+		t.Errorf("Expected response to pass through, got %d %q", rr.Code, rr.Body.String())
+	}
+
+	for _, want := range []string{"POST /items?debug=1 HTTP/1.1\r\n", "Content-Type: application/json\r\n", "\r\n\r\n{\"name\":\"widget\"}"} {
+		if !strings.Contains(string(reqDump), want) {
+			t.Errorf("Expected request dump to contain %q, got %q", want, reqDump)
+		}
+	}
+	for _, want := range []string{"HTTP/1.1 201 Created\r\n", "Content-Type: application/json\r\n", "\r\n\r\n{\"id\":1}"} {
+		if !strings.Contains(string(respDump), want) {
+			t.Errorf("Expected response dump to contain %q, got %q", want, respDump)
+		}
+	}
+}
+
+func TestDumpMaxBytes(t *testing.T) {
+	var reqDump, respDump []byte
+	middleware := New(
+		WithSkipper(dumpAll),
+		WithMaxBytes(10),
+		WithSink(func(req, resp []byte, r *http.Request) {
+			reqDump, respDump = req, resp
+		}),
+	)
+
+	body := strings.Repeat("a", 100)
+	var seen string
+	handler := middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		seen = string(b)
+		w.Write([]byte(strings.Repeat("b", 100)))
+	}))
+
+	req := httptest.NewRequest("POST", "/test", strings.NewReader(body))
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	// The handler and client are unaffected by the cap
+	if seen != body {
+		t.Errorf("Expected handler to read %d bytes, got %d", len(body), len(seen))
+	}
+	if rr.Body.Len() != 100 {
+		t.Errorf("Expected 100 response bytes, got %d", rr.Body.Len())
+	}
+
+	if !strings.HasSuffix(string(reqDump), "\r\n\r\n"+strings.Repeat("a", 10)) {
+		t.Errorf("Expected request dump body truncated to 10 bytes, got %q", reqDump)
+	}
+	if !strings.HasSuffix(string(respDump), "\r\n\r\n"+strings.Repeat("b", 10)) {
+		t.Errorf("Expected response dump body truncated to 10 bytes, got %q", respDump)
+	}
+}
+
+func TestDumpRedactHeaders(t *testing.T) {
+	var reqDump, respDump []byte
+	middleware := New(
+		WithSkipper(dumpAll),
+		WithRedactHeaders([]string{"Authorization", "X-Api-Key", "Set-Cookie"}),
+		WithSink(func(req, resp []byte, r *http.Request) {
+			reqDump, respDump = req, resp
+		}),
+	)
+
+	handler := middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "secret-cookie"})
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	req.Header.Set("Authorization", "Bearer secret-token")
+	req.Header.Set("X-Api-Key", "secret-key")
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	for _, secret := range []string{"secret-token", "secret-key"} {
+		if strings.Contains(string(reqDump), secret) {
+			t.Errorf("Expected %q to be redacted from request dump", secret)
+		}
+	}
+	if !strings.Contains(string(reqDump), "Authorization: [REDACTED]\r\n") {
+		t.Errorf("Expected redacted Authorization header, got %q", reqDump)
+	}
+	if strings.Contains(string(respDump), "secret-cookie") {
+		t.Error("Expected Set-Cookie to be redacted from response dump")
+	}
+
+	// The real headers are untouched
+	if req.Header.Get("Authorization") != "Bearer secret-token" {
+		t.Error("Expected request header to be unchanged")
+	}
+	if !strings.Contains(rr.Header().Get("Set-Cookie"), "secret-cookie") {
+		t.Error("Expected response cookie to be unchanged")
+	}
+}
+
+func TestDumpDefaultSkipsAll(t *testing.T) {
+	called := false
+	middleware := New(WithSink(func(req, resp []byte, r *http.Request) {
+		called = true
+	}))
+
+	handler := middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("GET", "/test", nil))
+
+	if called {
+		t.Error("Expected nothing dumped by default")
+	}
+}
+
+func TestDumpNilSinkPanic(t *testing.T) {
+	defer func() {
+		if r := recover(); r == nil {
+			t.Error("Expected panic for nil sink")
+		}
+	}()
+
+	New(WithSkipper(dumpAll))
+}
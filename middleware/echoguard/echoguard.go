@@ -0,0 +1,96 @@
+package echoguard
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"io"
+	"net/http"
+)
+
+// Option is echo guard option.
+type Option func(*options)
+
+// options defines the configuration for echo guard middleware
+type options struct {
+	// TokenHeader is the header carrying the debug token
+	// Default: X-Debug-Token
+	tokenHeader string
+
+	// Limit is the maximum allowed size for a request body in bytes
+	// Default: 64KB
+	limit int64
+}
+
+// WithTokenHeader sets the debug token header name
+func WithTokenHeader(header string) Option {
+	return func(o *options) {
+		o.tokenHeader = header
+	}
+}
+
+// WithLimit sets the body size limit
+func WithLimit(limit int64) Option {
+	return func(o *options) {
+		o.limit = limit
+	}
+}
+
+// jsonError writes a JSON error response
+func jsonError(w http.ResponseWriter, statusCode int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	w.Write([]byte(`{"error":"` + message + `"}`))
+}
+
+// New returns a middleware guarding a debug endpoint, such as one echoing
+// the request, behind a debug token and a body size limit. Requests without
+// the token get 403 and oversized bodies get 413.
+func New(token string, opts ...Option) func(http.Handler) http.Handler {
+	o := &options{
+		tokenHeader: "X-Debug-Token",
+		limit:       64 * 1024, // 64KB
+	}
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	if token == "" {
+		panic("debug token is empty")
+	}
+	if o.limit <= 0 {
+		panic("body limit must be greater than 0")
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			got := r.Header.Get(o.tokenHeader)
+			if subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+				jsonError(w, http.StatusForbidden, "forbidden")
+				return
+			}
+
+			if r.ContentLength > o.limit {
+				jsonError(w, http.StatusRequestEntityTooLarge, "request body too large")
+				return
+			}
+
+			// Read at most one byte past the limit to catch bodies of unknown length
+			if r.Body != nil {
+				body, err := io.ReadAll(io.LimitReader(r.Body, o.limit+1))
+				r.Body.Close()
+				if err != nil {
+					jsonError(w, http.StatusBadRequest, "failed to read request body")
+					return
+				}
+				if int64(len(body)) > o.limit {
+					jsonError(w, http.StatusRequestEntityTooLarge, "request body too large")
+					return
+				}
+				r.Body = io.NopCloser(bytes.NewReader(body))
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
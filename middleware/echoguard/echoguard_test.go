@@ -0,0 +1,97 @@
+package echoguard
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func echoHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.Write(body)
+	})
+}
+
+func TestEchoGuard(t *testing.T) {
+	handler := New("debug-secret", WithLimit(100))(echoHandler())
+
+	t.Run("Missing token", func(t *testing.T) {
+		req := httptest.NewRequest("POST", "/debug/echo", strings.NewReader("hello"))
+		rr := httptest.NewRecorder()
+
+		handler.ServeHTTP(rr, req)
+
+		if rr.Code != http.StatusForbidden {
+			t.Errorf("Expected status 403, got %d", rr.Code)
+		}
+	})
+
+	t.Run("Wrong token", func(t *testing.T) {
+		req := httptest.NewRequest("POST", "/debug/echo", strings.NewReader("hello"))
+		req.Header.Set("X-Debug-Token", "guess")
+		rr := httptest.NewRecorder()
+
+		handler.ServeHTTP(rr, req)
+
+		if rr.Code != http.StatusForbidden {
+			t.Errorf("Expected status 403, got %d", rr.Code)
+		}
+	})
+
+	t.Run("Oversized body", func(t *testing.T) {
+		req := httptest.NewRequest("POST", "/debug/echo", strings.NewReader(strings.Repeat("a", 150)))
+		req.Header.Set("X-Debug-Token", "debug-secret")
+		rr := httptest.NewRecorder()
+
+		handler.ServeHTTP(rr, req)
+
+		if rr.Code != http.StatusRequestEntityTooLarge {
+			t.Errorf("Expected status 413, got %d", rr.Code)
+		}
+	})
+
+	t.Run("Oversized body of unknown length", func(t *testing.T) {
+		req := httptest.NewRequest("POST", "/debug/echo", strings.NewReader(strings.Repeat("a", 150)))
+		req.ContentLength = -1
+		req.Header.Set("X-Debug-Token", "debug-secret")
+		rr := httptest.NewRecorder()
+
+		handler.ServeHTTP(rr, req)
+
+		if rr.Code != http.StatusRequestEntityTooLarge {
+			t.Errorf("Expected status 413, got %d", rr.Code)
+		}
+	})
+
+	t.Run("Authorized small body", func(t *testing.T) {
+		req := httptest.NewRequest("POST", "/debug/echo", strings.NewReader("hello"))
+		req.Header.Set("X-Debug-Token", "debug-secret")
+		rr := httptest.NewRecorder()
+
+		handler.ServeHTTP(rr, req)
+
+		if rr.Code != http.StatusOK {
+			t.Errorf("Expected status 200, got %d", rr.Code)
+		}
+		if rr.Body.String() != "hello" {
+			t.Errorf("Expected echoed body 'hello', got %q", rr.Body.String())
+		}
+	})
+}
+
+func TestEchoGuardEmptyTokenPanic(t *testing.T) {
+	defer func() {
+		if r := recover(); r == nil {
+			t.Error("Expected panic for empty token")
+		}
+	}()
+
+	New("")
+}
@@ -0,0 +1,181 @@
+package etag
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+)
+
+// Option is etag option.
+type Option func(*options)
+
+// options defines the configuration for the ETag middleware
+type options struct {
+	// Weak generates weak (W/"...") instead of strong ETags
+	// Default: false
+	weak bool
+
+	// MaxBytes is the largest response buffered to compute an ETag.
+	// Larger responses are streamed without one.
+	// Default: 1MB
+	maxBytes int
+}
+
+// WithWeak sets whether generated ETags are weak
+func WithWeak(weak bool) Option {
+	return func(o *options) {
+		o.weak = weak
+	}
+}
+
+// WithMaxBytes sets the largest response buffered to compute an ETag
+func WithMaxBytes(n int) Option {
+	return func(o *options) {
+		o.maxBytes = n
+	}
+}
+
+// etagWriter buffers a 200 response until it can be hashed
+type etagWriter struct {
+	http.ResponseWriter
+	status      int
+	buf         bytes.Buffer
+	maxBytes    int
+	wroteHeader bool
+	passthrough bool
+}
+
+// WriteHeader implements http.ResponseWriter
+func (w *etagWriter) WriteHeader(code int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+	w.status = code
+
+	// Only 200 responses get an ETag
+	if code != http.StatusOK {
+		w.passthrough = true
+		w.ResponseWriter.WriteHeader(code)
+	}
+}
+
+// Write implements http.ResponseWriter
+func (w *etagWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.passthrough {
+		return w.ResponseWriter.Write(b)
+	}
+	if w.buf.Len()+len(b) > w.maxBytes {
+		if err := w.startPassthrough(); err != nil {
+			return 0, err
+		}
+		return w.ResponseWriter.Write(b)
+	}
+	return w.buf.Write(b)
+}
+
+// Flush implements http.Flusher. Flushing streams the response without an ETag.
+func (w *etagWriter) Flush() {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if !w.passthrough {
+		w.startPassthrough()
+	}
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap returns the underlying http.ResponseWriter
+func (w *etagWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// startPassthrough writes the buffered response and stops buffering
+func (w *etagWriter) startPassthrough() error {
+	w.passthrough = true
+	w.ResponseWriter.WriteHeader(w.status)
+	_, err := w.ResponseWriter.Write(w.buf.Bytes())
+	w.buf.Reset()
+	return err
+}
+
+// generate returns the ETag for body
+func generate(body []byte, weak bool) string {
+	sum := sha256.Sum256(body)
+	tag := `"` + hex.EncodeToString(sum[:16]) + `"`
+	if weak {
+		return "W/" + tag
+	}
+	return tag
+}
+
+// noneMatch reports whether the If-None-Match header value matches etag.
+// If-None-Match uses weak comparison, so W/ prefixes are ignored.
+func noneMatch(header, etag string) bool {
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// New returns an ETag middleware that hashes GET and HEAD 200 responses and
+// answers matching If-None-Match requests with 304 Not Modified
+func New(opts ...Option) func(http.Handler) http.Handler {
+	o := &options{
+		maxBytes: 1 << 20, // 1MB
+	}
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	if o.maxBytes <= 0 {
+		panic("etag max bytes must be greater than 0")
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodGet && r.Method != http.MethodHead {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			ew := &etagWriter{ResponseWriter: w, status: http.StatusOK, maxBytes: o.maxBytes}
+
+			next.ServeHTTP(ew, r)
+
+			if ew.passthrough {
+				return
+			}
+
+			// Keep an ETag set by the handler
+			etag := w.Header().Get("ETag")
+			if etag == "" {
+				etag = generate(ew.buf.Bytes(), o.weak)
+				w.Header().Set("ETag", etag)
+			}
+
+			if match := r.Header.Get("If-None-Match"); match != "" && noneMatch(match, etag) {
+				h := w.Header()
+				h.Del("Content-Type")
+				h.Del("Content-Length")
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+
+			w.WriteHeader(http.StatusOK)
+			w.Write(ew.buf.Bytes())
+		})
+	}
+}
@@ -0,0 +1,162 @@
+package etag
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// configHandler serves a static JSON body
+var configHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write([]byte(`{"feature":true}`))
+})
+
+func TestETag(t *testing.T) {
+	handler := New()(configHandler)
+
+	// First request gets the body and an ETag
+	req := httptest.NewRequest("GET", "/config", nil)
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", rr.Code)
+	}
+	if rr.Body.String() != `{"feature":true}` {
+		t.Errorf("Unexpected body %s", rr.Body.String())
+	}
+
+	etag := rr.Header().Get("ETag")
+	if etag == "" || strings.HasPrefix(etag, "W/") {
+		t.Fatalf("Expected strong ETag, got %q", etag)
+	}
+
+	// Conditional request with the same ETag is not modified
+	req = httptest.NewRequest("GET", "/config", nil)
+	req.Header.Set("If-None-Match", etag)
+	rr = httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusNotModified {
+		t.Errorf("Expected status 304, got %d", rr.Code)
+	}
+	if rr.Body.Len() != 0 {
+		t.Errorf("Expected empty body for 304, got %s", rr.Body.String())
+	}
+	if rr.Header().Get("ETag") != etag {
+		t.Errorf("Expected ETag %s on 304, got %s", etag, rr.Header().Get("ETag"))
+	}
+}
+
+func TestETagIfNoneMatch(t *testing.T) {
+	handler := New()(configHandler)
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("GET", "/config", nil))
+	etag := rr.Header().Get("ETag")
+
+	tests := []struct {
+		name           string
+		ifNoneMatch    string
+		expectedStatus int
+	}{
+		{"Stale ETag", `"stale"`, http.StatusOK},
+		{"List containing ETag", `"stale", ` + etag, http.StatusNotModified},
+		{"Weak form of ETag", "W/" + etag, http.StatusNotModified},
+		{"Wildcard", "*", http.StatusNotModified},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/config", nil)
+			req.Header.Set("If-None-Match", tt.ifNoneMatch)
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d", tt.expectedStatus, rr.Code)
+			}
+		})
+	}
+}
+
+func TestETagWithWeak(t *testing.T) {
+	handler := New(WithWeak(true))(configHandler)
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("GET", "/config", nil))
+
+	if !strings.HasPrefix(rr.Header().Get("ETag"), `W/"`) {
+		t.Errorf("Expected weak ETag, got %q", rr.Header().Get("ETag"))
+	}
+}
+
+func TestETagSkipped(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		status int
+	}{
+		{"POST request", "POST", http.StatusOK},
+		{"Non-200 status", "GET", http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := New()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				w.Write([]byte("body"))
+			}))
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, httptest.NewRequest(tt.method, "/test", nil))
+
+			if rr.Header().Get("ETag") != "" {
+				t.Errorf("Expected no ETag, got %s", rr.Header().Get("ETag"))
+			}
+			if rr.Code != tt.status || rr.Body.String() != "body" {
+				t.Errorf("Expected response to pass through, got %d %s", rr.Code, rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestETagWithMaxBytes(t *testing.T) {
+	body := strings.Repeat("a", 100)
+	handler := New(WithMaxBytes(50))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(body[:40]))
+		w.Write([]byte(body[40:]))
+	}))
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("GET", "/test", nil))
+
+	if rr.Header().Get("ETag") != "" {
+		t.Errorf("Expected no ETag for response over MaxBytes, got %s", rr.Header().Get("ETag"))
+	}
+	if rr.Body.String() != body {
+		t.Error("Expected full body to be streamed")
+	}
+}
+
+func TestETagHandlerSetETag(t *testing.T) {
+	handler := New()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"v1"`)
+		w.Write([]byte("body"))
+	}))
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	req.Header.Set("If-None-Match", `"v1"`)
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusNotModified {
+		t.Errorf("Expected status 304 for handler ETag, got %d", rr.Code)
+	}
+}
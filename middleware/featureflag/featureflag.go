@@ -0,0 +1,76 @@
+package featureflag
+
+import (
+	"net/http"
+	"strings"
+)
+
+// Option is featureflag option.
+type Option func(*options)
+
+// options defines the configuration for the feature flag middleware
+type options struct {
+	// Flag is the name of the flag gating the routes
+	// Required
+	flag string
+
+	// Status is the status code returned while the flag is off. 404 hides
+	// that the routes exist; 403 reveals them as forbidden.
+	// Default: 404
+	status int
+}
+
+// WithFlag sets the name of the flag gating the routes
+func WithFlag(name string) Option {
+	return func(o *options) {
+		o.flag = name
+	}
+}
+
+// WithStatus sets the status code returned while the flag is off, e.g.
+// http.StatusForbidden. It must be a 4xx.
+func WithStatus(code int) Option {
+	return func(o *options) {
+		o.status = code
+	}
+}
+
+// New returns a middleware that only calls the next handler when check
+// reports the flag as on for the request, so flags can be per user.
+// Otherwise it responds with 404, hiding the routes. It panics if check is
+// nil, no flag is set or the status is not a 4xx.
+func New(check func(flag string, r *http.Request) bool, opts ...Option) func(http.Handler) http.Handler {
+	if check == nil {
+		panic("featureflag check is nil")
+	}
+
+	o := &options{
+		status: http.StatusNotFound,
+	}
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	if o.flag == "" {
+		panic("featureflag flag name is required")
+	}
+	if o.status < 400 || o.status > 499 {
+		panic("featureflag status must be a 4xx status code")
+	}
+
+	body := `{"error":"` + strings.ToLower(http.StatusText(o.status)) + `"}`
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !check(o.flag, r) {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(o.status)
+				w.Write([]byte(body))
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
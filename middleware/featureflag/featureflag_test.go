@@ -0,0 +1,116 @@
+package featureflag
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// flags is a check backed by per-user flag sets
+func flags(enabled map[string][]string) func(string, *http.Request) bool {
+	return func(flag string, r *http.Request) bool {
+		for _, f := range enabled[r.Header.Get("X-User-ID")] {
+			if f == flag {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+func TestFeatureFlag(t *testing.T) {
+	check := flags(map[string][]string{
+		"beta-user": {"new-checkout"},
+	})
+
+	tests := []struct {
+		name     string
+		opts     []Option
+		user     string
+		expected int
+		body     string
+		called   bool
+	}{
+		{"On", []Option{WithFlag("new-checkout")}, "beta-user", http.StatusOK, "ok", true},
+		{"Off hides route", []Option{WithFlag("new-checkout")}, "other-user", http.StatusNotFound, `{"error":"not found"}`, false},
+		{"Other flag off", []Option{WithFlag("new-search")}, "beta-user", http.StatusNotFound, `{"error":"not found"}`, false},
+		{"Off with 403", []Option{WithFlag("new-checkout"), WithStatus(http.StatusForbidden)}, "other-user", http.StatusForbidden, `{"error":"forbidden"}`, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			called := false
+			handler := New(check, tt.opts...)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				called = true
+				w.Write([]byte("ok"))
+			}))
+
+			req := httptest.NewRequest("GET", "/checkout", nil)
+			req.Header.Set("X-User-ID", tt.user)
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != tt.expected {
+				t.Errorf("Expected status %d, got %d", tt.expected, rr.Code)
+			}
+			if rr.Body.String() != tt.body {
+				t.Errorf("Expected body %q, got %q", tt.body, rr.Body.String())
+			}
+			if called != tt.called {
+				t.Errorf("Expected handler called %v, got %v", tt.called, called)
+			}
+		})
+	}
+}
+
+func TestFeatureFlagRuntimeToggle(t *testing.T) {
+	on := false
+	handler := New(func(flag string, r *http.Request) bool { return on }, WithFlag("launch"))(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		}),
+	)
+
+	for _, tt := range []struct {
+		on       bool
+		expected int
+	}{
+		{false, http.StatusNotFound},
+		{true, http.StatusOK},
+		{false, http.StatusNotFound},
+	} {
+		on = tt.on
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest("GET", "/launch", nil))
+
+		if rr.Code != tt.expected {
+			t.Errorf("Flag on=%v: expected status %d, got %d", tt.on, tt.expected, rr.Code)
+		}
+	}
+}
+
+func TestFeatureFlagPanics(t *testing.T) {
+	tests := []struct {
+		name  string
+		check func(string, *http.Request) bool
+		opts  []Option
+	}{
+		{"Nil check", nil, []Option{WithFlag("launch")}},
+		{"Missing flag", func(string, *http.Request) bool { return true }, nil},
+		{"Success status", func(string, *http.Request) bool { return true }, []Option{WithFlag("launch"), WithStatus(http.StatusOK)}},
+		{"Server error status", func(string, *http.Request) bool { return true }, []Option{WithFlag("launch"), WithStatus(http.StatusServiceUnavailable)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				if r := recover(); r == nil {
+					t.Error("Expected panic")
+				}
+			}()
+
+			New(tt.check, tt.opts...)
+		})
+	}
+}
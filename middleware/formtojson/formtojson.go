@@ -0,0 +1,161 @@
+package formtojson
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+)
+
+// Option is form to JSON option.
+type Option func(*options)
+
+// options defines the configuration for form to JSON middleware
+type options struct {
+	// TypeCoercion converts values that look like booleans or numbers
+	// into JSON booleans and numbers instead of strings
+	// Default: false
+	typeCoercion bool
+
+	// ArrayFields are fields always encoded as arrays, even with a single value
+	// Default: [] (only repeated keys become arrays)
+	arrayFields map[string]bool
+
+	// MaxBytes caps the size of the form body. Larger bodies are rejected
+	// with 413.
+	// Default: 1MB
+	maxBytes int64
+}
+
+// WithTypeCoercion enables conversion of boolean and numeric values
+func WithTypeCoercion(enabled bool) Option {
+	return func(o *options) {
+		o.typeCoercion = enabled
+	}
+}
+
+// WithArrayFields sets fields that are always encoded as arrays
+func WithArrayFields(fields ...string) Option {
+	return func(o *options) {
+		for _, f := range fields {
+			o.arrayFields[f] = true
+		}
+	}
+}
+
+// WithMaxBytes sets the maximum form body size
+func WithMaxBytes(n int64) Option {
+	return func(o *options) {
+		o.maxBytes = n
+	}
+}
+
+// jsonNumber matches JSON number literals, which excludes NaN, Inf, hex and
+// leading zeros such as "007"
+var jsonNumber = regexp.MustCompile(`^-?(0|[1-9][0-9]*)(\.[0-9]+)?([eE][+-]?[0-9]+)?$`)
+
+// coerce converts a form value to a JSON value. Numbers are passed through
+// as written so large integers keep their precision.
+func (o *options) coerce(v string) interface{} {
+	if !o.typeCoercion {
+		return v
+	}
+	if v == "true" || v == "false" {
+		return v == "true"
+	}
+	if jsonNumber.MatchString(v) {
+		// Out of range values like 1e999 can't be decoded as numbers
+		if _, err := strconv.ParseFloat(v, 64); err == nil {
+			return json.Number(v)
+		}
+	}
+	return v
+}
+
+// convert builds the JSON object for the parsed form
+func (o *options) convert(form url.Values) map[string]interface{} {
+	obj := make(map[string]interface{}, len(form))
+	for key, values := range form {
+		if len(values) == 1 && !o.arrayFields[key] {
+			obj[key] = o.coerce(values[0])
+			continue
+		}
+
+		arr := make([]interface{}, len(values))
+		for i, v := range values {
+			arr[i] = o.coerce(v)
+		}
+		obj[key] = arr
+	}
+	return obj
+}
+
+// jsonError writes a JSON error response
+func jsonError(w http.ResponseWriter, code int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	w.Write([]byte(`{"error":"` + message + `"}`))
+}
+
+// New returns a middleware that converts form-urlencoded request bodies to JSON
+func New(opts ...Option) func(http.Handler) http.Handler {
+	o := &options{
+		arrayFields: make(map[string]bool),
+		maxBytes:    1 << 20, // 1MB
+	}
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	if o.maxBytes <= 0 {
+		panic("max bytes must be greater than 0")
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+			if err != nil || mediaType != "application/x-www-form-urlencoded" || r.Body == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, o.maxBytes))
+			r.Body.Close()
+			if err != nil {
+				var maxBytesErr *http.MaxBytesError
+				if errors.As(err, &maxBytesErr) {
+					jsonError(w, http.StatusRequestEntityTooLarge, "request body too large")
+					return
+				}
+				jsonError(w, http.StatusBadRequest, "failed to read body")
+				return
+			}
+
+			form, err := url.ParseQuery(string(body))
+			if err != nil {
+				jsonError(w, http.StatusBadRequest, "invalid form body")
+				return
+			}
+
+			data, err := json.Marshal(o.convert(form))
+			if err != nil {
+				jsonError(w, http.StatusBadRequest, "invalid form body")
+				return
+			}
+
+			// Rewrite the request as a JSON request
+			r.Body = io.NopCloser(bytes.NewReader(data))
+			r.ContentLength = int64(len(data))
+			r.Header.Set("Content-Type", "application/json")
+			r.Header.Set("Content-Length", strconv.Itoa(len(data)))
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,165 @@
+package formtojson
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// captureJSON returns a handler decoding the JSON body it receives
+func captureJSON(t *testing.T, contentType *string, got *map[string]interface{}) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*contentType = r.Header.Get("Content-Type")
+		if err := json.NewDecoder(r.Body).Decode(got); err != nil {
+			t.Errorf("Failed to decode JSON body: %v", err)
+		}
+	})
+}
+
+func TestFormToJSON(t *testing.T) {
+	var contentType string
+	var got map[string]interface{}
+	handler := New()(captureJSON(t, &contentType, &got))
+
+	req := httptest.NewRequest("POST", "/submit", strings.NewReader("name=alice&tag=a&tag=b&age=30"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	if contentType != "application/json" {
+		t.Errorf("Expected Content-Type 'application/json', got %q", contentType)
+	}
+
+	expected := map[string]interface{}{
+		"name": "alice",
+		"tag":  []interface{}{"a", "b"},
+		"age":  "30",
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %v, got %v", expected, got)
+	}
+}
+
+func TestFormToJSONTypeCoercion(t *testing.T) {
+	var contentType string
+	var got map[string]interface{}
+	handler := New(WithTypeCoercion(true), WithArrayFields("ids"))(captureJSON(t, &contentType, &got))
+
+	req := httptest.NewRequest("POST", "/submit", strings.NewReader("age=30&price=9.5&active=true&ids=1&name=bob"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded; charset=utf-8")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	expected := map[string]interface{}{
+		"age":    float64(30),
+		"price":  9.5,
+		"active": true,
+		"ids":    []interface{}{float64(1)},
+		"name":   "bob",
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %v, got %v", expected, got)
+	}
+}
+
+func TestFormToJSONSkipsOtherContentTypes(t *testing.T) {
+	var body string
+	handler := New()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		body = string(b)
+	}))
+
+	req := httptest.NewRequest("POST", "/submit", strings.NewReader(`{"name":"alice"}`))
+	req.Header.Set("Content-Type", "application/json")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	if body != `{"name":"alice"}` {
+		t.Errorf("Expected body to be untouched, got %q", body)
+	}
+}
+
+func TestFormToJSONInvalidBody(t *testing.T) {
+	handler := New()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("Handler should not be called")
+	}))
+
+	req := httptest.NewRequest("POST", "/submit", strings.NewReader("name=%zz"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400, got %d", rr.Code)
+	}
+	if rr.Header().Get("Content-Type") != "application/json" {
+		t.Errorf("Expected JSON error, got Content-Type %q", rr.Header().Get("Content-Type"))
+	}
+}
+
+func TestFormToJSONCoercionEdgeCases(t *testing.T) {
+	var contentType string
+	var got map[string]interface{}
+	handler := New(WithTypeCoercion(true))(captureJSON(t, &contentType, &got))
+
+	form := "nan=NaN&inf=Inf&infinity=-infinity&zip=007&zero=0&neg=-0.5&exp=1e3&big=1e999&hex=0x1F&id=123456789012345678901234567890"
+	req := httptest.NewRequest("POST", "/submit", strings.NewReader(form))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	expected := map[string]interface{}{
+		"nan":      "NaN",
+		"inf":      "Inf",
+		"infinity": "-infinity",
+		"zip":      "007",
+		"zero":     float64(0),
+		"neg":      -0.5,
+		"exp":      float64(1000),
+		"big":      "1e999",
+		"hex":      "0x1F",
+		"id":       1.2345678901234568e+29,
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %v, got %v", expected, got)
+	}
+}
+
+func TestFormToJSONKeepsIntegerPrecision(t *testing.T) {
+	var body string
+	handler := New(WithTypeCoercion(true))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		body = string(b)
+	}))
+
+	req := httptest.NewRequest("POST", "/submit", strings.NewReader("id=123456789012345678901234567890"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	if body != `{"id":123456789012345678901234567890}` {
+		t.Errorf("Expected the integer to be passed through as written, got %s", body)
+	}
+}
+
+func TestFormToJSONWithMaxBytes(t *testing.T) {
+	handler := New(WithMaxBytes(16))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("Handler should not be called")
+	}))
+
+	req := httptest.NewRequest("POST", "/submit", strings.NewReader("name="+strings.Repeat("a", 100)))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("Expected status 413, got %d", rr.Code)
+	}
+	if rr.Body.String() != `{"error":"request body too large"}` {
+		t.Errorf("Expected JSON error body, got %q", rr.Body.String())
+	}
+}
@@ -4,8 +4,12 @@ import (
 	"compress/gzip"
 	"io"
 	"net/http"
+	"strconv"
 	"strings"
 	"sync"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
 )
 
 // GzipOption is gzip option.
@@ -18,7 +22,9 @@ type options struct {
 	level int
 
 	// MinLength is the minimum response size to compress
-	// Responses smaller than this will not be compressed
+	// Responses smaller than this will not be compressed. 0 compresses every
+	// response the client accepts
+	// Default: 1024
 	minLength int
 
 	// ExcludedExtensions is a list of file extensions to exclude from compression
@@ -26,6 +32,20 @@ type options struct {
 
 	// ExcludedPaths is a list of URL paths to exclude from compression
 	excludedPaths []string
+
+	// FlushContentTypes is a list of streaming content types that are
+	// compressed immediately and flushed after each Write
+	// Default: application/x-ndjson, text/event-stream
+	flushContentTypes []string
+
+	// FlushInterval batches the flushes of streaming responses, flushing at
+	// most this long after a Write instead of after every Write
+	// Default: 0 (flush after every Write)
+	flushInterval time.Duration
+
+	// AllowZstd enables zstd when the client prefers it over gzip
+	// Default: false
+	allowZstd bool
 }
 
 // WithLevel sets the compression level
@@ -35,7 +55,8 @@ func WithLevel(level int) Option {
 	}
 }
 
-// WithMinLength sets the minimum response size to compress
+// WithMinLength sets the minimum response size to compress. 0 disables the
+// threshold so every response is compressed
 func WithMinLength(length int) Option {
 	return func(o *options) {
 		o.minLength = length
@@ -56,15 +77,55 @@ func WithExcludedPaths(paths []string) Option {
 	}
 }
 
+// WithFlushContentTypes sets the streaming content types that are flushed after each Write
+func WithFlushContentTypes(contentTypes []string) Option {
+	return func(o *options) {
+		o.flushContentTypes = contentTypes
+	}
+}
+
+// WithFlushInterval sets the maximum delay before written data of a
+// streaming response is flushed, trading timeliness for better compression
+// of many small writes. Explicit Flush calls still flush immediately. Zero
+// or a negative value flushes after every Write.
+func WithFlushInterval(d time.Duration) Option {
+	return func(o *options) {
+		o.flushInterval = d
+	}
+}
+
+// WithAllowZstd enables zstd encoding for clients that prefer it
+func WithAllowZstd(allow bool) Option {
+	return func(o *options) {
+		o.allowZstd = allow
+	}
+}
+
+// compressor is the common interface of the gzip and zstd writers
+type compressor interface {
+	io.Writer
+	Flush() error
+	Close() error
+}
+
 // gzipResponseWriter wraps http.ResponseWriter to compress response
 type gzipResponseWriter struct {
 	http.ResponseWriter
-	writer         *gzip.Writer
+	writer         compressor
+	encoding       string
 	wroteHeader    bool
 	headersSent    bool
+	status         int
 	minLength      int
 	buffer         []byte
 	shouldCompress *bool  // Use pointer to track uninitialized state
+	flushTypes     []string
+	autoFlush      bool
+	flushInterval  time.Duration
+	timedFlush     bool        // streaming response flushed by flushTimer
+	flushTimer     *time.Timer // pending timed flush, nil if none
+	closed         bool
+	mu             sync.Mutex // serializes timed flushes with the handler
 }
 
 // gzipWriterPool is a pool of gzip writers
@@ -75,54 +136,167 @@ var gzipWriterPool = sync.Pool{
 	},
 }
 
-// newGzipResponseWriter creates a new gzip response writer
-func newGzipResponseWriter(w http.ResponseWriter, level, minLength int) *gzipResponseWriter {
-	gw := gzipWriterPool.Get().(*gzip.Writer)
-	gw.Reset(w)
+// zstdEncoderPool is a pool of zstd encoders
+var zstdEncoderPool = sync.Pool{
+	New: func() interface{} {
+		w, _ := zstd.NewWriter(nil, zstd.WithEncoderConcurrency(1))
+		return w
+	},
+}
+
+// newGzipResponseWriter creates a new response writer compressing with encoding
+func newGzipResponseWriter(w http.ResponseWriter, encoding string, level, minLength int, flushTypes []string, flushInterval time.Duration) *gzipResponseWriter {
+	var cw compressor
+	if encoding == "zstd" {
+		zw := zstdEncoderPool.Get().(*zstd.Encoder)
+		zw.Reset(w)
+		cw = zw
+	} else {
+		gw := gzipWriterPool.Get().(*gzip.Writer)
+		gw.Reset(w)
+		cw = gw
+	}
 
 	return &gzipResponseWriter{
 		ResponseWriter: w,
-		writer:         gw,
+		writer:         cw,
+		encoding:       encoding,
 		minLength:      minLength,
 		buffer:         make([]byte, 0, minLength),
 		shouldCompress: nil,  // Uninitialized - will decide later
+		flushTypes:     flushTypes,
+		flushInterval:  flushInterval,
 	}
 }
 
+// isStreaming reports whether the response Content-Type is a configured streaming type
+func (w *gzipResponseWriter) isStreaming() bool {
+	if len(w.flushTypes) == 0 {
+		return false
+	}
+	contentType := w.Header().Get("Content-Type")
+	if i := strings.IndexByte(contentType, ';'); i >= 0 {
+		contentType = contentType[:i]
+	}
+	contentType = strings.TrimSpace(contentType)
+	for _, t := range w.flushTypes {
+		if strings.EqualFold(contentType, t) {
+			return true
+		}
+	}
+	return false
+}
+
 // WriteHeader implements http.ResponseWriter
 func (w *gzipResponseWriter) WriteHeader(code int) {
 	if w.wroteHeader {
 		return
 	}
 	w.wroteHeader = true
+	w.status = code
+
+	w.decideEarly(code)
+
+	// Otherwise the decision waits until enough of the body is buffered or
+	// the handler returns, as for an implicit 200
+	if w.shouldCompress != nil {
+		w.sendHeader(nil)
+	}
+}
+
+// decideEarly makes the compression decision for responses that don't
+// depend on the body length
+func (w *gzipResponseWriter) decideEarly(code int) {
+	if w.shouldCompress != nil {
+		return
+	}
 
-	// Don't compress if status code indicates no body
-	if code == http.StatusNoContent || code == http.StatusNotModified {
+	// Don't compress if status code indicates no body, or a byte range whose
+	// offsets refer to the uncompressed representation
+	if code == http.StatusNoContent || code == http.StatusNotModified || code == http.StatusPartialContent {
 		compress := false
 		w.shouldCompress = &compress
+		return
+	}
+
+	// Streaming responses are compressed regardless of length and flushed
+	// per Write, or within the flush interval if set
+	if w.isStreaming() {
+		compress := true
+		w.shouldCompress = &compress
+		if w.flushInterval > 0 {
+			w.timedFlush = true
+		} else {
+			w.autoFlush = true
+		}
+		return
 	}
 
-	// If compression decision is not made yet, decide based on buffered content
-	if w.shouldCompress == nil {
-		compress := len(w.buffer) >= w.minLength
+	// A Content-Length set by the handler gives the size without buffering
+	if n, err := strconv.ParseInt(w.Header().Get("Content-Length"), 10, 64); err == nil && n >= 0 {
+		compress := n > 0 && n >= int64(w.minLength)
 		w.shouldCompress = &compress
 	}
+}
+
+// sendHeader writes the status code and headers once compression is decided.
+// pending is data about to be written after the buffer.
+func (w *gzipResponseWriter) sendHeader(pending []byte) {
+	if !w.wroteHeader {
+		w.wroteHeader = true
+		w.status = http.StatusOK
+	}
 
 	// Set Content-Encoding header if compressing
 	if *w.shouldCompress {
-		w.Header().Set("Content-Encoding", "gzip")
+		w.Header().Set("Content-Encoding", w.encoding)
 		w.Header().Del("Content-Length")
 		w.Header().Add("Vary", "Accept-Encoding")
+	} else {
+		w.sniffContentType(pending)
 	}
 
 	w.headersSent = true
-	w.ResponseWriter.WriteHeader(code)
+	w.ResponseWriter.WriteHeader(w.status)
+}
+
+// sniffContentType sets the Content-Type of an uncompressed response from the
+// start of its body, as net/http would for an unwrapped writer. Writers that
+// only sniff when WriteHeader wasn't called would otherwise miss it, because
+// the header is sent before the buffered body.
+func (w *gzipResponseWriter) sniffContentType(pending []byte) {
+	if _, ok := w.Header()["Content-Type"]; ok || w.Header().Get("Content-Encoding") != "" {
+		return
+	}
+	if w.status < 200 || w.status == http.StatusNoContent || w.status == http.StatusNotModified {
+		return
+	}
+
+	// The buffered bytes come first, as they are written first
+	data := w.buffer
+	if len(data) < sniffLen && len(pending) > 0 {
+		data = append(data[:len(data):len(data)], pending[:min(len(pending), sniffLen-len(data))]...)
+	}
+	if len(data) > 0 {
+		w.Header().Set("Content-Type", http.DetectContentType(data))
+	}
 }
 
+// sniffLen is the number of bytes http.DetectContentType considers
+const sniffLen = 512
+
 // Write implements http.ResponseWriter
 func (w *gzipResponseWriter) Write(b []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
 	// If headers haven't been sent yet, decide on compression
 	if !w.headersSent {
+		// Streaming responses skip buffering
+		if !w.wroteHeader {
+			w.decideEarly(http.StatusOK)
+		}
+
 		// Buffer data until we can make a decision or reach minimum length
 		if w.shouldCompress == nil && len(w.buffer)+len(b) < w.minLength {
 			w.buffer = append(w.buffer, b...)
@@ -136,10 +310,7 @@ func (w *gzipResponseWriter) Write(b []byte) (int, error) {
 			w.shouldCompress = &compress
 		}
 
-		// Write headers
-		if !w.wroteHeader {
-			w.WriteHeader(http.StatusOK)
-		}
+		w.sendHeader(b)
 	}
 
 	// If not compressing, write directly
@@ -162,20 +333,84 @@ func (w *gzipResponseWriter) Write(b []byte) (int, error) {
 		w.buffer = nil
 	}
 
-	return w.writer.Write(b)
+	n, err := w.writer.Write(b)
+	if err == nil && w.autoFlush {
+		w.flush()
+	}
+	if err == nil && w.timedFlush && w.flushTimer == nil {
+		w.flushTimer = time.AfterFunc(w.flushInterval, w.timerFlush)
+	}
+	return n, err
+}
+
+// timerFlush flushes data written since the timer was armed
+func (w *gzipResponseWriter) timerFlush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.flushTimer = nil
+	if !w.closed {
+		w.flush()
+	}
+}
+
+// Flush implements http.Flusher
+func (w *gzipResponseWriter) Flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.flush()
+}
+
+// flush implements Flush. The caller must hold mu.
+func (w *gzipResponseWriter) flush() {
+	// Decide on compression with whatever has been buffered so far
+	if !w.headersSent {
+		if w.shouldCompress == nil {
+			compress := w.isStreaming() || len(w.buffer) >= w.minLength
+			w.shouldCompress = &compress
+		}
+		w.sendHeader(nil)
+	}
+
+	if len(w.buffer) > 0 {
+		if *w.shouldCompress {
+			w.writer.Write(w.buffer)
+		} else {
+			w.ResponseWriter.Write(w.buffer)
+		}
+		w.buffer = nil
+	}
+
+	if *w.shouldCompress {
+		w.writer.Flush()
+	}
+
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
 }
 
 // Close closes the gzip writer and returns it to the pool
 func (w *gzipResponseWriter) Close() error {
-	// If we still have buffered data and no decision was made, make one now
-	if w.shouldCompress == nil && len(w.buffer) > 0 {
-		compress := len(w.buffer) >= w.minLength
-		w.shouldCompress = &compress
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	// The final flush happens here, so a pending timed flush is dropped
+	w.closed = true
+	if w.flushTimer != nil {
+		w.flushTimer.Stop()
+		w.flushTimer = nil
+	}
 
-		// Write headers if not already written
-		if !w.wroteHeader {
-			w.WriteHeader(http.StatusOK)
+	// Send pending headers, deciding on compression with what was buffered.
+	// An empty body is never compressed.
+	if !w.headersSent && (w.wroteHeader || len(w.buffer) > 0) {
+		if w.shouldCompress == nil {
+			compress := len(w.buffer) > 0 && len(w.buffer) >= w.minLength
+			w.shouldCompress = &compress
 		}
+		w.sendHeader(nil)
 	}
 
 	// Write any remaining buffered data
@@ -201,10 +436,58 @@ func (w *gzipResponseWriter) Close() error {
 		}
 	}
 
-	gzipWriterPool.Put(w.writer)
+	w.release()
 	return nil
 }
 
+// release returns the writer to its pool
+func (w *gzipResponseWriter) release() {
+	switch cw := w.writer.(type) {
+	case *gzip.Writer:
+		gzipWriterPool.Put(cw)
+	case *zstd.Encoder:
+		// Drop the reference to the response writer before pooling
+		cw.Reset(nil)
+		zstdEncoderPool.Put(cw)
+	}
+}
+
+// negotiate returns the encoding to use for an Accept-Encoding header value,
+// or "" if none is acceptable. The highest q-value wins; zstd is preferred
+// on ties when allowed.
+func negotiate(acceptEncoding string, allowZstd bool) string {
+	var gzipQ, zstdQ float64
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		name, params, _ := strings.Cut(part, ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+
+		q := 1.0
+		for _, param := range strings.Split(params, ";") {
+			key, value, ok := strings.Cut(strings.TrimSpace(param), "=")
+			if ok && strings.EqualFold(key, "q") {
+				if v, err := strconv.ParseFloat(value, 64); err == nil {
+					q = v
+				}
+			}
+		}
+
+		switch name {
+		case "gzip", "x-gzip":
+			gzipQ = max(gzipQ, q)
+		case "zstd":
+			zstdQ = max(zstdQ, q)
+		}
+	}
+
+	if allowZstd && zstdQ > 0 && zstdQ >= gzipQ {
+		return "zstd"
+	}
+	if gzipQ > 0 {
+		return "gzip"
+	}
+	return ""
+}
+
 // Gzip returns a gzip middleware with optional configuration
 func New(opts ...Option) func(http.Handler) http.Handler {
 	o := &options{
@@ -216,6 +499,7 @@ func New(opts ...Option) func(http.Handler) http.Handler {
 			".mp4", ".avi", ".mov", ".mp3", ".wav",
 			".pdf",
 		},
+		flushContentTypes: []string{"application/x-ndjson", "text/event-stream"},
 	}
 
 	for _, opt := range opts {
@@ -226,14 +510,21 @@ func New(opts ...Option) func(http.Handler) http.Handler {
 	if o.level < gzip.HuffmanOnly || o.level > gzip.BestCompression {
 		o.level = gzip.DefaultCompression
 	}
-	if o.minLength <= 0 {
+	if o.minLength < 0 {
 		o.minLength = 1024
 	}
 
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			// Check if client accepts gzip
-			if !strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") {
+			// Check if client accepts gzip (or zstd)
+			encoding := negotiate(r.Header.Get("Accept-Encoding"), o.allowZstd)
+			if encoding == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			// Range responses are served as identity so byte offsets stay valid
+			if r.Header.Get("Range") != "" {
 				next.ServeHTTP(w, r)
 				return
 			}
@@ -255,7 +546,7 @@ func New(opts ...Option) func(http.Handler) http.Handler {
 			}
 
 			// Create gzip response writer
-			gzw := newGzipResponseWriter(w, o.level, o.minLength)
+			gzw := newGzipResponseWriter(w, encoding, o.level, o.minLength, o.flushContentTypes, o.flushInterval)
 			defer gzw.Close()
 
 			next.ServeHTTP(gzw, r)
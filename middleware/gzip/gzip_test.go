@@ -1,12 +1,17 @@
 package gzip
 
 import (
+	"bufio"
 	"compress/gzip"
+	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
 )
 
 func TestGzip(t *testing.T) {
@@ -88,6 +93,127 @@ func TestGzipMinLength(t *testing.T) {
 	}
 }
 
+func TestGzipMinLengthZero(t *testing.T) {
+	middleware := New(WithMinLength(0))
+
+	body := strings.Repeat("a", 100)
+	handler := middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(body))
+	}))
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	if rr.Header().Get("Content-Encoding") != "gzip" {
+		t.Fatal("Expected Content-Encoding: gzip with the threshold disabled")
+	}
+
+	gr, err := gzip.NewReader(rr.Body)
+	if err != nil {
+		t.Fatalf("Failed to create gzip reader: %v", err)
+	}
+	defer gr.Close()
+
+	decompressed, err := io.ReadAll(gr)
+	if err != nil {
+		t.Fatalf("Failed to decompress: %v", err)
+	}
+	if string(decompressed) != body {
+		t.Error("Decompressed content doesn't match original")
+	}
+}
+
+func TestGzipExplicitWriteHeader(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		body     string
+		encoding string
+	}{
+		{"Large body", http.StatusOK, strings.Repeat("test data ", 200), "gzip"},
+		{"Small body", http.StatusCreated, "small data", ""},
+		{"Empty body", http.StatusAccepted, "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := New()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			}))
+
+			req := httptest.NewRequest("GET", "/test", nil)
+			req.Header.Set("Accept-Encoding", "gzip")
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != tt.status {
+				t.Errorf("Expected status %d, got %d", tt.status, rr.Code)
+			}
+			if rr.Header().Get("Content-Encoding") != tt.encoding {
+				t.Fatalf("Expected Content-Encoding %q, got %q", tt.encoding, rr.Header().Get("Content-Encoding"))
+			}
+			if tt.encoding == "" {
+				if rr.Body.String() != tt.body {
+					t.Errorf("Expected body %q, got %q", tt.body, rr.Body.String())
+				}
+				return
+			}
+
+			if rr.Header().Get("Vary") != "Accept-Encoding" {
+				t.Errorf("Expected Vary: Accept-Encoding, got %q", rr.Header().Get("Vary"))
+			}
+
+			gr, err := gzip.NewReader(rr.Body)
+			if err != nil {
+				t.Fatalf("Failed to create gzip reader: %v", err)
+			}
+			defer gr.Close()
+
+			decompressed, err := io.ReadAll(gr)
+			if err != nil {
+				t.Fatalf("Failed to decompress: %v", err)
+			}
+			if string(decompressed) != tt.body {
+				t.Error("Decompressed content doesn't match original")
+			}
+		})
+	}
+}
+
+func TestGzipContentLengthHint(t *testing.T) {
+	var rr *httptest.ResponseRecorder
+	var unbuffered bool
+
+	handler := New()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", "50")
+		w.Write([]byte(strings.Repeat("a", 50)))
+
+		// The declared size settles the decision, so nothing is buffered
+		unbuffered = rr.Body.Len() == 50
+	}))
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rr = httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	if !unbuffered {
+		t.Error("Expected the write to pass through without buffering")
+	}
+	if rr.Header().Get("Content-Encoding") != "" {
+		t.Error("Should not compress a response declared smaller than min length")
+	}
+	if rr.Header().Get("Content-Length") != "50" {
+		t.Errorf("Expected Content-Length 50, got %q", rr.Header().Get("Content-Length"))
+	}
+}
+
 func TestGzipExcludedExtensions(t *testing.T) {
 	middleware := New()
 
@@ -217,7 +343,7 @@ func TestGzipNoContentLength(t *testing.T) {
 	middleware := New()
 
 	handler := middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Content-Length", "1000")
+		w.Header().Set("Content-Length", "1500")
 		w.Write([]byte(strings.Repeat("test ", 300)))
 	}))
 
@@ -289,6 +415,68 @@ func TestGzipSmallResponse(t *testing.T) {
 	}
 }
 
+// TestGzipSniffsUncompressedContentType tests that an uncompressed response
+// gets the Content-Type sniffed from its buffered body
+func TestGzipSniffsUncompressedContentType(t *testing.T) {
+	tests := []struct {
+		name     string
+		handler  http.HandlerFunc
+		expected string
+	}{
+		{
+			name: "Buffered writes",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte("<!DOCTYPE html>"))
+				w.Write([]byte("<html><body>small</body></html>"))
+			},
+			expected: "text/html; charset=utf-8",
+		},
+		{
+			name: "Explicit WriteHeader",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+				w.Write([]byte("<html><body>small</body></html>"))
+			},
+			expected: "text/html; charset=utf-8",
+		},
+		{
+			name: "Unbuffered with Content-Length",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Length", "31")
+				w.Write([]byte("<html><body>small</body></html>"))
+			},
+			expected: "text/html; charset=utf-8",
+		},
+		{
+			name: "Explicit Content-Type kept",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "text/plain")
+				w.Write([]byte("<html><body>small</body></html>"))
+			},
+			expected: "text/plain",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := New()(tt.handler)
+
+			req := httptest.NewRequest("GET", "/test", nil)
+			req.Header.Set("Accept-Encoding", "gzip")
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			if rr.Header().Get("Content-Encoding") != "" {
+				t.Fatalf("Expected no Content-Encoding, got %q", rr.Header().Get("Content-Encoding"))
+			}
+			if got := rr.Header().Get("Content-Type"); got != tt.expected {
+				t.Errorf("Expected Content-Type %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
 // TestGzipContentTypeTests tests compression based on content type
 func TestGzipContentTypeTests(t *testing.T) {
 	middleware := New()
@@ -395,3 +583,372 @@ func TestGzipWriterPool(t *testing.T) {
 		}
 	}
 }
+
+func TestGzipNDJSONStreaming(t *testing.T) {
+	middleware := New()
+
+	ack := make(chan struct{})
+	handler := middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		for i := 0; i < 3; i++ {
+			fmt.Fprintf(w, "{\"id\":%d}\n", i)
+			// Wait for the client to receive the record before writing the next one
+			select {
+			case <-ack:
+			case <-time.After(2 * time.Second):
+				return
+			}
+		}
+	}))
+
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	req, _ := http.NewRequest("GET", server.URL, nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+
+	client := &http.Client{Transport: &http.Transport{DisableCompression: true}}
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.Header.Get("Content-Encoding") != "gzip" {
+		t.Fatal("Expected Content-Encoding: gzip")
+	}
+
+	gr, err := gzip.NewReader(resp.Body)
+	if err != nil {
+		t.Fatalf("Failed to create gzip reader: %v", err)
+	}
+	defer gr.Close()
+
+	reader := bufio.NewReader(gr)
+	for i := 0; i < 3; i++ {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("Failed to read record %d: %v", i, err)
+		}
+		expected := fmt.Sprintf("{\"id\":%d}\n", i)
+		if line != expected {
+			t.Errorf("Expected record %q, got %q", expected, line)
+		}
+		ack <- struct{}{}
+	}
+
+	// The stream must terminate as valid gzip
+	rest, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatalf("Failed to decompress remaining stream: %v", err)
+	}
+	if len(rest) != 0 {
+		t.Errorf("Expected no trailing data, got %q", rest)
+	}
+}
+
+func TestGzipWithFlushInterval(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		record      string
+	}{
+		{"NDJSON", "application/x-ndjson", "{\"id\":%d}\n"},
+		{"Server-sent events", "text/event-stream", "data: {\"id\":%d}\n\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testFlushInterval(t, tt.contentType, tt.record)
+		})
+	}
+}
+
+// testFlushInterval checks that records of a slow stream of contentType,
+// formatted by record, reach the client within the flush interval
+func testFlushInterval(t *testing.T, contentType, record string) {
+	const interval = 100 * time.Millisecond
+	middleware := New(WithFlushInterval(interval))
+
+	ack := make(chan struct{})
+	sent := make(chan time.Time, 3)
+	handler := middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", contentType)
+		for i := 0; i < 3; i++ {
+			sent <- time.Now()
+			fmt.Fprintf(w, record, i)
+			// A slow producer: nothing else is written until the client
+			// has received the record, so only the timer can flush it
+			select {
+			case <-ack:
+			case <-time.After(2 * time.Second):
+				return
+			}
+		}
+	}))
+
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	req, _ := http.NewRequest("GET", server.URL, nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+
+	client := &http.Client{Transport: &http.Transport{DisableCompression: true}}
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.Header.Get("Content-Encoding") != "gzip" {
+		t.Fatal("Expected Content-Encoding: gzip")
+	}
+
+	gr, err := gzip.NewReader(resp.Body)
+	if err != nil {
+		t.Fatalf("Failed to create gzip reader: %v", err)
+	}
+	defer gr.Close()
+
+	reader := bufio.NewReader(gr)
+	for i := 0; i < 3; i++ {
+		expected := fmt.Sprintf(record, i)
+		buf := make([]byte, len(expected))
+		if _, err := io.ReadFull(reader, buf); err != nil {
+			t.Fatalf("Failed to read record %d: %v", i, err)
+		}
+		if delay := time.Since(<-sent); delay > interval+500*time.Millisecond {
+			t.Errorf("Record %d: expected arrival within the flush interval, took %v", i, delay)
+		}
+		if string(buf) != expected {
+			t.Errorf("Expected record %q, got %q", expected, buf)
+		}
+		ack <- struct{}{}
+	}
+
+	rest, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatalf("Failed to decompress remaining stream: %v", err)
+	}
+	if len(rest) != 0 {
+		t.Errorf("Expected no trailing data, got %q", rest)
+	}
+}
+
+func TestGzipFlush(t *testing.T) {
+	middleware := New()
+
+	handler := middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("partial"))
+		w.(http.Flusher).Flush()
+	}))
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	if !rr.Flushed {
+		t.Error("Expected underlying writer to be flushed")
+	}
+
+	// Below MinLength at flush time, so the response is sent uncompressed
+	if rr.Header().Get("Content-Encoding") == "gzip" {
+		t.Error("Expected no Content-Encoding for small flushed response")
+	}
+
+	if rr.Body.String() != "partial" {
+		t.Errorf("Expected body 'partial', got %q", rr.Body.String())
+	}
+}
+
+func TestGzipWithFlushContentTypes(t *testing.T) {
+	middleware := New(WithFlushContentTypes([]string{"application/stream+json"}))
+
+	handler := middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/stream+json; charset=utf-8")
+		w.Write([]byte("{}\n"))
+	}))
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	if rr.Header().Get("Content-Encoding") != "gzip" {
+		t.Error("Expected streaming content type to be compressed regardless of MinLength")
+	}
+
+	if !rr.Flushed {
+		t.Error("Expected streaming response to be flushed")
+	}
+
+	gr, err := gzip.NewReader(rr.Body)
+	if err != nil {
+		t.Fatalf("Failed to create gzip reader: %v", err)
+	}
+	defer gr.Close()
+
+	decompressed, err := io.ReadAll(gr)
+	if err != nil {
+		t.Fatalf("Failed to decompress: %v", err)
+	}
+	if string(decompressed) != "{}\n" {
+		t.Errorf("Expected body '{}\\n', got %q", decompressed)
+	}
+}
+
+func TestGzipZstd(t *testing.T) {
+	middleware := New(WithAllowZstd(true))
+
+	body := strings.Repeat("test data ", 200)
+	handler := middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(body))
+	}))
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	req.Header.Set("Accept-Encoding", "gzip, zstd")
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	if rr.Header().Get("Content-Encoding") != "zstd" {
+		t.Fatalf("Expected Content-Encoding: zstd, got %q", rr.Header().Get("Content-Encoding"))
+	}
+
+	zr, err := zstd.NewReader(rr.Body)
+	if err != nil {
+		t.Fatalf("Failed to create zstd reader: %v", err)
+	}
+	defer zr.Close()
+
+	decompressed, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatalf("Failed to decompress: %v", err)
+	}
+	if string(decompressed) != body {
+		t.Error("Decompressed content doesn't match original")
+	}
+}
+
+func TestGzipZstdNegotiation(t *testing.T) {
+	tests := []struct {
+		acceptEncoding string
+		allowZstd      bool
+		expected       string
+	}{
+		{"zstd", true, "zstd"},
+		{"gzip, zstd", true, "zstd"},
+		{"gzip;q=1.0, zstd;q=0.5", true, "gzip"},
+		{"gzip;q=0.5, zstd;q=0.8", true, "zstd"},
+		{"gzip, zstd;q=0", true, "gzip"},
+		{"gzip;q=0, zstd", true, "zstd"},
+		{"gzip;q=0", true, ""},
+		{"zstd", false, ""},
+		{"gzip, zstd", false, "gzip"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.acceptEncoding, func(t *testing.T) {
+			middleware := New(WithAllowZstd(tt.allowZstd))
+
+			handler := middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte(strings.Repeat("test ", 300)))
+			}))
+
+			req := httptest.NewRequest("GET", "/test", nil)
+			req.Header.Set("Accept-Encoding", tt.acceptEncoding)
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			if rr.Header().Get("Content-Encoding") != tt.expected {
+				t.Errorf("Expected Content-Encoding %q, got %q", tt.expected, rr.Header().Get("Content-Encoding"))
+			}
+		})
+	}
+}
+
+func TestGzipZstdExclusions(t *testing.T) {
+	middleware := New(WithAllowZstd(true), WithExcludedPaths([]string{"/stream"}))
+
+	handler := middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/small" {
+			w.Write([]byte("small"))
+			return
+		}
+		w.Write([]byte(strings.Repeat("test ", 300)))
+	}))
+
+	for _, path := range []string{"/stream", "/image.png", "/small"} {
+		req := httptest.NewRequest("GET", path, nil)
+		req.Header.Set("Accept-Encoding", "zstd")
+		rr := httptest.NewRecorder()
+
+		handler.ServeHTTP(rr, req)
+
+		if rr.Header().Get("Content-Encoding") != "" {
+			t.Errorf("%s: expected no compression, got %q", path, rr.Header().Get("Content-Encoding"))
+		}
+	}
+}
+
+func TestGzipRangeResponses(t *testing.T) {
+	content := strings.Repeat("0123456789", 300)
+
+	tests := []struct {
+		name       string
+		rangeValue string
+		handler    http.HandlerFunc
+		expected   int
+		length     int
+	}{
+		{
+			"Range request",
+			"bytes=0-99",
+			func(w http.ResponseWriter, r *http.Request) {
+				http.ServeContent(w, r, "data.txt", time.Time{}, strings.NewReader(content))
+			},
+			http.StatusPartialContent,
+			100,
+		},
+		{
+			"Partial Content without Range header",
+			"",
+			func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Range", fmt.Sprintf("bytes 0-1999/%d", len(content)))
+				w.WriteHeader(http.StatusPartialContent)
+				w.Write([]byte(content[:2000]))
+			},
+			http.StatusPartialContent,
+			2000,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := New()(tt.handler)
+
+			req := httptest.NewRequest("GET", "/data.txt", nil)
+			req.Header.Set("Accept-Encoding", "gzip")
+			if tt.rangeValue != "" {
+				req.Header.Set("Range", tt.rangeValue)
+			}
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != tt.expected {
+				t.Errorf("Expected status %d, got %d", tt.expected, rr.Code)
+			}
+			if rr.Header().Get("Content-Encoding") == "gzip" {
+				t.Error("Expected no gzip Content-Encoding for a range response")
+			}
+			if rr.Body.Len() != tt.length || rr.Body.String() != content[:tt.length] {
+				t.Errorf("Expected the first %d bytes as identity, got %d bytes", tt.length, rr.Body.Len())
+			}
+		})
+	}
+}
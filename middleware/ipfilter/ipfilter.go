@@ -0,0 +1,130 @@
+package ipfilter
+
+import (
+	"net"
+	"net/http"
+	"net/netip"
+
+	"github.com/xushuhui/ares-contrib/middleware/realip"
+)
+
+// Option is ipfilter option.
+type Option func(*options)
+
+// options defines the configuration for the ipfilter middleware
+type options struct {
+	// AllowList restricts access to these CIDRs when set
+	allowList []netip.Prefix
+
+	// BlockList denies access to these CIDRs
+	blockList []netip.Prefix
+
+	// IPExtractor returns the client IP for a request
+	// Default: the IP resolved by the realip middleware, falling back to
+	// r.RemoteAddr
+	ipExtractor func(r *http.Request) string
+}
+
+// parsePrefixes parses CIDRs or single IPs, panicking on an invalid entry
+func parsePrefixes(entries []string) []netip.Prefix {
+	prefixes := make([]netip.Prefix, 0, len(entries))
+	for _, entry := range entries {
+		prefix, err := netip.ParsePrefix(entry)
+		if err != nil {
+			addr, addrErr := netip.ParseAddr(entry)
+			if addrErr != nil {
+				panic("ipfilter: invalid CIDR " + entry)
+			}
+			prefix = netip.PrefixFrom(addr, addr.BitLen())
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+	return prefixes
+}
+
+// WithAllowList sets the CIDRs (or single IPs) allowed access. Requests from
+// any other IP are denied. It panics on an invalid entry.
+func WithAllowList(cidrs []string) Option {
+	return func(o *options) {
+		o.allowList = append(o.allowList, parsePrefixes(cidrs)...)
+	}
+}
+
+// WithBlockList sets the CIDRs (or single IPs) denied access. It panics on
+// an invalid entry.
+func WithBlockList(cidrs []string) Option {
+	return func(o *options) {
+		o.blockList = append(o.blockList, parsePrefixes(cidrs)...)
+	}
+}
+
+// WithIPExtractor sets the function returning the client IP for a request
+func WithIPExtractor(f func(r *http.Request) string) Option {
+	return func(o *options) {
+		o.ipExtractor = f
+	}
+}
+
+// defaultIPExtractor returns the IP resolved by the realip middleware, so
+// forwarding headers are only trusted from trusted proxies, or the peer IP
+func defaultIPExtractor(r *http.Request) string {
+	if ip, ok := realip.FromContext(r.Context()); ok {
+		return ip
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// contains reports whether addr is in any of prefixes
+func contains(prefixes []netip.Prefix, addr netip.Addr) bool {
+	for _, prefix := range prefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// allowed reports whether a client IP may access the handler
+func (o *options) allowed(ip string) bool {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		// An unknown IP can't be matched against the allowlist
+		return len(o.allowList) == 0
+	}
+	addr = addr.Unmap()
+
+	if contains(o.blockList, addr) {
+		return false
+	}
+	return len(o.allowList) == 0 || contains(o.allowList, addr)
+}
+
+// New returns a middleware that denies requests from blocklisted IPs, and
+// from IPs outside the allowlist when one is set, with 403 Forbidden.
+// Register the realip middleware first when running behind proxies.
+func New(opts ...Option) func(http.Handler) http.Handler {
+	o := &options{
+		ipExtractor: defaultIPExtractor,
+	}
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !o.allowed(o.ipExtractor(r)) {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusForbidden)
+				w.Write([]byte(`{"error":"forbidden"}`))
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package ipfilter
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/xushuhui/ares-contrib/middleware/realip"
+)
+
+func okHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+}
+
+func TestIPFilter(t *testing.T) {
+	tests := []struct {
+		name       string
+		opts       []Option
+		remoteAddr string
+		expected   int
+	}{
+		{"Neither configured", nil, "203.0.113.7:1234", http.StatusOK},
+		{"Allowed", []Option{WithAllowList([]string{"10.0.0.0/8"})}, "10.1.2.3:1234", http.StatusOK},
+		{"Not in allowlist", []Option{WithAllowList([]string{"10.0.0.0/8"})}, "203.0.113.7:1234", http.StatusForbidden},
+		{"Single IP allowlist", []Option{WithAllowList([]string{"203.0.113.7"})}, "203.0.113.7:1234", http.StatusOK},
+		{"Blocked", []Option{WithBlockList([]string{"198.51.100.0/24"})}, "198.51.100.9:1234", http.StatusForbidden},
+		{"Not blocked", []Option{WithBlockList([]string{"198.51.100.0/24"})}, "203.0.113.7:1234", http.StatusOK},
+		{"Blocklist wins", []Option{WithAllowList([]string{"10.0.0.0/8"}), WithBlockList([]string{"10.0.0.1"})}, "10.0.0.1:1234", http.StatusForbidden},
+		{"IPv6 allowed", []Option{WithAllowList([]string{"2001:db8::/32"})}, "[2001:db8::1]:1234", http.StatusOK},
+		{"IPv4-mapped IPv6", []Option{WithAllowList([]string{"10.0.0.0/8"})}, "[::ffff:10.1.2.3]:1234", http.StatusOK},
+		{"Unparseable with allowlist", []Option{WithAllowList([]string{"10.0.0.0/8"})}, "garbage", http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := New(tt.opts...)(okHandler())
+
+			req := httptest.NewRequest("GET", "/admin", nil)
+			req.RemoteAddr = tt.remoteAddr
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != tt.expected {
+				t.Errorf("Expected status %d, got %d", tt.expected, rr.Code)
+			}
+			if tt.expected == http.StatusForbidden && rr.Body.String() != `{"error":"forbidden"}` {
+				t.Errorf("Expected JSON error body, got %q", rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestIPFilterWithRealIP(t *testing.T) {
+	// realip resolves the client behind the trusted proxy
+	handler := realip.New(realip.WithTrustedProxies([]string{"10.0.0.1"}))(
+		New(WithAllowList([]string{"203.0.113.0/24"}))(okHandler()),
+	)
+
+	req := httptest.NewRequest("GET", "/admin", nil)
+	req.RemoteAddr = "10.0.0.1:1234"
+	req.Header.Set("X-Forwarded-For", "203.0.113.7")
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", rr.Code)
+	}
+}
+
+func TestIPFilterWithIPExtractor(t *testing.T) {
+	handler := New(
+		WithBlockList([]string{"198.51.100.9"}),
+		WithIPExtractor(func(r *http.Request) string {
+			return r.Header.Get("CF-Connecting-IP")
+		}),
+	)(okHandler())
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	req.Header.Set("CF-Connecting-IP", "198.51.100.9")
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusForbidden {
+		t.Errorf("Expected status 403, got %d", rr.Code)
+	}
+}
+
+func TestIPFilterInvalidCIDRPanic(t *testing.T) {
+	defer func() {
+		if r := recover(); r == nil {
+			t.Error("Expected panic for invalid CIDR")
+		}
+	}()
+
+	New(WithAllowList([]string{"not-a-cidr"}))
+}
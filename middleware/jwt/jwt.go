@@ -2,10 +2,13 @@ package jwt
 
 import (
 	"context"
+	"crypto/ed25519"
 	"encoding/json"
 	"errors"
 	"net/http"
+	"regexp"
 	"strings"
+	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 	ae "github.com/xushuhui/ares/errors"
@@ -24,8 +27,27 @@ var (
 	ErrMissingKeyFunc         = errors.New("keyFunc is missing")
 	ErrTokenInvalid           = errors.New("token is invalid")
 	ErrTokenExpired           = errors.New("JWT token has expired")
+	ErrTokenNotValidYet       = errors.New("JWT token is not valid yet")
 	ErrTokenParseFail         = errors.New("fail to parse JWT token")
 	ErrUnSupportSigningMethod = errors.New("wrong signing method")
+	ErrInvalidIssuer          = errors.New("token issuer is not allowed")
+	ErrInvalidAudience        = errors.New("token audience is not allowed")
+	ErrClaimNotAllowed        = errors.New("token claim is not allowed")
+)
+
+// Auth outcomes recorded by Metrics
+const (
+	OutcomeSuccess            = "success"
+	OutcomeMissing            = "missing"
+	OutcomeAnonymous          = "anonymous"
+	OutcomeExpired            = "expired"
+	OutcomeNotValidYet        = "not_valid_yet"
+	OutcomeInvalidSignature   = "invalid_signature"
+	OutcomeInvalid            = "invalid"
+	OutcomeParseFail          = "parse_fail"
+	OutcomeWrongSigningMethod = "wrong_signing_method"
+	OutcomeInvalidIssuer      = "invalid_issuer"
+	OutcomeInvalidAudience    = "invalid_audience"
 )
 
 // Option is jwt option.
@@ -33,10 +55,25 @@ type Option func(*options)
 
 // options holds JWT middleware configuration
 type options struct {
-	signingKey    []byte
-	signingMethod jwt.SigningMethod
-	claims        func() jwt.Claims
-	contextKey    string
+	signingKey      []byte
+	keyFunc         jwt.Keyfunc
+	signingMethod   jwt.SigningMethod
+	claims          func() jwt.Claims
+	contextKey      string
+	issuerPattern   *regexp.Regexp
+	audiencePattern *regexp.Regexp
+	metrics         Metrics
+	realm           string
+	refreshWindow   time.Duration
+	refreshSign     func(claims jwt.Claims) (string, error)
+	refreshHeader   string
+	authSchemes     []string
+	expiryContext   bool
+	validMethods    []string
+	optional        bool
+	cookieName      string
+	cookie          http.Cookie
+	unauthorized    int
 }
 
 // WithSigningMethod with signing method option.
@@ -46,6 +83,23 @@ func WithSigningMethod(method jwt.SigningMethod) Option {
 	}
 }
 
+// WithKeyFunc sets the function returning the key that verifies a token,
+// replacing the signing key passed to New. Use it for asymmetric methods,
+// e.g. returning an ed25519.PublicKey with jwt.SigningMethodEdDSA.
+func WithKeyFunc(f jwt.Keyfunc) Option {
+	return func(o *options) {
+		o.keyFunc = f
+	}
+}
+
+// WithValidMethods pins the accepted alg header values. Tokens with any other
+// alg, including "none", are rejected before the key is looked up.
+func WithValidMethods(methods []string) Option {
+	return func(o *options) {
+		o.validMethods = methods
+	}
+}
+
 // WithClaims with custom claim
 // f needs to return a new jwt.Claims object each time to avoid concurrent write problems
 func WithClaims(f func() jwt.Claims) Option {
@@ -61,6 +115,210 @@ func WithContextKey(key string) Option {
 	}
 }
 
+// WithIssuerPattern only accepts tokens whose iss claim matches pattern.
+// Anchor the pattern (^...$) to avoid partial matches.
+func WithIssuerPattern(pattern *regexp.Regexp) Option {
+	return func(o *options) {
+		o.issuerPattern = pattern
+	}
+}
+
+// WithAudiencePattern only accepts tokens with at least one aud claim value
+// matching pattern. Anchor the pattern (^...$) to avoid partial matches.
+func WithAudiencePattern(pattern *regexp.Regexp) Option {
+	return func(o *options) {
+		o.audiencePattern = pattern
+	}
+}
+
+// Metrics records auth outcomes. See the prommetrics package for a
+// Prometheus implementation.
+type Metrics interface {
+	// IncOutcome is called once per authentication decision with one of the
+	// Outcome constants
+	IncOutcome(outcome string)
+}
+
+// WithMetrics sets the metrics recorder
+func WithMetrics(m Metrics) Option {
+	return func(o *options) {
+		o.metrics = m
+	}
+}
+
+// WithAuthScheme sets the accepted Authorization header schemes, matched
+// case-insensitively, e.g. "Bearer" and "Token"
+func WithAuthScheme(schemes ...string) Option {
+	return func(o *options) {
+		o.authSchemes = schemes
+	}
+}
+
+// WithRealm sets the realm reported in the WWW-Authenticate challenge
+func WithRealm(realm string) Option {
+	return func(o *options) {
+		o.realm = realm
+	}
+}
+
+// WithAutoRefresh re-issues tokens that expire within window. sign receives
+// the validated claims and returns a new signed token, typically with a later
+// exp; the token is sent in the refresh header. Expired tokens are rejected
+// as usual and never refreshed.
+func WithAutoRefresh(window time.Duration, sign func(claims jwt.Claims) (string, error)) Option {
+	return func(o *options) {
+		o.refreshWindow = window
+		o.refreshSign = sign
+	}
+}
+
+// WithExpiryContext sets whether the request context is cancelled when the
+// token expires, so work started under the token can't outlive it. Tokens
+// without an exp claim leave the context unchanged.
+func WithExpiryContext(enabled bool) Option {
+	return func(o *options) {
+		o.expiryContext = enabled
+	}
+}
+
+// WithOptional sets whether requests without an Authorization header proceed
+// anonymously, with no claims in the context. Invalid tokens, and headers
+// with an unaccepted scheme, are still rejected.
+func WithOptional(optional bool) Option {
+	return func(o *options) {
+		o.optional = optional
+	}
+}
+
+// WithCookieRenewal reads the token from the cookie name when the request has
+// no Authorization header, and sends tokens re-issued by WithAutoRefresh as
+// that cookie instead of the refresh header. The renewal cookie copies the
+// Path, Domain, MaxAge, Expires, Secure, HttpOnly and SameSite of cookie.
+func WithCookieRenewal(name string, cookie http.Cookie) Option {
+	return func(o *options) {
+		o.cookieName = name
+		o.cookie = cookie
+	}
+}
+
+// WithUnauthorizedStatus sets the status code of token failure responses,
+// e.g. 403 to keep browsers from showing an auth dialog. It must be a 4xx.
+func WithUnauthorizedStatus(code int) Option {
+	return func(o *options) {
+		o.unauthorized = code
+	}
+}
+
+// WithRefreshHeader sets the response header carrying a refreshed token
+func WithRefreshHeader(header string) Option {
+	return func(o *options) {
+		o.refreshHeader = header
+	}
+}
+
+// observe records outcome if metrics are enabled
+func (o *options) observe(outcome string) {
+	if o.metrics != nil {
+		o.metrics.IncOutcome(outcome)
+	}
+}
+
+// reject records outcome and writes a JSON error response with the
+// unauthorized status and an RFC 6750 challenge for the first accepted auth
+// scheme
+func (o *options) reject(w http.ResponseWriter, outcome string, err error) {
+	o.observe(outcome)
+	o.unauthorizedResponse(w, outcome, err)
+}
+
+// unauthorizedResponse writes the response of reject without recording it
+func (o *options) unauthorizedResponse(w http.ResponseWriter, outcome string, err error) {
+	w.Header().Set("WWW-Authenticate", o.challenge(outcome, err))
+	jsonResponse(w, o.unauthorized, err.Error())
+}
+
+// challenge builds the WWW-Authenticate header value for a failed outcome
+func (o *options) challenge(outcome string, err error) string {
+	code := "invalid_token"
+	if outcome == OutcomeMissing {
+		code = "invalid_request"
+	}
+
+	params := make([]string, 0, 3)
+	if o.realm != "" {
+		params = append(params, `realm="`+quoteEscape(o.realm)+`"`)
+	}
+	params = append(params, `error="`+code+`"`)
+	params = append(params, `error_description="`+quoteEscape(err.Error())+`"`)
+	return o.authSchemes[0] + " " + strings.Join(params, ", ")
+}
+
+// quoteEscape escapes s for use inside a quoted-string header parameter
+func quoteEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s)
+}
+
+// refresh sets the refresh header with a new token if claims expire within
+// the refresh window. Signing failures leave the current token in use.
+func (o *options) refresh(w http.ResponseWriter, claims jwt.Claims) {
+	if o.refreshSign == nil {
+		return
+	}
+	exp, err := claims.GetExpirationTime()
+	if err != nil || exp == nil || time.Until(exp.Time) > o.refreshWindow {
+		return
+	}
+	token, err := o.refreshSign(claims)
+	if err != nil {
+		return
+	}
+
+	if o.cookieName != "" {
+		cookie := o.cookie
+		cookie.Name = o.cookieName
+		cookie.Value = token
+		http.SetCookie(w, &cookie)
+		return
+	}
+	w.Header().Set(o.refreshHeader, token)
+}
+
+// matchScheme reports whether scheme is one of the accepted auth schemes
+func (o *options) matchScheme(scheme string) bool {
+	for _, s := range o.authSchemes {
+		if strings.EqualFold(scheme, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchIssuer reports whether the token issuer matches the configured pattern
+func (o *options) matchIssuer(claims jwt.Claims) bool {
+	if o.issuerPattern == nil {
+		return true
+	}
+	iss, err := claims.GetIssuer()
+	return err == nil && o.issuerPattern.MatchString(iss)
+}
+
+// matchAudience reports whether any token audience matches the configured pattern
+func (o *options) matchAudience(claims jwt.Claims) bool {
+	if o.audiencePattern == nil {
+		return true
+	}
+	aud, err := claims.GetAudience()
+	if err != nil {
+		return false
+	}
+	for _, a := range aud {
+		if o.audiencePattern.MatchString(a) {
+			return true
+		}
+	}
+	return false
+}
+
 // jsonResponse is a helper function to write JSON error responses
 func jsonResponse(w http.ResponseWriter, statusCode int, message string) {
 	w.Header().Set("Content-Type", "application/json")
@@ -77,25 +335,56 @@ func New(signingKey []byte, opts ...Option) func(http.Handler) http.Handler {
 		signingKey:    signingKey,
 		signingMethod: jwt.SigningMethodHS256,
 		contextKey:    "user",
+		refreshHeader: "X-Refresh-Token",
+		authSchemes:   []string{bearerWord},
+		unauthorized:  http.StatusUnauthorized,
 	}
 	for _, opt := range opts {
 		opt(o)
 	}
 
-	// Validate signing key
-	if o.signingKey == nil {
+	if len(o.authSchemes) == 0 {
+		panic("jwt: at least one auth scheme is required")
+	}
+
+	if o.unauthorized < 400 || o.unauthorized > 499 {
+		panic("jwt: unauthorized status must be a 4xx status code")
+	}
+
+	// Validate signing key; a key func replaces it
+	if o.signingKey == nil && o.keyFunc == nil {
 		panic("signing key is nil")
 	}
 
+	var parserOpts []jwt.ParserOption
+	if len(o.validMethods) > 0 {
+		parserOpts = append(parserOpts, jwt.WithValidMethods(o.validMethods))
+	}
+
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			// Extract token from Authorization header
-			auths := strings.SplitN(r.Header.Get(authorizationKey), " ", 2)
-			if len(auths) != 2 || !strings.EqualFold(auths[0], bearerWord) {
-				jsonResponse(w, http.StatusUnauthorized, ErrMissingJwtToken.Error())
+			// Extract token from Authorization header, or the renewal
+			// cookie without one
+			var jwtToken string
+			authorization := r.Header.Get(authorizationKey)
+			if authorization == "" && o.cookieName != "" {
+				if cookie, err := r.Cookie(o.cookieName); err == nil {
+					jwtToken = cookie.Value
+				}
+			}
+			if authorization == "" && jwtToken == "" && o.optional {
+				o.observe(OutcomeAnonymous)
+				next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), optionsKey{}, o)))
 				return
 			}
-			jwtToken := auths[1]
+			if jwtToken == "" {
+				auths := strings.SplitN(authorization, " ", 2)
+				if len(auths) != 2 || !o.matchScheme(auths[0]) {
+					o.reject(w, OutcomeMissing, ErrMissingJwtToken)
+					return
+				}
+				jwtToken = auths[1]
+			}
 
 			// Parse token
 			var (
@@ -104,44 +393,77 @@ func New(signingKey []byte, opts ...Option) func(http.Handler) http.Handler {
 			)
 
 			// Create keyFunc
-			keyFunc := func(token *jwt.Token) (interface{}, error) {
-				return o.signingKey, nil
+			keyFunc := o.keyFunc
+			if keyFunc == nil {
+				keyFunc = func(token *jwt.Token) (interface{}, error) {
+					return o.signingKey, nil
+				}
 			}
 
 			if o.claims != nil {
-				tokenInfo, err = jwt.ParseWithClaims(jwtToken, o.claims(), keyFunc)
+				tokenInfo, err = jwt.ParseWithClaims(jwtToken, o.claims(), keyFunc, parserOpts...)
 			} else {
-				tokenInfo, err = jwt.Parse(jwtToken, keyFunc)
+				tokenInfo, err = jwt.Parse(jwtToken, keyFunc, parserOpts...)
 			}
 
 			if err != nil {
 				// Classify error types
 				if errors.Is(err, jwt.ErrTokenMalformed) || errors.Is(err, jwt.ErrTokenUnverifiable) {
-					jsonResponse(w, http.StatusUnauthorized, ErrTokenInvalid.Error())
+					o.reject(w, OutcomeInvalid, ErrTokenInvalid)
+					return
+				}
+				if errors.Is(err, jwt.ErrTokenExpired) {
+					o.reject(w, OutcomeExpired, ErrTokenExpired)
 					return
 				}
-				if errors.Is(err, jwt.ErrTokenNotValidYet) || errors.Is(err, jwt.ErrTokenExpired) {
-					jsonResponse(w, http.StatusUnauthorized, ErrTokenExpired.Error())
+				if errors.Is(err, jwt.ErrTokenNotValidYet) {
+					o.reject(w, OutcomeNotValidYet, ErrTokenNotValidYet)
 					return
 				}
-				jsonResponse(w, http.StatusUnauthorized, ErrTokenParseFail.Error())
+				if errors.Is(err, jwt.ErrTokenSignatureInvalid) {
+					o.reject(w, OutcomeInvalidSignature, ErrTokenParseFail)
+					return
+				}
+				o.reject(w, OutcomeParseFail, ErrTokenParseFail)
 				return
 			}
 
 			// Validate token
 			if !tokenInfo.Valid {
-				jsonResponse(w, http.StatusUnauthorized, ErrTokenInvalid.Error())
+				o.reject(w, OutcomeInvalid, ErrTokenInvalid)
 				return
 			}
 
 			// Verify signing method
 			if tokenInfo.Method != o.signingMethod {
-				jsonResponse(w, http.StatusUnauthorized, ErrUnSupportSigningMethod.Error())
+				o.reject(w, OutcomeWrongSigningMethod, ErrUnSupportSigningMethod)
 				return
 			}
 
+			// Verify issuer and audience
+			if !o.matchIssuer(tokenInfo.Claims) {
+				o.reject(w, OutcomeInvalidIssuer, ErrInvalidIssuer)
+				return
+			}
+			if !o.matchAudience(tokenInfo.Claims) {
+				o.reject(w, OutcomeInvalidAudience, ErrInvalidAudience)
+				return
+			}
+
+			o.observe(OutcomeSuccess)
+			o.refresh(w, tokenInfo.Claims)
+
 			// Store claims in context
 			ctx := context.WithValue(r.Context(), contextKey(o.contextKey), tokenInfo.Claims)
+			ctx = context.WithValue(ctx, tokenKey{}, jwtToken)
+			ctx = context.WithValue(ctx, optionsKey{}, o)
+			if o.expiryContext {
+				if exp, err := tokenInfo.Claims.GetExpirationTime(); err == nil && exp != nil {
+					var cancel context.CancelFunc
+					ctx, cancel = context.WithDeadline(ctx, exp.Time)
+					defer cancel()
+				}
+			}
 			r = r.WithContext(ctx)
 
 			next.ServeHTTP(w, r)
@@ -152,6 +474,26 @@ func New(signingKey []byte, opts ...Option) func(http.Handler) http.Handler {
 // contextKey is the type used for context keys
 type contextKey string
 
+// tokenKey stores the raw token independently of the claims context key
+type tokenKey struct{}
+
+// optionsKey stores the options of the New middleware that handled the
+// request, so RequireClaim finds its claims and responds like it
+type optionsKey struct{}
+
+// defaultOptions are used by RequireClaim without a New middleware
+var defaultOptions = &options{
+	contextKey:   "user",
+	authSchemes:  []string{bearerWord},
+	unauthorized: http.StatusUnauthorized,
+}
+
+// GetToken extracts the raw JWT token string from context
+func GetToken(ctx context.Context) (string, bool) {
+	token, ok := ctx.Value(tokenKey{}).(string)
+	return token, ok
+}
+
 // GetClaims extracts JWT claims from context
 func GetClaims(ctx context.Context) (jwt.Claims, bool) {
 	claims, ok := ctx.Value(contextKey("user")).(jwt.Claims)
@@ -164,6 +506,89 @@ func GetClaimsWithKey(ctx context.Context, key string) (jwt.Claims, bool) {
 	return claims, ok
 }
 
+// RequireClaim returns a middleware that only lets requests through whose
+// claims, stored in the context by New, hold one of allowedValues for the
+// claim key. For array-valued claims such as roles, any allowed element
+// matches. Requests without claims are rejected like New rejects a missing
+// token, with its unauthorized status and challenge, and requests whose
+// claim doesn't match get 403.
+func RequireClaim(key string, allowedValues ...string) func(http.Handler) http.Handler {
+	return requireClaim("", key, allowedValues)
+}
+
+// RequireClaimWithKey is RequireClaim reading claims stored under the custom
+// context key ctxKey, for claims not placed in the context by New
+func RequireClaimWithKey(ctxKey, key string, allowedValues ...string) func(http.Handler) http.Handler {
+	return requireClaim(ctxKey, key, allowedValues)
+}
+
+// requireClaim implements RequireClaim, reading claims from ctxKey or, if
+// empty, from the context key New was configured with
+func requireClaim(ctxKey, key string, allowedValues []string) func(http.Handler) http.Handler {
+	allowed := make(map[string]struct{}, len(allowedValues))
+	for _, v := range allowedValues {
+		allowed[v] = struct{}{}
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			o, ok := r.Context().Value(optionsKey{}).(*options)
+			if !ok {
+				o = defaultOptions
+			}
+			claimsKey := ctxKey
+			if claimsKey == "" {
+				claimsKey = o.contextKey
+			}
+
+			claims, ok := GetClaimsWithKey(r.Context(), claimsKey)
+			if !ok {
+				// New already recorded the outcome of the request
+				o.unauthorizedResponse(w, OutcomeMissing, ErrMissingJwtToken)
+				return
+			}
+
+			for _, v := range claimValues(claims, key) {
+				if _, ok := allowed[v]; ok {
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+
+			jsonResponse(w, http.StatusForbidden, ErrClaimNotAllowed.Error())
+		})
+	}
+}
+
+// claimValues returns the string values of a claim, which may be a single
+// string or an array. Claims other than MapClaims are read through their
+// JSON encoding.
+func claimValues(claims jwt.Claims, key string) []string {
+	m, ok := claims.(jwt.MapClaims)
+	if !ok {
+		data, err := json.Marshal(claims)
+		if err != nil || json.Unmarshal(data, &m) != nil {
+			return nil
+		}
+	}
+
+	switch v := m[key].(type) {
+	case string:
+		return []string{v}
+	case []string:
+		return v
+	case []interface{}:
+		values := make([]string, 0, len(v))
+		for _, e := range v {
+			if s, ok := e.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	}
+	return nil
+}
+
 // GenerateToken creates a signed JWT token with the given claims and middleware configuration
 // This function uses the same signing key and method as configured in the middleware
 func GenerateToken(signingKey []byte, claims jwt.Claims, opts ...Option) (string, error) {
@@ -189,6 +614,16 @@ func GenerateToken(signingKey []byte, claims jwt.Claims, opts ...Option) (string
 	return tokenString, nil
 }
 
+// GenerateTokenEdDSA creates a JWT token signed with an Ed25519 private key.
+// Verify it with WithSigningMethod(jwt.SigningMethodEdDSA) and a key func
+// returning the matching public key.
+func GenerateTokenEdDSA(priv ed25519.PrivateKey, claims jwt.Claims) (string, error) {
+	if priv == nil {
+		return "", errors.New("private key is nil")
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodEdDSA, claims).SignedString(priv)
+}
+
 // GenerateTokenWithDefaultClaims creates a signed JWT token with default MapClaims
 // This is a convenience function for simple use cases
 func GenerateTokenWithDefaultClaims(signingKey []byte, claims map[string]interface{}, opts ...Option) (string, error) {
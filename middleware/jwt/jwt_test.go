@@ -2,12 +2,17 @@ package jwt
 
 import (
 	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"regexp"
 	"testing"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	ae "github.com/xushuhui/ares/errors"
 )
 
 func TestNew(t *testing.T) {
@@ -167,6 +172,43 @@ func TestJWTExpiredToken(t *testing.T) {
 	}
 }
 
+func TestJWTNotValidYet(t *testing.T) {
+	secret := []byte("test-secret")
+
+	// Create token that only becomes valid in an hour
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"user_id": "123",
+		"nbf":     time.Now().Add(time.Hour).Unix(),
+		"exp":     time.Now().Add(2 * time.Hour).Unix(),
+	})
+	tokenString, err := token.SignedString(secret)
+	if err != nil {
+		t.Fatalf("Failed to create token: %v", err)
+	}
+
+	handler := New(secret)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	req.Header.Set("Authorization", "Bearer "+tokenString)
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status 401 for token not valid yet, got %d", rr.Code)
+	}
+
+	var body ae.Error
+	if err := json.Unmarshal(rr.Body.Bytes(), &body); err != nil {
+		t.Fatalf("Failed to decode body: %v", err)
+	}
+	if body.Message != ErrTokenNotValidYet.Error() {
+		t.Errorf("Expected message %q, got %q", ErrTokenNotValidYet.Error(), body.Message)
+	}
+}
+
 func TestJWTWrongSigningMethod(t *testing.T) {
 	secret := []byte("test-secret")
 
@@ -540,3 +582,887 @@ func TestGenerateTokenWithCustomSigningMethod(t *testing.T) {
 		t.Errorf("Expected signing method HS512, got %v", token.Method)
 	}
 }
+
+func TestJWTIssuerAndAudiencePatterns(t *testing.T) {
+	secret := []byte("test-secret")
+
+	handler := New(secret,
+		WithIssuerPattern(regexp.MustCompile(`^https://auth\.[a-z]+\.example\.com$`)),
+		WithAudiencePattern(regexp.MustCompile(`^api(-[a-z]+)?$`)),
+	)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		name           string
+		claims         jwt.MapClaims
+		expectedStatus int
+	}{
+		{
+			name:           "Matching regional issuer",
+			claims:         jwt.MapClaims{"iss": "https://auth.eu.example.com", "aud": "api"},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "Non-matching issuer",
+			claims:         jwt.MapClaims{"iss": "https://auth.example.org", "aud": "api"},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "Missing issuer",
+			claims:         jwt.MapClaims{"aud": "api"},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "One of several audiences matches",
+			claims:         jwt.MapClaims{"iss": "https://auth.us.example.com", "aud": []string{"web", "api-admin"}},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "Non-matching audience",
+			claims:         jwt.MapClaims{"iss": "https://auth.us.example.com", "aud": "web"},
+			expectedStatus: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.claims["exp"] = time.Now().Add(time.Hour).Unix()
+			tokenString, err := jwt.NewWithClaims(jwt.SigningMethodHS256, tt.claims).SignedString(secret)
+			if err != nil {
+				t.Fatalf("Failed to create token: %v", err)
+			}
+
+			req := httptest.NewRequest("GET", "/test", nil)
+			req.Header.Set("Authorization", "Bearer "+tokenString)
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d", tt.expectedStatus, rr.Code)
+			}
+		})
+	}
+}
+
+// outcomeRecorder counts outcomes in memory
+type outcomeRecorder map[string]float64
+
+func (m outcomeRecorder) IncOutcome(outcome string) {
+	m[outcome]++
+}
+
+func TestJWTMetrics(t *testing.T) {
+	secret := []byte("test-secret")
+	outcomes := outcomeRecorder{}
+
+	handler := New(secret, WithMetrics(outcomes))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	valid, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"exp": time.Now().Add(time.Hour).Unix(),
+	}).SignedString(secret)
+	expired, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"exp": time.Now().Add(-time.Hour).Unix(),
+	}).SignedString(secret)
+	badSignature, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"exp": time.Now().Add(time.Hour).Unix(),
+	}).SignedString([]byte("other-secret"))
+
+	for _, token := range []string{valid, valid, expired, badSignature, ""} {
+		req := httptest.NewRequest("GET", "/test", nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	expected := map[string]float64{
+		OutcomeSuccess:          2,
+		OutcomeExpired:          1,
+		OutcomeInvalidSignature: 1,
+		OutcomeMissing:          1,
+		OutcomeInvalid:          0,
+	}
+	for outcome, want := range expected {
+		if got := outcomes[outcome]; got != want {
+			t.Errorf("Expected %v %s outcomes, got %v", want, outcome, got)
+		}
+	}
+}
+
+func TestGetToken(t *testing.T) {
+	secret := []byte("test-secret")
+
+	tokenString, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"exp": time.Now().Add(time.Hour).Unix(),
+	}).SignedString(secret)
+	if err != nil {
+		t.Fatalf("Failed to create token: %v", err)
+	}
+
+	handler := New(secret, WithContextKey("custom"))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := GetToken(r.Context())
+		if !ok {
+			t.Error("Expected raw token in context")
+		}
+		if token != tokenString {
+			t.Errorf("Expected raw token %s, got %s", tokenString, token)
+		}
+
+		// Claims remain retrievable independently
+		if _, ok := GetClaimsWithKey(r.Context(), "custom"); !ok {
+			t.Error("Expected claims with custom key")
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	req.Header.Set("Authorization", "Bearer "+tokenString)
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", rr.Code)
+	}
+
+	if _, ok := GetToken(context.Background()); ok {
+		t.Error("Expected no token in empty context")
+	}
+}
+
+func TestJWTWWWAuthenticate(t *testing.T) {
+	secret := []byte("test-secret")
+
+	expired, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"exp": time.Now().Add(-time.Hour).Unix(),
+	}).SignedString(secret)
+	if err != nil {
+		t.Fatalf("Failed to create token: %v", err)
+	}
+
+	handler := New(secret, WithRealm("api"))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		name      string
+		auth      string
+		challenge string
+	}{
+		{"Missing token", "", `Bearer realm="api", error="invalid_request", error_description="JWT token is missing"`},
+		{"Expired token", "Bearer " + expired, `Bearer realm="api", error="invalid_token", error_description="JWT token has expired"`},
+		{"Malformed token", "Bearer not-a-token", `Bearer realm="api", error="invalid_token", error_description="token is invalid"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/test", nil)
+			if tt.auth != "" {
+				req.Header.Set("Authorization", tt.auth)
+			}
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != http.StatusUnauthorized {
+				t.Errorf("Expected status 401, got %d", rr.Code)
+			}
+			if got := rr.Header().Get("WWW-Authenticate"); got != tt.challenge {
+				t.Errorf("Expected WWW-Authenticate %s, got %s", tt.challenge, got)
+			}
+		})
+	}
+
+	// Without a realm the parameter is omitted
+	req := httptest.NewRequest("GET", "/test", nil)
+	rr := httptest.NewRecorder()
+	New(secret)(handler).ServeHTTP(rr, req)
+
+	want := `Bearer error="invalid_request", error_description="JWT token is missing"`
+	if got := rr.Header().Get("WWW-Authenticate"); got != want {
+		t.Errorf("Expected WWW-Authenticate %s, got %s", want, got)
+	}
+}
+
+func TestJWTAutoRefresh(t *testing.T) {
+	secret := []byte("test-secret")
+
+	sign := func(claims jwt.Claims) (string, error) {
+		mc := claims.(jwt.MapClaims)
+		mc["exp"] = time.Now().Add(time.Hour).Unix()
+		return GenerateToken(secret, mc)
+	}
+
+	handler := New(secret, WithAutoRefresh(5*time.Minute, sign))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		name    string
+		exp     time.Duration
+		code    int
+		refresh bool
+	}{
+		{"Near expiry", time.Minute, http.StatusOK, true},
+		{"Outside window", time.Hour, http.StatusOK, false},
+		{"Expired", -time.Minute, http.StatusUnauthorized, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tokenString, err := GenerateToken(secret, jwt.MapClaims{
+				"user_id": "123",
+				"exp":     time.Now().Add(tt.exp).Unix(),
+			})
+			if err != nil {
+				t.Fatalf("Failed to create token: %v", err)
+			}
+
+			req := httptest.NewRequest("GET", "/test", nil)
+			req.Header.Set("Authorization", "Bearer "+tokenString)
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != tt.code {
+				t.Errorf("Expected status %d, got %d", tt.code, rr.Code)
+			}
+
+			refreshed := rr.Header().Get("X-Refresh-Token")
+			if (refreshed != "") != tt.refresh {
+				t.Fatalf("Expected refresh %v, got header %q", tt.refresh, refreshed)
+			}
+			if !tt.refresh {
+				return
+			}
+
+			claims := jwt.MapClaims{}
+			if _, err := jwt.ParseWithClaims(refreshed, claims, func(*jwt.Token) (interface{}, error) {
+				return secret, nil
+			}); err != nil {
+				t.Fatalf("Failed to parse refreshed token: %v", err)
+			}
+			exp, _ := claims.GetExpirationTime()
+			if time.Until(exp.Time) < 30*time.Minute {
+				t.Errorf("Expected refreshed token to expire later, got %v", exp.Time)
+			}
+			if claims["user_id"] != "123" {
+				t.Errorf("Expected user_id 123, got %v", claims["user_id"])
+			}
+		})
+	}
+}
+
+func TestJWTWithRefreshHeader(t *testing.T) {
+	secret := []byte("test-secret")
+
+	sign := func(claims jwt.Claims) (string, error) {
+		return "new-token", nil
+	}
+
+	handler := New(secret,
+		WithAutoRefresh(5*time.Minute, sign),
+		WithRefreshHeader("X-New-Token"),
+	)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tokenString, err := GenerateToken(secret, jwt.MapClaims{
+		"exp": time.Now().Add(time.Minute).Unix(),
+	})
+	if err != nil {
+		t.Fatalf("Failed to create token: %v", err)
+	}
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	req.Header.Set("Authorization", "Bearer "+tokenString)
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	if rr.Header().Get("X-New-Token") != "new-token" {
+		t.Errorf("Expected X-New-Token 'new-token', got %q", rr.Header().Get("X-New-Token"))
+	}
+	if rr.Header().Get("X-Refresh-Token") != "" {
+		t.Error("Expected no X-Refresh-Token header")
+	}
+}
+
+func TestJWTWithAuthScheme(t *testing.T) {
+	secret := []byte("test-secret")
+
+	tokenString, err := GenerateToken(secret, jwt.MapClaims{
+		"exp": time.Now().Add(time.Hour).Unix(),
+	})
+	if err != nil {
+		t.Fatalf("Failed to create token: %v", err)
+	}
+
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	tests := []struct {
+		name     string
+		opts     []Option
+		auth     string
+		expected int
+	}{
+		{"Default Bearer", nil, "Bearer " + tokenString, http.StatusOK},
+		{"Default rejects Token", nil, "Token " + tokenString, http.StatusUnauthorized},
+		{"Token configured", []Option{WithAuthScheme("Bearer", "Token")}, "Token " + tokenString, http.StatusOK},
+		{"Token case-insensitive", []Option{WithAuthScheme("Bearer", "Token")}, "token " + tokenString, http.StatusOK},
+		{"Bearer still accepted", []Option{WithAuthScheme("Bearer", "Token")}, "Bearer " + tokenString, http.StatusOK},
+		{"Token only rejects Bearer", []Option{WithAuthScheme("Token")}, "Bearer " + tokenString, http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := New(secret, tt.opts...)(ok)
+
+			req := httptest.NewRequest("GET", "/test", nil)
+			req.Header.Set("Authorization", tt.auth)
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != tt.expected {
+				t.Errorf("Expected status %d, got %d", tt.expected, rr.Code)
+			}
+		})
+	}
+}
+
+func TestJWTWithExpiryContext(t *testing.T) {
+	secret := []byte("test-secret")
+	exp := time.Now().Add(time.Hour).Truncate(time.Second)
+
+	tests := []struct {
+		name     string
+		claims   jwt.MapClaims
+		opts     []Option
+		deadline bool
+	}{
+		{"Deadline from exp", jwt.MapClaims{"exp": exp.Unix()}, []Option{WithExpiryContext(true)}, true},
+		{"No exp claim", jwt.MapClaims{"user_id": "123"}, []Option{WithExpiryContext(true)}, false},
+		{"Disabled", jwt.MapClaims{"exp": exp.Unix()}, nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tokenString, err := GenerateToken(secret, tt.claims)
+			if err != nil {
+				t.Fatalf("Failed to create token: %v", err)
+			}
+
+			var deadline time.Time
+			var ok bool
+			handler := New(secret, tt.opts...)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				deadline, ok = r.Context().Deadline()
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			req := httptest.NewRequest("GET", "/test", nil)
+			req.Header.Set("Authorization", "Bearer "+tokenString)
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != http.StatusOK {
+				t.Fatalf("Expected status 200, got %d", rr.Code)
+			}
+			if ok != tt.deadline {
+				t.Fatalf("Expected deadline %v, got %v", tt.deadline, ok)
+			}
+			if tt.deadline && !deadline.Equal(exp) {
+				t.Errorf("Expected deadline %v, got %v", exp, deadline)
+			}
+		})
+	}
+}
+
+func TestJWTWithValidMethods(t *testing.T) {
+	secret := []byte("test-secret")
+	claims := jwt.MapClaims{"exp": time.Now().Add(time.Hour).Unix()}
+
+	hs256, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(secret)
+	if err != nil {
+		t.Fatalf("Failed to create token: %v", err)
+	}
+	hs512, err := jwt.NewWithClaims(jwt.SigningMethodHS512, claims).SignedString(secret)
+	if err != nil {
+		t.Fatalf("Failed to create token: %v", err)
+	}
+	none, err := jwt.NewWithClaims(jwt.SigningMethodNone, claims).SignedString(jwt.UnsafeAllowNoneSignatureType)
+	if err != nil {
+		t.Fatalf("Failed to create token: %v", err)
+	}
+
+	handler := New(secret, WithValidMethods([]string{"HS256"}))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		name     string
+		token    string
+		expected int
+	}{
+		{"Allowed HS256", hs256, http.StatusOK},
+		{"Unlisted HS512", hs512, http.StatusUnauthorized},
+		{"alg none", none, http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/test", nil)
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != tt.expected {
+				t.Errorf("Expected status %d, got %d", tt.expected, rr.Code)
+			}
+		})
+	}
+}
+
+func TestJWTWithOptional(t *testing.T) {
+	secret := []byte("test-secret")
+	token, err := GenerateTokenWithDefaultClaims(secret, map[string]interface{}{
+		"sub": "user123",
+		"exp": time.Now().Add(time.Hour).Unix(),
+	})
+	if err != nil {
+		t.Fatalf("Failed to generate token: %v", err)
+	}
+
+	tests := []struct {
+		name          string
+		authorization string
+		expected      int
+		hasClaims     bool
+	}{
+		{"No token", "", http.StatusOK, false},
+		{"Bad token", "Bearer invalid.token.here", http.StatusUnauthorized, false},
+		{"Unaccepted scheme", "Basic dXNlcjpwYXNz", http.StatusUnauthorized, false},
+		{"Good token", "Bearer " + token, http.StatusOK, true},
+	}
+
+	outcomes := outcomeRecorder{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			called := false
+			var hasClaims bool
+			handler := New(secret, WithOptional(true), WithMetrics(outcomes))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				called = true
+				_, hasClaims = GetClaims(r.Context())
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			req := httptest.NewRequest("GET", "/test", nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != tt.expected {
+				t.Errorf("Expected status %d, got %d", tt.expected, rr.Code)
+			}
+			if called != (tt.expected == http.StatusOK) {
+				t.Errorf("Expected handler called %v, got %v", tt.expected == http.StatusOK, called)
+			}
+			if hasClaims != tt.hasClaims {
+				t.Errorf("Expected claims present %v, got %v", tt.hasClaims, hasClaims)
+			}
+		})
+	}
+
+	// Anonymous requests aren't counted as missing-token rejections; the
+	// unaccepted scheme is
+	if outcomes[OutcomeAnonymous] != 1 || outcomes[OutcomeMissing] != 1 {
+		t.Errorf("Expected 1 anonymous and 1 missing outcome, got %v", outcomes)
+	}
+}
+
+func TestJWTEdDSA(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	_, otherPriv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+
+	claims := jwt.MapClaims{"sub": "user123", "exp": time.Now().Add(time.Hour).Unix()}
+	valid, err := GenerateTokenEdDSA(priv, claims)
+	if err != nil {
+		t.Fatalf("Failed to generate token: %v", err)
+	}
+	otherKey, err := GenerateTokenEdDSA(otherPriv, claims)
+	if err != nil {
+		t.Fatalf("Failed to generate token: %v", err)
+	}
+	hmac, err := GenerateToken([]byte("test-secret"), claims)
+	if err != nil {
+		t.Fatalf("Failed to generate token: %v", err)
+	}
+
+	var subject string
+	handler := New(nil,
+		WithSigningMethod(jwt.SigningMethodEdDSA),
+		WithValidMethods([]string{"EdDSA"}),
+		WithKeyFunc(func(token *jwt.Token) (interface{}, error) {
+			return pub, nil
+		}),
+	)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		claims, _ := GetClaims(r.Context())
+		subject, _ = claims.GetSubject()
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		name     string
+		token    string
+		expected int
+	}{
+		{"Ed25519 token", valid, http.StatusOK},
+		{"Signed with another key", otherKey, http.StatusUnauthorized},
+		{"HS256 token", hmac, http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			subject = ""
+			req := httptest.NewRequest("GET", "/test", nil)
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != tt.expected {
+				t.Errorf("Expected status %d, got %d", tt.expected, rr.Code)
+			}
+			if tt.expected == http.StatusOK && subject != "user123" {
+				t.Errorf("Expected subject 'user123', got %q", subject)
+			}
+		})
+	}
+
+	if _, err := GenerateTokenEdDSA(nil, claims); err == nil {
+		t.Error("Expected error for nil private key")
+	}
+}
+
+func TestJWTWithCookieRenewal(t *testing.T) {
+	secret := []byte("test-secret")
+
+	sign := func(claims jwt.Claims) (string, error) {
+		mc := claims.(jwt.MapClaims)
+		mc["exp"] = time.Now().Add(time.Hour).Unix()
+		return GenerateToken(secret, mc)
+	}
+
+	handler := New(secret,
+		WithAutoRefresh(5*time.Minute, sign),
+		WithCookieRenewal("access_token", http.Cookie{
+			Path:     "/app",
+			HttpOnly: true,
+			Secure:   true,
+			SameSite: http.SameSiteStrictMode,
+		}),
+	)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tokenString, err := GenerateToken(secret, jwt.MapClaims{
+		"user_id": "123",
+		"exp":     time.Now().Add(time.Minute).Unix(),
+	})
+	if err != nil {
+		t.Fatalf("Failed to create token: %v", err)
+	}
+
+	// The token is read from the cookie
+	req := httptest.NewRequest("GET", "/app", nil)
+	req.AddCookie(&http.Cookie{Name: "access_token", Value: tokenString})
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", rr.Code)
+	}
+	if rr.Header().Get("X-Refresh-Token") != "" {
+		t.Error("Expected the renewed token in a cookie, not the refresh header")
+	}
+
+	cookies := rr.Result().Cookies()
+	if len(cookies) != 1 {
+		t.Fatalf("Expected 1 renewal cookie, got %d", len(cookies))
+	}
+	c := cookies[0]
+	if c.Name != "access_token" || c.Value == "" || c.Value == tokenString {
+		t.Errorf("Expected a renewed access_token cookie, got %s=%q", c.Name, c.Value)
+	}
+	if c.Path != "/app" || !c.HttpOnly || !c.Secure || c.SameSite != http.SameSiteStrictMode {
+		t.Errorf("Expected cookie flags to be preserved, got %+v", c)
+	}
+
+	// The renewed cookie authenticates the next request
+	req = httptest.NewRequest("GET", "/app", nil)
+	req.AddCookie(&http.Cookie{Name: "access_token", Value: c.Value})
+	rr = httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Errorf("Expected status 200 with renewed cookie, got %d", rr.Code)
+	}
+	if len(rr.Result().Cookies()) != 0 {
+		t.Error("Expected no renewal for a token outside the refresh window")
+	}
+}
+
+func TestRequireClaim(t *testing.T) {
+	secret := []byte("test-secret")
+
+	tests := []struct {
+		name     string
+		claims   map[string]interface{}
+		expected int
+	}{
+		{"Permitted role", map[string]interface{}{"roles": []string{"user", "admin"}}, http.StatusOK},
+		{"Forbidden role", map[string]interface{}{"roles": []string{"user"}}, http.StatusForbidden},
+		{"String claim", map[string]interface{}{"roles": "admin"}, http.StatusOK},
+		{"Missing claim", map[string]interface{}{"sub": "user123"}, http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.claims["exp"] = time.Now().Add(time.Hour).Unix()
+			token, err := GenerateTokenWithDefaultClaims(secret, tt.claims)
+			if err != nil {
+				t.Fatalf("Failed to generate token: %v", err)
+			}
+
+			handler := New(secret)(RequireClaim("roles", "admin", "owner")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			})))
+
+			req := httptest.NewRequest("GET", "/admin", nil)
+			req.Header.Set("Authorization", "Bearer "+token)
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != tt.expected {
+				t.Errorf("Expected status %d, got %d", tt.expected, rr.Code)
+			}
+			if tt.expected == http.StatusForbidden {
+				var body ae.Error
+				if err := json.Unmarshal(rr.Body.Bytes(), &body); err != nil || body.Message != ErrClaimNotAllowed.Error() {
+					t.Errorf("Expected JSON error %q, got %q", ErrClaimNotAllowed.Error(), rr.Body.String())
+				}
+			}
+		})
+	}
+
+	// Anonymous requests let through by WithOptional have no claims
+	handler := New(secret, WithOptional(true))(RequireClaim("roles", "admin")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})))
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, httptest.NewRequest("GET", "/admin", nil))
+
+	if rr.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status 401 without claims, got %d", rr.Code)
+	}
+	if rr.Header().Get("WWW-Authenticate") == "" {
+		t.Error("Expected WWW-Authenticate challenge without claims")
+	}
+}
+
+func TestRequireClaimWithContextKey(t *testing.T) {
+	secret := []byte("test-secret")
+	token, err := GenerateTokenWithDefaultClaims(secret, map[string]interface{}{
+		"roles": []string{"admin"},
+		"exp":   time.Now().Add(time.Hour).Unix(),
+	})
+	if err != nil {
+		t.Fatalf("Failed to generate token: %v", err)
+	}
+
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	// RequireClaim follows the context key New was configured with
+	handler := New(secret, WithContextKey("auth"))(RequireClaim("roles", "admin")(ok))
+	req := httptest.NewRequest("GET", "/admin", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Errorf("Expected status 200 with WithContextKey, got %d", rr.Code)
+	}
+
+	// RequireClaimWithKey reads claims stored by other middleware
+	handler = RequireClaimWithKey("principal", "roles", "admin")(ok)
+	req = httptest.NewRequest("GET", "/admin", nil)
+	ctx := context.WithValue(req.Context(), contextKey("principal"), jwt.MapClaims{"roles": "admin"})
+	rr = httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req.WithContext(ctx))
+
+	if rr.Code != http.StatusOK {
+		t.Errorf("Expected status 200 with RequireClaimWithKey, got %d", rr.Code)
+	}
+}
+
+func TestRequireClaimUnauthorizedResponse(t *testing.T) {
+	handler := New([]byte("test-secret"),
+		WithOptional(true),
+		WithRealm("api"),
+		WithUnauthorizedStatus(http.StatusForbidden),
+	)(RequireClaim("roles", "admin")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})))
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, httptest.NewRequest("GET", "/admin", nil))
+
+	if rr.Code != http.StatusForbidden {
+		t.Errorf("Expected the configured status 403, got %d", rr.Code)
+	}
+	want := `Bearer realm="api", error="invalid_request", error_description="JWT token is missing"`
+	if got := rr.Header().Get("WWW-Authenticate"); got != want {
+		t.Errorf("Expected WWW-Authenticate %s, got %s", want, got)
+	}
+}
+
+func TestRequireClaimStructClaims(t *testing.T) {
+	type roleClaims struct {
+		Roles []string `json:"roles"`
+		jwt.RegisteredClaims
+	}
+
+	handler := RequireClaim("roles", "admin")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest("GET", "/admin", nil)
+	ctx := context.WithValue(req.Context(), contextKey("user"), &roleClaims{Roles: []string{"admin"}})
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req.WithContext(ctx))
+
+	if rr.Code != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", rr.Code)
+	}
+}
+
+func TestJWTWithUnauthorizedStatus(t *testing.T) {
+	secret := []byte("test-secret")
+
+	sign := func(method jwt.SigningMethod, key []byte, claims jwt.MapClaims) string {
+		if _, ok := claims["exp"]; !ok {
+			claims["exp"] = time.Now().Add(time.Hour).Unix()
+		}
+		token, err := jwt.NewWithClaims(method, claims).SignedString(key)
+		if err != nil {
+			t.Fatalf("Failed to create token: %v", err)
+		}
+		return token
+	}
+
+	handler := New(secret,
+		WithUnauthorizedStatus(http.StatusForbidden),
+		WithIssuerPattern(regexp.MustCompile(`^https://issuer\.example\.com$`)),
+		WithAudiencePattern(regexp.MustCompile(`^api$`)),
+	)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	valid := jwt.MapClaims{"iss": "https://issuer.example.com", "aud": "api"}
+	with := func(k string, v interface{}) jwt.MapClaims {
+		claims := jwt.MapClaims{}
+		for key, value := range valid {
+			claims[key] = value
+		}
+		claims[k] = v
+		return claims
+	}
+
+	tests := []struct {
+		name    string
+		auth    string
+		message string
+	}{
+		{"Missing token", "", ErrMissingJwtToken.Error()},
+		{"Unaccepted scheme", "Basic dXNlcjpwYXNz", ErrMissingJwtToken.Error()},
+		{"Malformed token", "Bearer not-a-token", ErrTokenInvalid.Error()},
+		{"Expired token", "Bearer " + sign(jwt.SigningMethodHS256, secret, with("exp", time.Now().Add(-time.Hour).Unix())), ErrTokenExpired.Error()},
+		{"Not valid yet", "Bearer " + sign(jwt.SigningMethodHS256, secret, with("nbf", time.Now().Add(time.Hour).Unix())), ErrTokenNotValidYet.Error()},
+		{"Invalid signature", "Bearer " + sign(jwt.SigningMethodHS256, []byte("other-secret"), with("sub", "user123")), ErrTokenParseFail.Error()},
+		{"Wrong signing method", "Bearer " + sign(jwt.SigningMethodHS384, secret, with("sub", "user123")), ErrUnSupportSigningMethod.Error()},
+		{"Invalid issuer", "Bearer " + sign(jwt.SigningMethodHS256, secret, with("iss", "https://evil.example.com")), ErrInvalidIssuer.Error()},
+		{"Invalid audience", "Bearer " + sign(jwt.SigningMethodHS256, secret, with("aud", "other")), ErrInvalidAudience.Error()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/test", nil)
+			if tt.auth != "" {
+				req.Header.Set("Authorization", tt.auth)
+			}
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != http.StatusForbidden {
+				t.Errorf("Expected status 403, got %d", rr.Code)
+			}
+			var body ae.Error
+			if err := json.Unmarshal(rr.Body.Bytes(), &body); err != nil {
+				t.Fatalf("Expected JSON error body, got %q", rr.Body.String())
+			}
+			if body.Code != http.StatusForbidden || body.Message != tt.message {
+				t.Errorf("Expected error %d %q, got %d %q", http.StatusForbidden, tt.message, body.Code, body.Message)
+			}
+		})
+	}
+
+	// A valid token still passes
+	req := httptest.NewRequest("GET", "/test", nil)
+	req.Header.Set("Authorization", "Bearer "+sign(jwt.SigningMethodHS256, secret, with("sub", "user123")))
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Errorf("Expected status 200 for a valid token, got %d", rr.Code)
+	}
+}
+
+func TestJWTInvalidUnauthorizedStatusPanic(t *testing.T) {
+	for _, code := range []int{http.StatusOK, http.StatusInternalServerError} {
+		func() {
+			defer func() {
+				if r := recover(); r == nil {
+					t.Errorf("Expected panic for status %d", code)
+				}
+			}()
+			New([]byte("test-secret"), WithUnauthorizedStatus(code))
+		}()
+	}
+}
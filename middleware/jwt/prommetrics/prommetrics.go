@@ -0,0 +1,44 @@
+// Package prommetrics provides a Prometheus implementation of jwt.Metrics.
+// It lives in its own package so the jwt middleware does not depend on
+// Prometheus unless this package is imported.
+package prommetrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Metrics records JWT auth outcomes as Prometheus metrics
+type Metrics struct {
+	outcomes *prometheus.CounterVec
+}
+
+// New creates JWT auth metrics and registers them with reg:
+//   - jwt_auth_total{outcome}
+//
+// A counter already registered by another middleware instance is reused, so
+// several instances can share reg.
+func New(reg prometheus.Registerer) (*Metrics, error) {
+	outcomes := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "jwt_auth_total",
+		Help: "JWT authentication decisions, by outcome.",
+	}, []string{"outcome"})
+
+	if err := reg.Register(outcomes); err != nil {
+		are, ok := err.(prometheus.AlreadyRegisteredError)
+		if !ok {
+			return nil, err
+		}
+		existing, ok := are.ExistingCollector.(*prometheus.CounterVec)
+		if !ok {
+			return nil, err
+		}
+		outcomes = existing
+	}
+
+	return &Metrics{outcomes: outcomes}, nil
+}
+
+// IncOutcome implements jwt.Metrics
+func (m *Metrics) IncOutcome(outcome string) {
+	m.outcomes.WithLabelValues(outcome).Inc()
+}
@@ -0,0 +1,73 @@
+package prommetrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	gojwt "github.com/golang-jwt/jwt/v5"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/xushuhui/ares-contrib/middleware/jwt"
+)
+
+func TestMetrics(t *testing.T) {
+	secret := []byte("test-secret")
+	reg := prometheus.NewRegistry()
+	metrics, err := New(reg)
+	if err != nil {
+		t.Fatalf("Failed to create metrics: %v", err)
+	}
+
+	handler := jwt.New(secret, jwt.WithMetrics(metrics))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	valid, _ := gojwt.NewWithClaims(gojwt.SigningMethodHS256, gojwt.MapClaims{
+		"exp": time.Now().Add(time.Hour).Unix(),
+	}).SignedString(secret)
+	expired, _ := gojwt.NewWithClaims(gojwt.SigningMethodHS256, gojwt.MapClaims{
+		"exp": time.Now().Add(-time.Hour).Unix(),
+	}).SignedString(secret)
+
+	for _, token := range []string{valid, valid, expired, ""} {
+		req := httptest.NewRequest("GET", "/test", nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	expected := map[string]float64{
+		jwt.OutcomeSuccess: 2,
+		jwt.OutcomeExpired: 1,
+		jwt.OutcomeMissing: 1,
+	}
+	for outcome, want := range expected {
+		if got := testutil.ToFloat64(metrics.outcomes.WithLabelValues(outcome)); got != want {
+			t.Errorf("Expected %v %s outcomes, got %v", want, outcome, got)
+		}
+	}
+}
+
+func TestMetricsSharedRegistry(t *testing.T) {
+	reg := prometheus.NewRegistry()
+
+	first, err := New(reg)
+	if err != nil {
+		t.Fatalf("Failed to create metrics: %v", err)
+	}
+	second, err := New(reg)
+	if err != nil {
+		t.Fatalf("Expected second metrics to reuse the counter, got %v", err)
+	}
+
+	first.IncOutcome(jwt.OutcomeSuccess)
+	second.IncOutcome(jwt.OutcomeSuccess)
+
+	if got := testutil.ToFloat64(first.outcomes.WithLabelValues(jwt.OutcomeSuccess)); got != 2 {
+		t.Errorf("Expected shared counter to be 2, got %v", got)
+	}
+}
@@ -0,0 +1,215 @@
+package logger
+
+import (
+	"bufio"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/xushuhui/ares-contrib/middleware/requestid"
+)
+
+// Option is logger option.
+type Option func(*options)
+
+// options defines the configuration for the access logger middleware
+type options struct {
+	// Logger receives one record per request
+	// Default: slog.Default()
+	logger *slog.Logger
+
+	// Message is the log record message
+	// Default: "http request"
+	message string
+
+	// Skipper skips logging for a request, e.g. health checks
+	skipper func(r *http.Request) bool
+
+	// Fields returns extra attributes to add to the record
+	fields func(r *http.Request) []slog.Attr
+
+	// StatusLevels maps a status class (1-5) to a log level
+	// Default: 4xx -> Warn, 5xx -> Error, others -> Info
+	statusLevels map[int]slog.Level
+
+	// RequestIDHeader is read when no request ID is stored in the context
+	// Default: "X-Request-ID"
+	requestIDHeader string
+}
+
+// WithLogger sets the slog logger
+func WithLogger(logger *slog.Logger) Option {
+	return func(o *options) {
+		o.logger = logger
+	}
+}
+
+// WithMessage sets the log record message
+func WithMessage(message string) Option {
+	return func(o *options) {
+		o.message = message
+	}
+}
+
+// WithSkipper sets a function to skip the middleware for a request
+func WithSkipper(f func(r *http.Request) bool) Option {
+	return func(o *options) {
+		o.skipper = f
+	}
+}
+
+// WithFields sets a function returning extra attributes for each record
+func WithFields(f func(r *http.Request) []slog.Attr) Option {
+	return func(o *options) {
+		o.fields = f
+	}
+}
+
+// WithStatusLevel sets the log level for a status class, e.g.
+// WithStatusLevel(4, slog.LevelInfo) logs 4xx responses at info
+func WithStatusLevel(class int, level slog.Level) Option {
+	return func(o *options) {
+		o.statusLevels[class] = level
+	}
+}
+
+// WithRequestIDHeader sets the header read when no request ID is stored in the context
+func WithRequestIDHeader(header string) Option {
+	return func(o *options) {
+		o.requestIDHeader = header
+	}
+}
+
+// responseWriter captures the status code and bytes written
+type responseWriter struct {
+	http.ResponseWriter
+	status      int
+	bytes       int64
+	wroteHeader bool
+}
+
+// WriteHeader implements http.ResponseWriter
+func (w *responseWriter) WriteHeader(code int) {
+	if !w.wroteHeader {
+		w.status = code
+		w.wroteHeader = true
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+// Write implements http.ResponseWriter
+func (w *responseWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	n, err := w.ResponseWriter.Write(b)
+	w.bytes += int64(n)
+	return n, err
+}
+
+// Flush implements http.Flusher
+func (w *responseWriter) Flush() {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack implements http.Hijacker
+func (w *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("logger: underlying ResponseWriter does not implement http.Hijacker")
+	}
+	if !w.wroteHeader {
+		// The connection is handed over; report it as a protocol switch
+		w.status = http.StatusSwitchingProtocols
+		w.wroteHeader = true
+	}
+	return h.Hijack()
+}
+
+// Unwrap returns the underlying http.ResponseWriter
+func (w *responseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// clientIP returns the host part of RemoteAddr
+func clientIP(r *http.Request) string {
+	if ip, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return ip
+	}
+	return r.RemoteAddr
+}
+
+// New returns an access logger middleware recording one slog record per request
+func New(opts ...Option) func(http.Handler) http.Handler {
+	o := &options{
+		message: "http request",
+		statusLevels: map[int]slog.Level{
+			4: slog.LevelWarn,
+			5: slog.LevelError,
+		},
+		requestIDHeader: "X-Request-ID",
+	}
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if o.skipper != nil && o.skipper(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			start := time.Now()
+			rw := &responseWriter{ResponseWriter: w, status: http.StatusOK}
+
+			next.ServeHTTP(rw, r)
+
+			logger := o.logger
+			if logger == nil {
+				logger = slog.Default()
+			}
+
+			level, ok := o.statusLevels[rw.status/100]
+			if !ok {
+				level = slog.LevelInfo
+			}
+
+			attrs := []slog.Attr{
+				slog.String("method", r.Method),
+				slog.String("path", r.URL.Path),
+				slog.Int("status", rw.status),
+				slog.Int64("bytes", rw.bytes),
+				slog.Duration("latency", time.Since(start)),
+				slog.String("ip", clientIP(r)),
+			}
+
+			// The request ID is only in the context when the requestid
+			// middleware runs before this one; otherwise use the header it sets
+			id, ok := requestid.FromContext(r.Context())
+			if !ok {
+				id = w.Header().Get(o.requestIDHeader)
+			}
+			if id == "" {
+				id = r.Header.Get(o.requestIDHeader)
+			}
+			if id != "" {
+				attrs = append(attrs, slog.String("request_id", id))
+			}
+
+			if o.fields != nil {
+				attrs = append(attrs, o.fields(r)...)
+			}
+
+			logger.LogAttrs(r.Context(), level, o.message, attrs...)
+		})
+	}
+}
@@ -0,0 +1,217 @@
+package logger
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/xushuhui/ares-contrib/middleware/requestid"
+)
+
+// newTestLogger returns a JSON logger writing to buf
+func newTestLogger(buf *bytes.Buffer) *slog.Logger {
+	return slog.New(slog.NewJSONHandler(buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+}
+
+// decodeRecord decodes the single JSON record in buf
+func decodeRecord(t *testing.T, buf *bytes.Buffer) map[string]any {
+	t.Helper()
+	var record map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatalf("Failed to decode log record %q: %v", buf.String(), err)
+	}
+	return record
+}
+
+func TestLogger(t *testing.T) {
+	var buf bytes.Buffer
+	middleware := New(WithLogger(newTestLogger(&buf)))
+
+	handler := middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte("hello"))
+	}))
+
+	req := httptest.NewRequest("POST", "/users", nil)
+	req.RemoteAddr = "10.0.0.1:1234"
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	record := decodeRecord(t, &buf)
+
+	expected := map[string]any{
+		"level":  "INFO",
+		"msg":    "http request",
+		"method": "POST",
+		"path":   "/users",
+		"status": float64(201),
+		"bytes":  float64(5),
+		"ip":     "10.0.0.1",
+	}
+	for key, want := range expected {
+		if record[key] != want {
+			t.Errorf("Expected %s=%v, got %v", key, want, record[key])
+		}
+	}
+
+	if _, ok := record["latency"]; !ok {
+		t.Error("Expected latency to be logged")
+	}
+}
+
+func TestLoggerStatusLevels(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		opts     []Option
+		expected string
+	}{
+		{"2xx", http.StatusOK, nil, "INFO"},
+		{"4xx", http.StatusNotFound, nil, "WARN"},
+		{"5xx", http.StatusInternalServerError, nil, "ERROR"},
+		{"Custom 4xx", http.StatusNotFound, []Option{WithStatusLevel(4, slog.LevelInfo)}, "INFO"},
+		{"Custom 2xx", http.StatusOK, []Option{WithStatusLevel(2, slog.LevelDebug)}, "DEBUG"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			middleware := New(append([]Option{WithLogger(newTestLogger(&buf))}, tt.opts...)...)
+
+			handler := middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+			}))
+
+			handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/test", nil))
+
+			record := decodeRecord(t, &buf)
+			if record["level"] != tt.expected {
+				t.Errorf("Expected level %s, got %v", tt.expected, record["level"])
+			}
+		})
+	}
+}
+
+func TestLoggerSkipper(t *testing.T) {
+	var buf bytes.Buffer
+	middleware := New(
+		WithLogger(newTestLogger(&buf)),
+		WithSkipper(func(r *http.Request) bool {
+			return r.URL.Path == "/health"
+		}),
+	)
+
+	handler := middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/health", nil))
+
+	if buf.Len() != 0 {
+		t.Errorf("Expected no log output for skipped request, got %q", buf.String())
+	}
+}
+
+func TestLoggerWithFields(t *testing.T) {
+	var buf bytes.Buffer
+	middleware := New(
+		WithLogger(newTestLogger(&buf)),
+		WithFields(func(r *http.Request) []slog.Attr {
+			return []slog.Attr{slog.String("user_agent", r.UserAgent())}
+		}),
+	)
+
+	handler := middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	req.Header.Set("User-Agent", "test-agent")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	record := decodeRecord(t, &buf)
+	if record["user_agent"] != "test-agent" {
+		t.Errorf("Expected user_agent=test-agent, got %v", record["user_agent"])
+	}
+	if record["status"] != float64(200) {
+		t.Errorf("Expected implicit status 200, got %v", record["status"])
+	}
+}
+
+func TestLoggerRequestID(t *testing.T) {
+	var buf bytes.Buffer
+
+	// Logger wraps requestid, so the ID is taken from the response header
+	handler := New(WithLogger(newTestLogger(&buf)))(
+		requestid.New()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})),
+	)
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	req.Header.Set("X-Request-ID", "abc-123")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	record := decodeRecord(t, &buf)
+	if record["request_id"] != "abc-123" {
+		t.Errorf("Expected request_id=abc-123, got %v", record["request_id"])
+	}
+}
+
+// hijackRecorder is a ResponseRecorder that supports hijacking
+type hijackRecorder struct {
+	*httptest.ResponseRecorder
+	hijacked bool
+}
+
+func (h *hijackRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h.hijacked = true
+	return nil, nil, nil
+}
+
+func TestLoggerPassthrough(t *testing.T) {
+	var buf bytes.Buffer
+	middleware := New(WithLogger(newTestLogger(&buf)))
+
+	rec := &hijackRecorder{ResponseRecorder: httptest.NewRecorder()}
+
+	handler := middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := w.(http.Flusher); !ok {
+			t.Error("Expected wrapper to implement http.Flusher")
+		}
+		hj, ok := w.(http.Hijacker)
+		if !ok {
+			t.Fatal("Expected wrapper to implement http.Hijacker")
+		}
+		hj.Hijack()
+	}))
+
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/ws", nil))
+
+	if !rec.hijacked {
+		t.Error("Expected Hijack to reach the underlying writer")
+	}
+
+	if !strings.Contains(buf.String(), `"status":101`) {
+		t.Errorf("Expected hijacked request logged with status 101, got %q", buf.String())
+	}
+}
+
+func TestLoggerFlush(t *testing.T) {
+	middleware := New(WithLogger(slog.New(slog.DiscardHandler)))
+
+	handler := middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("data"))
+		w.(http.Flusher).Flush()
+	}))
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("GET", "/test", nil))
+
+	if !rr.Flushed {
+		t.Error("Expected Flush to reach the underlying writer")
+	}
+}
@@ -0,0 +1,107 @@
+package maintenance
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Option is maintenance option.
+type Option func(*options)
+
+// options defines the configuration for the maintenance middleware
+type options struct {
+	// Allowlist is a list of URL path prefixes served during maintenance,
+	// e.g. health checks
+	allowlist []string
+
+	// RetryAfter sets the `Retry-After` header, rounded up to whole seconds.
+	// Zero omits it.
+	// Default: 60s
+	retryAfter time.Duration
+
+	// Response writes the maintenance response. The Retry-After header is
+	// set before it is called.
+	// Default: 503 JSON error
+	response func(w http.ResponseWriter, r *http.Request)
+}
+
+// WithAllowlist sets the URL path prefixes served during maintenance
+func WithAllowlist(paths []string) Option {
+	return func(o *options) {
+		o.allowlist = paths
+	}
+}
+
+// WithRetryAfter sets the Retry-After duration sent during maintenance
+func WithRetryAfter(d time.Duration) Option {
+	return func(o *options) {
+		o.retryAfter = d
+	}
+}
+
+// WithResponse sets the function writing the maintenance response,
+// including its status code (typically 503)
+func WithResponse(f func(w http.ResponseWriter, r *http.Request)) Option {
+	return func(o *options) {
+		o.response = f
+	}
+}
+
+// defaultResponse writes a 503 JSON error response
+func defaultResponse(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusServiceUnavailable)
+	w.Write([]byte(`{"error":"service under maintenance"}`))
+}
+
+// allowed reports whether the request path is allowlisted
+func (o *options) allowed(r *http.Request) bool {
+	for _, path := range o.allowlist {
+		if strings.HasPrefix(r.URL.Path, path) {
+			return true
+		}
+	}
+	return false
+}
+
+// New returns a maintenance middleware that answers all requests with 503
+// while enabled is true. Toggle enabled at runtime to enter or leave
+// maintenance without redeploying.
+func New(enabled *atomic.Bool, opts ...Option) func(http.Handler) http.Handler {
+	if enabled == nil {
+		panic("maintenance enabled flag is nil")
+	}
+
+	o := &options{
+		retryAfter: 60 * time.Second,
+		response:   defaultResponse,
+	}
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	// Round up so sub-second durations never send "0"
+	var retryAfter string
+	if o.retryAfter > 0 {
+		retryAfter = strconv.Itoa(max(int(math.Ceil(o.retryAfter.Seconds())), 1))
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !enabled.Load() || o.allowed(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if retryAfter != "" {
+				w.Header().Set("Retry-After", retryAfter)
+			}
+			o.response(w, r)
+		})
+	}
+}
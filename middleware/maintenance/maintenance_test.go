@@ -0,0 +1,131 @@
+package maintenance
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var okHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+})
+
+func TestMaintenance(t *testing.T) {
+	var enabled atomic.Bool
+	handler := New(&enabled, WithAllowlist([]string{"/health"}))(okHandler)
+
+	serve := func(path string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest("GET", path, nil))
+		return rr
+	}
+
+	// Disabled: requests pass through
+	if rr := serve("/api"); rr.Code != http.StatusOK {
+		t.Errorf("Expected status 200 when disabled, got %d", rr.Code)
+	}
+
+	// Enabled at runtime
+	enabled.Store(true)
+
+	rr := serve("/api")
+	if rr.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected status 503 when enabled, got %d", rr.Code)
+	}
+	if rr.Header().Get("Retry-After") != "60" {
+		t.Errorf("Expected Retry-After 60, got %s", rr.Header().Get("Retry-After"))
+	}
+	expected := `{"error":"service under maintenance"}`
+	if rr.Body.String() != expected {
+		t.Errorf("Expected body %s, got %s", expected, rr.Body.String())
+	}
+
+	// Allowlisted paths still reach the handler
+	if rr := serve("/health"); rr.Code != http.StatusOK {
+		t.Errorf("Expected allowlisted path to return 200, got %d", rr.Code)
+	}
+
+	// Disabled again
+	enabled.Store(false)
+
+	if rr := serve("/api"); rr.Code != http.StatusOK {
+		t.Errorf("Expected status 200 after disabling, got %d", rr.Code)
+	}
+}
+
+func TestMaintenanceWithResponse(t *testing.T) {
+	var enabled atomic.Bool
+	enabled.Store(true)
+
+	handler := New(&enabled,
+		WithRetryAfter(5*time.Minute),
+		WithResponse(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/html")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte("<h1>Back soon</h1>"))
+		}),
+	)(okHandler)
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("GET", "/", nil))
+
+	if rr.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected status 503, got %d", rr.Code)
+	}
+	if rr.Header().Get("Retry-After") != "300" {
+		t.Errorf("Expected Retry-After 300, got %s", rr.Header().Get("Retry-After"))
+	}
+	if rr.Body.String() != "<h1>Back soon</h1>" {
+		t.Errorf("Expected custom body, got %s", rr.Body.String())
+	}
+}
+
+func TestMaintenanceRetryAfterRoundsUp(t *testing.T) {
+	var enabled atomic.Bool
+	enabled.Store(true)
+
+	tests := []struct {
+		name       string
+		retryAfter time.Duration
+		expected   string
+	}{
+		{"Sub-second", 500 * time.Millisecond, "1"},
+		{"Fractional", 1900 * time.Millisecond, "2"},
+		{"Whole seconds", 3 * time.Second, "3"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			New(&enabled, WithRetryAfter(tt.retryAfter))(okHandler).ServeHTTP(rr, httptest.NewRequest("GET", "/", nil))
+
+			if rr.Header().Get("Retry-After") != tt.expected {
+				t.Errorf("Expected Retry-After %s, got %s", tt.expected, rr.Header().Get("Retry-After"))
+			}
+		})
+	}
+}
+
+func TestMaintenanceWithoutRetryAfter(t *testing.T) {
+	var enabled atomic.Bool
+	enabled.Store(true)
+
+	rr := httptest.NewRecorder()
+	New(&enabled, WithRetryAfter(0))(okHandler).ServeHTTP(rr, httptest.NewRequest("GET", "/", nil))
+
+	if rr.Header().Get("Retry-After") != "" {
+		t.Errorf("Expected no Retry-After, got %s", rr.Header().Get("Retry-After"))
+	}
+}
+
+func TestMaintenanceNilPanic(t *testing.T) {
+	defer func() {
+		if r := recover(); r == nil {
+			t.Error("Expected panic for nil flag")
+		}
+	}()
+
+	New(nil)
+}
@@ -0,0 +1,76 @@
+package options
+
+import (
+	"net/http"
+	"strings"
+)
+
+// Option is OPTIONS responder option.
+type Option func(*options)
+
+// options defines the configuration for OPTIONS responder middleware
+type options struct {
+	// AllowedMethods is the list of methods advertised in the Allow header
+	// Default value is ["GET", "HEAD", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"]
+	allowedMethods []string
+
+	// MethodsFunc resolves the allowed methods per request, e.g. per route
+	// Optional. Takes precedence over AllowedMethods when set
+	methodsFunc func(*http.Request) []string
+}
+
+// WithAllowedMethods sets the methods advertised in the Allow header
+func WithAllowedMethods(methods []string) Option {
+	return func(o *options) {
+		o.allowedMethods = methods
+	}
+}
+
+// WithMethodsFunc sets a function resolving the allowed methods per request
+func WithMethodsFunc(f func(r *http.Request) []string) Option {
+	return func(o *options) {
+		o.methodsFunc = f
+	}
+}
+
+// allowHeader builds the Allow header value, always including OPTIONS
+func allowHeader(methods []string) string {
+	for _, m := range methods {
+		if strings.EqualFold(m, http.MethodOptions) {
+			return strings.Join(methods, ", ")
+		}
+	}
+	return strings.Join(append(methods[:len(methods):len(methods)], http.MethodOptions), ", ")
+}
+
+// New returns a middleware answering OPTIONS requests with 204 and an Allow
+// header. Headers already set by upstream middleware, such as CORS headers
+// when CORS passes OPTIONS through, are kept. It should be the innermost
+// middleware so that it only answers what upstream middleware let through.
+func New(opts ...Option) func(http.Handler) http.Handler {
+	o := &options{
+		allowedMethods: []string{"GET", "HEAD", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+	}
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	allow := allowHeader(o.allowedMethods)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodOptions {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if o.methodsFunc != nil {
+				w.Header().Set("Allow", allowHeader(o.methodsFunc(r)))
+			} else {
+				w.Header().Set("Allow", allow)
+			}
+			w.WriteHeader(http.StatusNoContent)
+		})
+	}
+}
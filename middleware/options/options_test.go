@@ -0,0 +1,83 @@
+package options
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// corsHeaders simulates an upstream CORS middleware passing OPTIONS through
+func corsHeaders(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Access-Control-Allow-Origin", "https://example.com")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST")
+		next.ServeHTTP(w, r)
+	})
+}
+
+func TestOptionsResponse(t *testing.T) {
+	handler := corsHeaders(New()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("Handler should not be called for OPTIONS")
+	})))
+
+	req := httptest.NewRequest("OPTIONS", "/users", nil)
+	req.Header.Set("Origin", "https://example.com")
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusNoContent {
+		t.Errorf("Expected status 204, got %d", rr.Code)
+	}
+
+	if rr.Header().Get("Allow") != "GET, HEAD, POST, PUT, PATCH, DELETE, OPTIONS" {
+		t.Errorf("Unexpected Allow header: %s", rr.Header().Get("Allow"))
+	}
+
+	if rr.Header().Get("Access-Control-Allow-Origin") != "https://example.com" {
+		t.Errorf("Expected CORS headers to be kept, got %s", rr.Header().Get("Access-Control-Allow-Origin"))
+	}
+}
+
+func TestOptionsWithMethodsFunc(t *testing.T) {
+	handler := New(WithMethodsFunc(func(r *http.Request) []string {
+		if r.URL.Path == "/users" {
+			return []string{"GET", "POST"}
+		}
+		return []string{"GET"}
+	}))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	tests := map[string]string{
+		"/users":  "GET, POST, OPTIONS",
+		"/health": "GET, OPTIONS",
+	}
+
+	for path, expected := range tests {
+		req := httptest.NewRequest("OPTIONS", path, nil)
+		rr := httptest.NewRecorder()
+
+		handler.ServeHTTP(rr, req)
+
+		if rr.Header().Get("Allow") != expected {
+			t.Errorf("%s: Expected Allow=%q, got %q", path, expected, rr.Header().Get("Allow"))
+		}
+	}
+}
+
+func TestOptionsPassesOtherMethods(t *testing.T) {
+	handler := New()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest("GET", "/users", nil)
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", rr.Code)
+	}
+	if rr.Header().Get("Allow") != "" {
+		t.Error("Expected no Allow header for GET")
+	}
+}
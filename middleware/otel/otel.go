@@ -0,0 +1,176 @@
+// Package otel provides OpenTelemetry server tracing middleware. It is a
+// separate module so the rest of ares-contrib does not depend on
+// OpenTelemetry.
+package otel
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracerName identifies the instrumentation library
+const tracerName = "github.com/xushuhui/ares-contrib/middleware/otel"
+
+// Option is otel option.
+type Option func(*options)
+
+// options defines the configuration for the tracing middleware
+type options struct {
+	// TracerProvider creates the tracer
+	// Default: otel.GetTracerProvider()
+	tracerProvider trace.TracerProvider
+
+	// Propagator extracts the parent span context from request headers
+	// Default: otel.GetTextMapPropagator()
+	propagator propagation.TextMapPropagator
+
+	// SpanNameFunc returns the span name for a request
+	// Default: method, followed by the matched ServeMux route once known
+	spanNameFunc func(r *http.Request) string
+}
+
+// WithTracerProvider sets the TracerProvider used to create spans
+func WithTracerProvider(tp trace.TracerProvider) Option {
+	return func(o *options) {
+		o.tracerProvider = tp
+	}
+}
+
+// WithPropagator sets the propagator used to extract the parent context
+func WithPropagator(p propagation.TextMapPropagator) Option {
+	return func(o *options) {
+		o.propagator = p
+	}
+}
+
+// WithSpanNameFunc sets a function returning the span name for a request
+func WithSpanNameFunc(f func(r *http.Request) string) Option {
+	return func(o *options) {
+		o.spanNameFunc = f
+	}
+}
+
+// statusWriter captures the response status code
+type statusWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+// WriteHeader implements http.ResponseWriter
+func (w *statusWriter) WriteHeader(code int) {
+	if !w.wroteHeader {
+		w.status = code
+		w.wroteHeader = true
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+// Write implements http.ResponseWriter
+func (w *statusWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush implements http.Flusher
+func (w *statusWriter) Flush() {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap returns the underlying http.ResponseWriter
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// route returns the path of the ServeMux pattern matched for r, if any
+func route(r *http.Request) string {
+	pattern := r.Pattern
+	// Strip the method, e.g. "GET /users/{id}"
+	if i := strings.IndexByte(pattern, ' '); i >= 0 {
+		pattern = pattern[i+1:]
+	}
+	// Strip the host, e.g. "example.com/users/{id}"
+	if i := strings.IndexByte(pattern, '/'); i > 0 {
+		pattern = pattern[i:]
+	}
+	return pattern
+}
+
+// New returns a middleware starting a server span per request
+func New(opts ...Option) func(http.Handler) http.Handler {
+	o := &options{}
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			tp := o.tracerProvider
+			if tp == nil {
+				tp = otel.GetTracerProvider()
+			}
+			propagator := o.propagator
+			if propagator == nil {
+				propagator = otel.GetTextMapPropagator()
+			}
+
+			ctx := propagator.Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+
+			name := r.Method
+			if o.spanNameFunc != nil {
+				name = o.spanNameFunc(r)
+			}
+
+			ctx, span := tp.Tracer(tracerName).Start(ctx, name,
+				trace.WithSpanKind(trace.SpanKindServer),
+				trace.WithAttributes(
+					attribute.String("http.request.method", r.Method),
+					attribute.String("url.path", r.URL.Path),
+				),
+			)
+
+			sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+			r = r.WithContext(ctx)
+
+			defer func() {
+				// A ServeMux directly below sets the matched pattern on r
+				if route := route(r); route != "" {
+					span.SetAttributes(attribute.String("http.route", route))
+					if o.spanNameFunc == nil {
+						span.SetName(r.Method + " " + route)
+					}
+				}
+
+				if rec := recover(); rec != nil {
+					span.RecordError(fmt.Errorf("panic: %v", rec), trace.WithStackTrace(true))
+					span.SetStatus(codes.Error, fmt.Sprint(rec))
+					span.End()
+					panic(rec)
+				}
+
+				span.SetAttributes(attribute.Int("http.response.status_code", sw.status))
+				if sw.status >= http.StatusInternalServerError {
+					span.SetStatus(codes.Error, http.StatusText(sw.status))
+				}
+				span.End()
+			}()
+
+			next.ServeHTTP(sw, r)
+		})
+	}
+}
@@ -0,0 +1,152 @@
+package otel
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// newTestProvider returns a TracerProvider recording ended spans
+func newTestProvider() (*sdktrace.TracerProvider, *tracetest.SpanRecorder) {
+	sr := tracetest.NewSpanRecorder()
+	return sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr)), sr
+}
+
+// attr returns the value of key in span attributes
+func attr(span sdktrace.ReadOnlySpan, key attribute.Key) (attribute.Value, bool) {
+	for _, kv := range span.Attributes() {
+		if kv.Key == key {
+			return kv.Value, true
+		}
+	}
+	return attribute.Value{}, false
+}
+
+func TestOtel(t *testing.T) {
+	tp, sr := newTestProvider()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if !trace.SpanContextFromContext(r.Context()).IsValid() {
+			t.Error("Expected span in request context")
+		}
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	handler := New(
+		WithTracerProvider(tp),
+		WithPropagator(propagation.TraceContext{}),
+	)(mux)
+
+	req := httptest.NewRequest("GET", "/users/42", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	spans := sr.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("Expected 1 span, got %d", len(spans))
+	}
+	span := spans[0]
+
+	if span.SpanKind() != trace.SpanKindServer {
+		t.Errorf("Expected server span, got %v", span.SpanKind())
+	}
+	if span.Name() != "GET /users/{id}" {
+		t.Errorf("Expected span name 'GET /users/{id}', got %s", span.Name())
+	}
+	if span.Parent().TraceID().String() != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("Expected parent trace ID from traceparent, got %s", span.Parent().TraceID())
+	}
+	if !span.Parent().IsRemote() {
+		t.Error("Expected remote parent")
+	}
+
+	if v, _ := attr(span, "http.request.method"); v.AsString() != "GET" {
+		t.Errorf("Expected http.request.method=GET, got %s", v.AsString())
+	}
+	if v, _ := attr(span, "http.route"); v.AsString() != "/users/{id}" {
+		t.Errorf("Expected http.route=/users/{id}, got %s", v.AsString())
+	}
+	if v, _ := attr(span, "http.response.status_code"); v.AsInt64() != http.StatusCreated {
+		t.Errorf("Expected http.response.status_code=201, got %d", v.AsInt64())
+	}
+	if span.Status().Code == codes.Error {
+		t.Error("Expected non-error status")
+	}
+}
+
+func TestOtelServerError(t *testing.T) {
+	tp, sr := newTestProvider()
+
+	handler := New(WithTracerProvider(tp))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/test", nil))
+
+	spans := sr.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("Expected 1 span, got %d", len(spans))
+	}
+	if spans[0].Status().Code != codes.Error {
+		t.Errorf("Expected error status for 500, got %v", spans[0].Status().Code)
+	}
+}
+
+func TestOtelPanic(t *testing.T) {
+	tp, sr := newTestProvider()
+
+	handler := New(WithTracerProvider(tp))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	}))
+
+	func() {
+		defer func() {
+			if rec := recover(); rec != "boom" {
+				t.Errorf("Expected panic to propagate, got %v", rec)
+			}
+		}()
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/test", nil))
+	}()
+
+	spans := sr.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("Expected span to be ended after panic, got %d spans", len(spans))
+	}
+	if spans[0].Status().Code != codes.Error {
+		t.Errorf("Expected error status after panic, got %v", spans[0].Status().Code)
+	}
+	if len(spans[0].Events()) == 0 {
+		t.Error("Expected panic to be recorded as an event")
+	}
+}
+
+func TestOtelWithSpanNameFunc(t *testing.T) {
+	tp, sr := newTestProvider()
+
+	handler := New(
+		WithTracerProvider(tp),
+		WithSpanNameFunc(func(r *http.Request) string {
+			return "custom " + r.URL.Path
+		}),
+	)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/test", nil))
+
+	spans := sr.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("Expected 1 span, got %d", len(spans))
+	}
+	if spans[0].Name() != "custom /test" {
+		t.Errorf("Expected span name 'custom /test', got %s", spans[0].Name())
+	}
+}
@@ -0,0 +1,116 @@
+package propagate
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/xushuhui/ares-contrib/middleware/requestid"
+)
+
+// Option is propagate option.
+type Option func(*options)
+
+// headerSource resolves an outbound header value from context
+type headerSource struct {
+	header string
+	value  func(context.Context) string
+}
+
+// options defines the configuration for the propagating transport
+type options struct {
+	// RequestIDHeader is the outbound header name for the request ID
+	// Default: X-Request-ID
+	requestIDHeader string
+
+	// ContextKey is the key the requestid middleware stored the ID under
+	// Default: "" (uses requestid.FromContext)
+	contextKey string
+
+	// Sources are additional headers resolved from context, e.g. traceparent
+	sources []headerSource
+}
+
+// WithRequestIDHeader sets the outbound request ID header name
+func WithRequestIDHeader(header string) Option {
+	return func(o *options) {
+		o.requestIDHeader = header
+	}
+}
+
+// WithRequestIDContextKey sets the context key used to look up the request ID
+func WithRequestIDContextKey(key string) Option {
+	return func(o *options) {
+		o.contextKey = key
+	}
+}
+
+// WithHeaderFromContext propagates an additional header whose value is
+// resolved from the outbound request context. Empty values are skipped.
+func WithHeaderFromContext(header string, f func(context.Context) string) Option {
+	return func(o *options) {
+		o.sources = append(o.sources, headerSource{header: header, value: f})
+	}
+}
+
+// transport is an http.RoundTripper setting correlation headers from context
+type transport struct {
+	base http.RoundTripper
+	opts *options
+}
+
+// Transport returns an http.RoundTripper that copies the request ID and any
+// configured headers from the outbound request context onto its headers.
+// If base is nil, http.DefaultTransport is used.
+func Transport(base http.RoundTripper, opts ...Option) http.RoundTripper {
+	o := &options{
+		requestIDHeader: "X-Request-ID",
+	}
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	return &transport{base: base, opts: o}
+}
+
+// requestID returns the request ID stored in ctx
+func (t *transport) requestID(ctx context.Context) (string, bool) {
+	if t.opts.contextKey != "" {
+		return requestid.GetRequestIDWithKey(ctx, t.opts.contextKey)
+	}
+	return requestid.FromContext(ctx)
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+
+	headers := make(map[string]string)
+	if id, ok := t.requestID(ctx); ok && id != "" {
+		headers[t.opts.requestIDHeader] = id
+	}
+	for _, s := range t.opts.sources {
+		if v := s.value(ctx); v != "" {
+			headers[s.header] = v
+		}
+	}
+
+	if len(headers) == 0 {
+		return t.base.RoundTrip(req)
+	}
+
+	// RoundTrippers must not modify the caller's request
+	req = req.Clone(ctx)
+	for header, value := range headers {
+		// Headers set explicitly by the caller take precedence
+		if req.Header.Get(header) == "" {
+			req.Header.Set(header, value)
+		}
+	}
+
+	return t.base.RoundTrip(req)
+}
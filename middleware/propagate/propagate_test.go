@@ -0,0 +1,106 @@
+package propagate
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/xushuhui/ares-contrib/middleware/requestid"
+)
+
+// roundTripFunc is a stub http.RoundTripper capturing outbound requests
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func capture(captured **http.Request) http.RoundTripper {
+	return roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		*captured = req
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody, Request: req}, nil
+	})
+}
+
+func TestTransportPropagatesRequestID(t *testing.T) {
+	var outbound *http.Request
+	client := &http.Client{Transport: Transport(capture(&outbound))}
+
+	// Run a request through the requestid middleware and call downstream
+	handler := requestid.New()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req, _ := http.NewRequestWithContext(r.Context(), "GET", "http://downstream/api", nil)
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		resp.Body.Close()
+	}))
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	req.Header.Set("X-Request-ID", "abc-123")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	if outbound == nil {
+		t.Fatal("Expected outbound request")
+	}
+	if got := outbound.Header.Get("X-Request-ID"); got != "abc-123" {
+		t.Errorf("Expected X-Request-ID 'abc-123', got %q", got)
+	}
+}
+
+func TestTransportPropagatesHeaderFromContext(t *testing.T) {
+	type traceKey struct{}
+
+	var outbound *http.Request
+	rt := Transport(capture(&outbound), WithHeaderFromContext("traceparent", func(ctx context.Context) string {
+		v, _ := ctx.Value(traceKey{}).(string)
+		return v
+	}))
+
+	traceparent := "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	ctx := context.WithValue(context.Background(), traceKey{}, traceparent)
+	req, _ := http.NewRequestWithContext(ctx, "GET", "http://downstream/api", nil)
+	if _, err := rt.RoundTrip(req); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if got := outbound.Header.Get("traceparent"); got != traceparent {
+		t.Errorf("Expected traceparent %q, got %q", traceparent, got)
+	}
+
+	// The caller's request must not be modified
+	if req.Header.Get("traceparent") != "" {
+		t.Error("Expected original request headers to be untouched")
+	}
+}
+
+func TestTransportKeepsExplicitHeader(t *testing.T) {
+	var outbound *http.Request
+	rt := Transport(capture(&outbound))
+
+	ctx := context.Background()
+	requestid.New()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx = r.Context()
+	})).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+
+	req, _ := http.NewRequestWithContext(ctx, "GET", "http://downstream/api", nil)
+	req.Header.Set("X-Request-ID", "explicit")
+	rt.RoundTrip(req)
+
+	if got := outbound.Header.Get("X-Request-ID"); got != "explicit" {
+		t.Errorf("Expected explicit X-Request-ID to be kept, got %q", got)
+	}
+}
+
+func TestTransportWithoutContextValues(t *testing.T) {
+	var outbound *http.Request
+	rt := Transport(capture(&outbound))
+
+	req, _ := http.NewRequest("GET", "http://downstream/api", nil)
+	rt.RoundTrip(req)
+
+	if outbound != req {
+		t.Error("Expected request to be passed through unchanged")
+	}
+}
@@ -0,0 +1,130 @@
+package ratelimiter
+
+import (
+	"context"
+	"net/http"
+	"sync"
+)
+
+// semaphore bounds the number of in-flight requests for a key
+type semaphore struct {
+	slots chan struct{}
+	refs  int // number of requests holding or waiting for a slot
+}
+
+// concurrencyLimiter holds the semaphores for each key
+type concurrencyLimiter struct {
+	semaphores  map[string]*semaphore
+	mu          sync.Mutex
+	maxInFlight int
+}
+
+// newConcurrencyLimiter creates a new concurrency limiter
+func newConcurrencyLimiter(maxInFlight int) *concurrencyLimiter {
+	return &concurrencyLimiter{
+		semaphores:  make(map[string]*semaphore),
+		maxInFlight: maxInFlight,
+	}
+}
+
+// acquire reserves a slot for key, returning the semaphore to release
+func (cl *concurrencyLimiter) acquire(key string) *semaphore {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+
+	sem, exists := cl.semaphores[key]
+	if !exists {
+		sem = &semaphore{slots: make(chan struct{}, cl.maxInFlight)}
+		cl.semaphores[key] = sem
+	}
+	sem.refs++
+	return sem
+}
+
+// release drops the reference to sem, removing idle semaphores
+func (cl *concurrencyLimiter) release(key string, sem *semaphore) {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+
+	sem.refs--
+	if sem.refs == 0 {
+		delete(cl.semaphores, key)
+	}
+}
+
+// NewConcurrency returns a middleware admitting at most maxInFlight concurrent
+// requests. By default the limit is global; use WithKeyFunc for a limit per
+// key and WithWait to wait for a free slot instead of rejecting.
+func NewConcurrency(maxInFlight int, opts ...Option) func(http.Handler) http.Handler {
+	o := &options{}
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	if maxInFlight <= 0 {
+		panic("concurrency limit must be greater than 0")
+	}
+
+	limiter := newConcurrencyLimiter(maxInFlight)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if o.skipper != nil && o.skipper(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			var key string
+			if o.keyFunc != nil {
+				key = o.keyFunc(r)
+				if key == "" {
+					switch o.emptyKeyPolicy {
+					case EmptyKeyAllow:
+						next.ServeHTTP(w, r)
+						return
+					case EmptyKeyDeny:
+						o.reject(w, r, key)
+						return
+					}
+				}
+			}
+
+			sem := limiter.acquire(key)
+			defer limiter.release(key, sem)
+
+			if !acquireSlot(r, sem, o) {
+				o.reject(w, r, key)
+				return
+			}
+			o.accept(r, key)
+			// Release on return, including when next panics
+			defer func() { <-sem.slots }()
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// acquireSlot takes a slot from sem, waiting up to maxWait if configured
+func acquireSlot(r *http.Request, sem *semaphore, o *options) bool {
+	select {
+	case sem.slots <- struct{}{}:
+		return true
+	default:
+	}
+
+	if o.maxWait <= 0 {
+		return false
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), o.maxWait)
+	defer cancel()
+
+	select {
+	case sem.slots <- struct{}{}:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
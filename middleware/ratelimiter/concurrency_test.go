@@ -0,0 +1,152 @@
+package ratelimiter
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestConcurrencyLimiter(t *testing.T) {
+	const maxInFlight = 3
+
+	release := make(chan struct{})
+	started := make(chan struct{}, maxInFlight)
+
+	handler := NewConcurrency(maxInFlight)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		started <- struct{}{}
+		<-release
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	codes := make(chan int, maxInFlight+1)
+	var wg sync.WaitGroup
+	for i := 0; i < maxInFlight; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, httptest.NewRequest("GET", "/test", nil))
+			codes <- rr.Code
+		}()
+	}
+
+	// Wait until all slots are taken
+	for i := 0; i < maxInFlight; i++ {
+		<-started
+	}
+
+	// One more request must be rejected
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("GET", "/test", nil))
+	if rr.Code != http.StatusTooManyRequests {
+		t.Errorf("Expected status 429, got %d", rr.Code)
+	}
+
+	close(release)
+	wg.Wait()
+	close(codes)
+
+	for code := range codes {
+		if code != http.StatusOK {
+			t.Errorf("Expected status 200, got %d", code)
+		}
+	}
+}
+
+func TestConcurrencyLimiterReleasesOnPanic(t *testing.T) {
+	handler := NewConcurrency(1)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/panic" {
+			panic("boom")
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	func() {
+		defer func() { recover() }()
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/panic", nil))
+	}()
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("GET", "/test", nil))
+	if rr.Code != http.StatusOK {
+		t.Errorf("Expected status 200 after panic, got %d", rr.Code)
+	}
+}
+
+func TestConcurrencyLimiterPerKey(t *testing.T) {
+	release := make(chan struct{})
+	started := make(chan struct{})
+
+	handler := NewConcurrency(1, WithKeyFunc(func(r *http.Request) string {
+		return r.Header.Get("X-User-ID")
+	}))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-User-ID") == "user1" {
+			started <- struct{}{}
+			<-release
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		req := httptest.NewRequest("GET", "/test", nil)
+		req.Header.Set("X-User-ID", "user1")
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}()
+	<-started
+
+	// A different key has its own slot
+	req := httptest.NewRequest("GET", "/test", nil)
+	req.Header.Set("X-User-ID", "user2")
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", rr.Code)
+	}
+
+	close(release)
+	<-done
+}
+
+func TestConcurrencyLimiterWithWait(t *testing.T) {
+	started := make(chan struct{})
+
+	handler := NewConcurrency(1, WithWait(time.Second))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			close(started)
+			time.Sleep(50 * time.Millisecond)
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	go handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/slow", nil))
+	<-started
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("GET", "/test", nil))
+	if rr.Code != http.StatusOK {
+		t.Errorf("Expected status 200 after waiting, got %d", rr.Code)
+	}
+}
+
+func TestConcurrencyLimiterWithObserver(t *testing.T) {
+	var allowed int
+	middleware := NewConcurrency(1, WithObserver(func(ok bool, key string, r *http.Request) {
+		if ok {
+			allowed++
+		}
+	}))
+
+	handler := middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/test", nil))
+
+	if allowed != 1 {
+		t.Errorf("Expected 1 allowed event, got %d", allowed)
+	}
+}
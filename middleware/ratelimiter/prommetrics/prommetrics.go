@@ -0,0 +1,78 @@
+// Package prommetrics provides a Prometheus implementation of
+// ratelimiter.Metrics. It lives in its own package so the rate limiter does
+// not depend on Prometheus unless this package is imported.
+package prommetrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Metrics records rate limiter outcomes as Prometheus metrics
+type Metrics struct {
+	allowed  prometheus.Counter
+	rejected prometheus.Counter
+	keys     prometheus.Gauge
+}
+
+// New creates rate limiter metrics labelled with the limiter name and
+// registers them with reg:
+//   - ratelimiter_requests_total{limiter, result="allowed|rejected"}
+//   - ratelimiter_keys{limiter}
+func New(reg prometheus.Registerer, name string) (*Metrics, error) {
+	requests := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "ratelimiter_requests_total",
+		Help: "Requests handled by the rate limiter, by result.",
+	}, []string{"limiter", "result"})
+
+	keys := prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "ratelimiter_keys",
+		Help: "Number of keys tracked by the rate limiter.",
+	}, []string{"limiter"})
+
+	requests, err := register(reg, requests)
+	if err != nil {
+		return nil, err
+	}
+	keys, err = register(reg, keys)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Metrics{
+		allowed:  requests.WithLabelValues(name, "allowed"),
+		rejected: requests.WithLabelValues(name, "rejected"),
+		keys:     keys.WithLabelValues(name),
+	}, nil
+}
+
+// register registers c, reusing an identical collector registered by
+// another limiter so several named limiters can share reg
+func register[T prometheus.Collector](reg prometheus.Registerer, c T) (T, error) {
+	if err := reg.Register(c); err != nil {
+		are, ok := err.(prometheus.AlreadyRegisteredError)
+		if !ok {
+			return c, err
+		}
+		existing, ok := are.ExistingCollector.(T)
+		if !ok {
+			return c, err
+		}
+		return existing, nil
+	}
+	return c, nil
+}
+
+// IncAllowed implements ratelimiter.Metrics
+func (m *Metrics) IncAllowed() {
+	m.allowed.Inc()
+}
+
+// IncRejected implements ratelimiter.Metrics
+func (m *Metrics) IncRejected() {
+	m.rejected.Inc()
+}
+
+// SetKeys implements ratelimiter.Metrics
+func (m *Metrics) SetKeys(n int) {
+	m.keys.Set(float64(n))
+}
@@ -0,0 +1,77 @@
+package prommetrics
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/xushuhui/ares-contrib/middleware/ratelimiter"
+)
+
+func TestMetrics(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	metrics, err := New(reg, "api")
+	if err != nil {
+		t.Fatalf("Failed to create metrics: %v", err)
+	}
+
+	middleware := ratelimiter.New(
+		ratelimiter.WithRate(1),
+		ratelimiter.WithBurst(1),
+		ratelimiter.WithMetrics(metrics),
+	)
+
+	handler := middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	// Two requests from the first IP (one rejected), one from each of two others
+	for _, ip := range []string{"10.0.0.1", "10.0.0.1", "10.0.0.2", "10.0.0.3"} {
+		req := httptest.NewRequest("GET", "/test", nil)
+		req.RemoteAddr = fmt.Sprintf("%s:1234", ip)
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	if got := testutil.ToFloat64(metrics.allowed); got != 3 {
+		t.Errorf("Expected 3 allowed requests, got %v", got)
+	}
+	if got := testutil.ToFloat64(metrics.rejected); got != 1 {
+		t.Errorf("Expected 1 rejected request, got %v", got)
+	}
+	if got := testutil.ToFloat64(metrics.keys); got != 3 {
+		t.Errorf("Expected 3 tracked keys, got %v", got)
+	}
+}
+
+func TestMetricsSharedRegistry(t *testing.T) {
+	reg := prometheus.NewRegistry()
+
+	api, err := New(reg, "api")
+	if err != nil {
+		t.Fatalf("Failed to create metrics: %v", err)
+	}
+	search, err := New(reg, "search")
+	if err != nil {
+		t.Fatalf("Failed to create second metrics on the same registry: %v", err)
+	}
+
+	api.IncAllowed()
+	search.IncRejected()
+	search.IncRejected()
+
+	count, err := testutil.GatherAndCount(reg, "ratelimiter_requests_total")
+	if err != nil {
+		t.Fatalf("Failed to gather metrics: %v", err)
+	}
+	// allowed and rejected series for each limiter
+	if count != 4 {
+		t.Errorf("Expected 4 labelled series, got %d", count)
+	}
+	if got := testutil.ToFloat64(search.rejected); got != 2 {
+		t.Errorf("Expected 2 rejected requests for search, got %v", got)
+	}
+}
@@ -1,11 +1,17 @@
 package ratelimiter
 
 import (
+	"container/list"
 	"context"
+	"hash/fnv"
+	"math"
 	"net"
 	"net/http"
+	"net/netip"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"golang.org/x/time/rate"
@@ -23,12 +29,124 @@ type options struct {
 	burst int
 
 	// KeyFunc is a function to extract the key for rate limiting
-	// Default: uses IP address
+	// Default: uses IP address, masked to IPv4PrefixLen or IPv6PrefixLen
 	keyFunc func(*http.Request) string
 
+	// IPv4PrefixLen and IPv6PrefixLen are the prefix lengths client
+	// addresses are masked to by the default key func, so a client rotating
+	// through the addresses of its network shares one bucket
+	// Optional. Default 32 (whole address) for IPv4 and 64 for IPv6
+	ipv4PrefixLen int
+	ipv6PrefixLen int
+
 	// ErrorHandler defines a function which is executed when rate limit is exceeded
 	// Optional. Default value returns 429 Too Many Requests
 	errorHandler func(http.ResponseWriter, *http.Request)
+
+	// RejectStatus is the status code of the default rejection response
+	// Optional. Default value 429 Too Many Requests
+	rejectStatus int
+
+	// RejectContentType and RejectBody replace the default rejection body
+	// Optional. Default value is a JSON error body
+	rejectContentType string
+	rejectBody        string
+
+	// LimitFunc resolves the rate and burst for a request
+	// Optional. Default uses Rate and Burst for every request
+	limitFunc func(*http.Request) (float64, int)
+
+	// LimitLookup resolves the key, rate and burst for a request in one call,
+	// replacing KeyFunc and LimitFunc
+	// Optional. Default nil
+	limitLookup func(*http.Request) (string, float64, int)
+
+	// MaxWait is the maximum time a request waits for a token before being rejected
+	// Optional. Default value 0 rejects immediately
+	maxWait time.Duration
+
+	// Context controls the lifetime of the background cleanup goroutine
+	// Optional. Default value context.Background() runs for the process lifetime
+	ctx context.Context
+
+	// MaxEntries caps the number of tracked keys, evicting the least recently
+	// accessed one when exceeded
+	// Optional. Default value 0 means unlimited
+	maxEntries int
+
+	// KeyHasher hashes keys before they are stored, trading a tiny collision
+	// risk for a fixed memory footprint per key
+	// Optional. Default stores raw string keys
+	keyHasher func(string) uint64
+
+	// CostFunc returns the number of tokens a request consumes
+	// Optional. Default cost is 1
+	costFunc func(*http.Request) int
+
+	// Skipper defines a function to skip rate limiting for a request
+	// Optional. Default nil limits every request
+	skipper func(*http.Request) bool
+
+	// AnnotateFunc is called for allowed requests with the remaining tokens
+	// before the handler runs, e.g. to set quota warning headers
+	// Optional. Default nil
+	annotateFunc func(http.ResponseWriter, float64)
+
+	// Metrics receives allowed/rejected counts and the number of tracked keys
+	// Optional. Default nil records nothing
+	metrics Metrics
+
+	// EmptyKeyPolicy decides how requests are handled when KeyFunc returns ""
+	// Optional. Default EmptyKeyShared limits them together in one bucket
+	emptyKeyPolicy EmptyKeyPolicy
+
+	// MethodScoped appends the request method to the key so each method is
+	// limited in its own bucket
+	// Optional. Default false shares one bucket across methods
+	methodScoped bool
+
+	// Observer is called once per rate limiting decision
+	// Optional. Default nil
+	observer func(allowed bool, key string, r *http.Request)
+
+	// StatsHandle is bound to the limiter so its state can be queried
+	// Optional. Default nil
+	statsHandle *StatsHandle
+
+	// Global limits all requests in one shared bucket instead of per key
+	// Optional. Default false
+	global bool
+
+	// DrainRetryAfter is the Retry-After sent with 503s once draining
+	// Optional. Default 5s
+	drainRetryAfter time.Duration
+}
+
+// EmptyKeyPolicy decides how requests with an empty key are limited
+type EmptyKeyPolicy int
+
+const (
+	// EmptyKeyShared limits all requests with an empty key in one shared bucket
+	EmptyKeyShared EmptyKeyPolicy = iota
+
+	// EmptyKeyAllow skips rate limiting for requests with an empty key
+	EmptyKeyAllow
+
+	// EmptyKeyDeny rejects requests with an empty key
+	EmptyKeyDeny
+)
+
+// Metrics records rate limiter outcomes. See the prommetrics package for a
+// Prometheus implementation.
+type Metrics interface {
+	// IncAllowed is called for each allowed request
+	IncAllowed()
+
+	// IncRejected is called for each rejected request
+	IncRejected()
+
+	// SetKeys is called with the number of tracked keys when it changes
+	SetKeys(n int)
 }
 
 // WithRate sets the rate limit (requests per second)
@@ -52,6 +170,74 @@ func WithKeyFunc(f func(*http.Request) string) Option {
 	}
 }
 
+// WithIPv4PrefixLen sets the prefix length IPv4 addresses are masked to by
+// the default key func. It has no effect with WithKeyFunc.
+func WithIPv4PrefixLen(bits int) Option {
+	return func(o *options) {
+		o.ipv4PrefixLen = bits
+	}
+}
+
+// WithIPv6PrefixLen sets the prefix length IPv6 addresses are masked to by
+// the default key func. It has no effect with WithKeyFunc.
+func WithIPv6PrefixLen(bits int) Option {
+	return func(o *options) {
+		o.ipv6PrefixLen = bits
+	}
+}
+
+// WithEmptyKeyPolicy sets how requests are handled when the key function
+// returns an empty string, e.g. for a missing X-User-ID header
+func WithEmptyKeyPolicy(policy EmptyKeyPolicy) Option {
+	return func(o *options) {
+		o.emptyKeyPolicy = policy
+	}
+}
+
+// WithMethodScoped sets whether each HTTP method gets its own bucket, so
+// e.g. reads from a client don't consume the budget for its writes
+func WithMethodScoped(scoped bool) Option {
+	return func(o *options) {
+		o.methodScoped = scoped
+	}
+}
+
+// WithObserver sets a function called once per request with the rate
+// limiting decision, e.g. to count allowed and throttled requests. Unlike
+// the error handler it is also called for allowed requests. Skipped
+// requests are not observed.
+func WithObserver(f func(allowed bool, key string, r *http.Request)) Option {
+	return func(o *options) {
+		o.observer = f
+	}
+}
+
+// WithGlobal sets whether all requests share one bucket, e.g. to protect a
+// fragile singleton backend. No keys are tracked, so the key function,
+// limit function, empty key policy and method scoping are not used.
+func WithGlobal(global bool) Option {
+	return func(o *options) {
+		o.global = global
+	}
+}
+
+// WithDrainRetryAfter sets the Retry-After duration sent with the 503
+// responses of a draining limiter, rounded up to whole seconds. Zero omits
+// the header.
+func WithDrainRetryAfter(d time.Duration) Option {
+	return func(o *options) {
+		o.drainRetryAfter = d
+	}
+}
+
+// WithStatsHandle binds h to the limiter built by New so its state can be
+// queried with h.Stats, e.g. from a dashboard handler
+func WithStatsHandle(h *StatsHandle) Option {
+	return func(o *options) {
+		o.statsHandle = h
+	}
+}
+
 // WithErrorHandler sets the error handler
 func WithErrorHandler(h func(http.ResponseWriter, *http.Request)) Option {
 	return func(o *options) {
@@ -59,18 +245,138 @@ func WithErrorHandler(h func(http.ResponseWriter, *http.Request)) Option {
 	}
 }
 
+// WithRejectStatus sets the status code of the default rejection response.
+// Ignored when WithErrorHandler is set.
+func WithRejectStatus(status int) Option {
+	return func(o *options) {
+		o.rejectStatus = status
+	}
+}
+
+// WithRejectBody sets the content type and body of the default rejection
+// response. Ignored when WithErrorHandler is set.
+func WithRejectBody(contentType, body string) Option {
+	return func(o *options) {
+		o.rejectContentType = contentType
+		o.rejectBody = body
+	}
+}
+
+// WithLimitFunc sets a function resolving the rate and burst per request,
+// e.g. to apply a tighter limit to expensive routes
+func WithLimitFunc(f func(r *http.Request) (rate float64, burst int)) Option {
+	return func(o *options) {
+		o.limitFunc = f
+	}
+}
+
+// WithLimitLookup sets a function resolving the key together with its rate
+// and burst, e.g. a user ID and the limits of the user's tier. It replaces
+// the key and limit functions; each key and limit pair gets its own limiter.
+func WithLimitLookup(f func(r *http.Request) (key string, rate float64, burst int)) Option {
+	return func(o *options) {
+		o.limitLookup = f
+	}
+}
+
+// WithWait makes requests wait up to maxWait for a token instead of being
+// rejected immediately
+func WithWait(maxWait time.Duration) Option {
+	return func(o *options) {
+		o.maxWait = maxWait
+	}
+}
+
+// WithContext sets the context whose cancellation stops the background
+// cleanup goroutine
+func WithContext(ctx context.Context) Option {
+	return func(o *options) {
+		o.ctx = ctx
+	}
+}
+
+// WithMaxEntries caps the number of tracked keys. When the cap is exceeded
+// the least recently accessed limiter is evicted.
+func WithMaxEntries(n int) Option {
+	return func(o *options) {
+		o.maxEntries = n
+	}
+}
+
+// WithKeyHasher sets a function hashing keys before they are stored.
+// Distinct keys with the same hash share a limiter.
+func WithKeyHasher(f func(string) uint64) Option {
+	return func(o *options) {
+		o.keyHasher = f
+	}
+}
+
+// WithCostFunc sets a function returning the number of tokens a request
+// consumes. Requests costing more than the burst are always rejected.
+func WithCostFunc(f func(r *http.Request) int) Option {
+	return func(o *options) {
+		o.costFunc = f
+	}
+}
+
+// WithSkipper sets a function exempting requests from rate limiting, e.g.
+// health checks or internal traffic. Skipped requests create no limiter.
+func WithSkipper(f func(r *http.Request) bool) Option {
+	return func(o *options) {
+		o.skipper = f
+	}
+}
+
+// WithAnnotateFunc sets a function called for allowed requests with the
+// remaining tokens, before the handler runs
+func WithAnnotateFunc(f func(w http.ResponseWriter, remaining float64)) Option {
+	return func(o *options) {
+		o.annotateFunc = f
+	}
+}
+
+// WithMetrics sets the metrics recorder
+func WithMetrics(m Metrics) Option {
+	return func(o *options) {
+		o.metrics = m
+	}
+}
+
+// FNVKeyHasher hashes a key with 64-bit FNV-1a, for use with WithKeyHasher
+func FNVKeyHasher(key string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	return h.Sum64()
+}
+
+// limiterKey identifies a limiter by client key and resolved limit.
+// Only one of key and hash is set, depending on whether a key hasher is used.
+type limiterKey struct {
+	key   string
+	hash  uint64
+	rate  rate.Limit
+	burst int
+}
+
 // limiterEntry holds a rate limiter with its last access time
 type limiterEntry struct {
 	limiter    *rate.Limiter
 	lastAccess time.Time
+	element    *list.Element // position in the access order list
 }
 
 // rateLimiter holds the rate limiters for each key
 type rateLimiter struct {
-	limiters      map[string]*limiterEntry
+	limiters      map[limiterKey]*limiterEntry
+	accessOrder   *list.List // most recently accessed keys at the front
+	maxEntries    int
+	keyHasher     func(string) uint64
+	onKeys        func(int) // called with the number of keys when it changes
 	mu            sync.RWMutex
 	rate          rate.Limit
 	burst         int
+	global        *rate.Limiter // shared limiter in global mode
+	draining      atomic.Bool
 	cleanupCancel context.CancelFunc
 	cleanupDone   chan struct{}
 }
@@ -78,16 +384,68 @@ type rateLimiter struct {
 // newRateLimiter creates a new rate limiter
 func newRateLimiter(r float64, burst int) *rateLimiter {
 	return &rateLimiter{
-		limiters:    make(map[string]*limiterEntry),
+		limiters:    make(map[limiterKey]*limiterEntry),
+		accessOrder: list.New(),
 		rate:        rate.Limit(r),
 		burst:       burst,
 		cleanupDone: make(chan struct{}),
 	}
 }
 
-// getLimiter returns the rate limiter for the given key
+// getLimiter returns the rate limiter for the given key using the default limit
 func (rl *rateLimiter) getLimiter(key string) *rate.Limiter {
+	rl.mu.RLock()
+	r, burst := rl.rate, rl.burst
+	rl.mu.RUnlock()
+	return rl.getLimiterWithLimit(key, r, burst)
+}
+
+// setRate changes the default limit, updating the limiters of existing keys
+// in place so they keep their tokens
+func (rl *rateLimiter) setRate(r rate.Limit, burst int) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	oldRate, oldBurst := rl.rate, rl.burst
+	rl.rate, rl.burst = r, burst
+
+	if rl.global != nil {
+		rl.global.SetLimit(r)
+		rl.global.SetBurst(burst)
+		return
+	}
+
+	// Limiters resolved by a limit func keep their own limit
+	for key, entry := range rl.limiters {
+		if key.rate != oldRate || key.burst != oldBurst {
+			continue
+		}
+		entry.limiter.SetLimit(r)
+		entry.limiter.SetBurst(burst)
+
+		// Re-key the entry so lookups with the new limit find it
+		newKey := key
+		newKey.rate, newKey.burst = r, burst
+		if newKey == key {
+			continue
+		}
+		rl.remove(newKey)
+		delete(rl.limiters, key)
+		entry.element.Value = newKey
+		rl.limiters[newKey] = entry
+	}
+	rl.keysChanged()
+}
+
+// getLimiterWithLimit returns the rate limiter for the given key and limit
+func (rl *rateLimiter) getLimiterWithLimit(k string, r rate.Limit, burst int) *rate.Limiter {
 	now := time.Now()
+	key := limiterKey{rate: r, burst: burst}
+	if rl.keyHasher != nil {
+		key.hash = rl.keyHasher(k)
+	} else {
+		key.key = k
+	}
 
 	rl.mu.RLock()
 	entry, exists := rl.limiters[key]
@@ -97,6 +455,7 @@ func (rl *rateLimiter) getLimiter(key string) *rate.Limiter {
 		// Update last access time
 		rl.mu.Lock()
 		entry.lastAccess = now
+		rl.accessOrder.MoveToFront(entry.element)
 		rl.mu.Unlock()
 		return entry.limiter
 	}
@@ -106,21 +465,44 @@ func (rl *rateLimiter) getLimiter(key string) *rate.Limiter {
 	entry, exists = rl.limiters[key]
 	if !exists {
 		entry = &limiterEntry{
-			limiter:    rate.NewLimiter(rl.rate, rl.burst),
+			limiter:    rate.NewLimiter(key.rate, key.burst),
 			lastAccess: now,
 		}
+		entry.element = rl.accessOrder.PushFront(key)
 		rl.limiters[key] = entry
+
+		// Evict the least recently accessed limiters over the cap
+		for rl.maxEntries > 0 && len(rl.limiters) > rl.maxEntries {
+			rl.remove(rl.accessOrder.Back().Value.(limiterKey))
+		}
+		rl.keysChanged()
 	} else {
 		entry.lastAccess = now
+		rl.accessOrder.MoveToFront(entry.element)
 	}
 	rl.mu.Unlock()
 
 	return entry.limiter
 }
 
-// cleanup removes old limiters periodically
-func (rl *rateLimiter) cleanup(interval time.Duration, maxAge time.Duration) {
-	ctx, cancel := context.WithCancel(context.Background())
+// remove deletes the limiter for key. The caller must hold the write lock.
+func (rl *rateLimiter) remove(key limiterKey) {
+	if entry, ok := rl.limiters[key]; ok {
+		rl.accessOrder.Remove(entry.element)
+		delete(rl.limiters, key)
+	}
+}
+
+// keysChanged reports the number of keys. The caller must hold the write lock.
+func (rl *rateLimiter) keysChanged() {
+	if rl.onKeys != nil {
+		rl.onKeys(len(rl.limiters))
+	}
+}
+
+// cleanup removes old limiters periodically until ctx is done
+func (rl *rateLimiter) cleanup(parent context.Context, interval time.Duration, maxAge time.Duration) {
+	ctx, cancel := context.WithCancel(parent)
 	rl.cleanupCancel = cancel
 
 	ticker := time.NewTicker(interval)
@@ -138,15 +520,108 @@ func (rl *rateLimiter) cleanup(interval time.Duration, maxAge time.Duration) {
 				// Remove limiters that haven't been accessed recently
 				for key, entry := range rl.limiters {
 					if now.Sub(entry.lastAccess) > maxAge {
-						delete(rl.limiters, key)
+						rl.remove(key)
 					}
 				}
+				rl.keysChanged()
 				rl.mu.Unlock()
 			}
 		}
 	}()
 }
 
+// RateStats is a snapshot of a rate limiter's state
+type RateStats struct {
+	// Keys is the number of tracked keys
+	Keys int
+
+	// Tracked reports whether the queried key has a limiter
+	Tracked bool
+
+	// Remaining is the approximate number of tokens left for the queried key
+	Remaining float64
+
+	// Reset is when the queried key's bucket will be full again
+	Reset time.Time
+}
+
+// StatsHandle queries and adjusts the limiter it is bound to with
+// WithStatsHandle. It is safe for concurrent use with request handling.
+type StatsHandle struct {
+	rl atomic.Pointer[rateLimiter]
+}
+
+// Stats returns the number of tracked keys and the remaining tokens and
+// reset time for key, as returned by the key function. A key without a
+// limiter reports a full bucket at the default limit.
+func (h *StatsHandle) Stats(key string) RateStats {
+	rl := h.rl.Load()
+	if rl == nil {
+		return RateStats{}
+	}
+	return rl.stats(key)
+}
+
+// SetRate changes the default rate and burst at runtime, e.g. on a config
+// reload. Existing keys keep their remaining tokens, capped at the new burst,
+// and new keys use the new limit. Limits from WithLimitFunc are unaffected.
+func (h *StatsHandle) SetRate(r float64, burst int) {
+	if rl := h.rl.Load(); rl != nil {
+		rl.setRate(rate.Limit(r), burst)
+	}
+}
+
+// Drain makes the limiter reject every new request with 503 Service
+// Unavailable, regardless of available tokens, e.g. during graceful
+// shutdown. Requests already admitted are unaffected. Draining can't be
+// undone.
+func (h *StatsHandle) Drain() {
+	if rl := h.rl.Load(); rl != nil {
+		rl.draining.Store(true)
+	}
+}
+
+// stats implements StatsHandle.Stats
+func (rl *rateLimiter) stats(k string) RateStats {
+	now := time.Now()
+	want := limiterKey{key: k}
+	if rl.keyHasher != nil {
+		want = limiterKey{hash: rl.keyHasher(k)}
+	}
+
+	rl.mu.RLock()
+	stats := RateStats{Keys: len(rl.limiters)}
+	var found *limiterEntry
+	if rl.global != nil {
+		// Every key shares the global limiter
+		found = &limiterEntry{limiter: rl.global}
+	}
+	for key, entry := range rl.limiters {
+		match := key.key == want.key && key.hash == want.hash
+		// Keys limited with several limits report the most recently used one
+		if match && (found == nil || entry.lastAccess.After(found.lastAccess)) {
+			found = entry
+		}
+	}
+	burst := rl.burst
+	rl.mu.RUnlock()
+
+	if found == nil {
+		stats.Remaining = float64(burst)
+		stats.Reset = now
+		return stats
+	}
+
+	l := found.limiter
+	stats.Tracked = true
+	stats.Remaining = l.TokensAt(now)
+	stats.Reset = now
+	if missing := float64(l.Burst()) - stats.Remaining; missing > 0 && l.Limit() > 0 && l.Limit() != rate.Inf {
+		stats.Reset = now.Add(time.Duration(missing / float64(l.Limit()) * float64(time.Second)))
+	}
+	return stats
+}
+
 // Stop stops the cleanup goroutine and cleans up resources
 func (rl *rateLimiter) Stop() {
 	if rl.cleanupCancel != nil {
@@ -186,44 +661,216 @@ func extractIP(r *http.Request) string {
 	return r.RemoteAddr
 }
 
+// ipKeyFunc returns a key func keying requests by the client IP masked to
+// the given prefix lengths. Addresses that don't parse are used as is.
+func ipKeyFunc(ipv4Bits, ipv6Bits int) func(*http.Request) string {
+	return func(r *http.Request) string {
+		ip := extractIP(r)
+		addr, err := netip.ParseAddr(ip)
+		if err != nil {
+			return ip
+		}
+		addr = addr.Unmap().WithZone("")
+
+		bits := ipv6Bits
+		if addr.Is4() {
+			bits = ipv4Bits
+		}
+		if bits >= addr.BitLen() {
+			return addr.String()
+		}
+		prefix, _ := addr.Prefix(bits)
+		return prefix.String()
+	}
+}
+
+// accept records an allowed request
+func (o *options) accept(r *http.Request, key string) {
+	if o.metrics != nil {
+		o.metrics.IncAllowed()
+	}
+	if o.observer != nil {
+		o.observer(true, key, r)
+	}
+}
+
+// rejected records a rejected request
+func (o *options) rejected(r *http.Request, key string) {
+	if o.metrics != nil {
+		o.metrics.IncRejected()
+	}
+	if o.observer != nil {
+		o.observer(false, key, r)
+	}
+}
+
+// reject records a rejected request and writes the rate limit exceeded response
+func (o *options) reject(w http.ResponseWriter, r *http.Request, key string) {
+	o.rejected(r, key)
+
+	if o.errorHandler != nil {
+		o.errorHandler(w, r)
+		return
+	}
+
+	status := o.rejectStatus
+	if status == 0 {
+		status = http.StatusTooManyRequests
+	}
+	contentType, body := o.rejectContentType, o.rejectBody
+	if body == "" {
+		contentType, body = "application/json", `{"error":"rate limit exceeded"}`
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(status)
+	w.Write([]byte(body))
+}
+
+// allow reports whether the request may proceed, consuming n tokens and
+// waiting up to maxWait for them. Waiting stops early when the request
+// context is canceled.
+func allow(r *http.Request, l *rate.Limiter, n int, maxWait time.Duration) bool {
+	if maxWait <= 0 {
+		return l.AllowN(time.Now(), n)
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), maxWait)
+	defer cancel()
+
+	// WaitN fails fast when n exceeds the burst or the required delay
+	// exceeds the deadline
+	return l.WaitN(ctx, n) == nil
+}
+
 // New returns a rate limiter middleware with optional configuration
 func New(opts ...Option) func(http.Handler) http.Handler {
 	o := &options{
 		rate:  10,  // 10 requests per second
 		burst: 20,  // Allow burst of 20 requests
-		keyFunc: extractIP, // Use secure IP extraction
+		ctx:   context.Background(),
+
+		ipv4PrefixLen:   32,
+		ipv6PrefixLen:   64,
+		drainRetryAfter: 5 * time.Second,
 	}
 
 	for _, opt := range opts {
 		opt(o)
 	}
 
+	if o.ipv4PrefixLen < 0 || o.ipv4PrefixLen > 32 {
+		panic("IPv4 prefix length must be between 0 and 32")
+	}
+	if o.ipv6PrefixLen < 0 || o.ipv6PrefixLen > 128 {
+		panic("IPv6 prefix length must be between 0 and 128")
+	}
+	if o.keyFunc == nil {
+		// Use secure IP extraction
+		o.keyFunc = ipKeyFunc(o.ipv4PrefixLen, o.ipv6PrefixLen)
+	}
+
 	limiter := newRateLimiter(o.rate, o.burst)
+	limiter.maxEntries = o.maxEntries
+	limiter.keyHasher = o.keyHasher
+	if o.metrics != nil {
+		limiter.onKeys = o.metrics.SetKeys
+	}
 
-	// Start cleanup goroutine to remove old limiters
-	// Clean up limiters that haven't been used for 10 minutes every 5 minutes
-	limiter.cleanup(5*time.Minute, 10*time.Minute)
+	if o.global {
+		limiter.global = rate.NewLimiter(rate.Limit(o.rate), o.burst)
+	} else {
+		// Start cleanup goroutine to remove old limiters
+		// Clean up limiters that haven't been used for 10 minutes every 5 minutes
+		limiter.cleanup(o.ctx, 5*time.Minute, 10*time.Minute)
+	}
+
+	if o.statsHandle != nil {
+		o.statsHandle.rl.Store(limiter)
+	}
 
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			// Get key for rate limiting
-			key := o.keyFunc(r)
+			// A draining limiter admits nothing, skipped requests included,
+			// so health checks report the shutdown too. No key has been
+			// extracted yet, so the rejection is recorded with an empty key.
+			if limiter.draining.Load() {
+				o.rejected(r, "")
+				if o.drainRetryAfter > 0 {
+					// Round up so sub-second durations never send "0"
+					retryAfter := max(int(math.Ceil(o.drainRetryAfter.Seconds())), 1)
+					w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+				}
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusServiceUnavailable)
+				w.Write([]byte(`{"error":"server is shutting down"}`))
+				return
+			}
 
-			// Get limiter for this key
-			l := limiter.getLimiter(key)
+			if o.skipper != nil && o.skipper(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
 
-			// Check if request is allowed
-			if !l.Allow() {
-				if o.errorHandler != nil {
-					o.errorHandler(w, r)
-					return
+			// Global mode draws every request from the shared bucket
+			var (
+				key string
+				l   *rate.Limiter
+			)
+			if o.global {
+				l = limiter.global
+			} else {
+				// Get key, and the limit if looked up together, for rate limiting
+				var (
+					limit  float64
+					burst  int
+					lookup = o.limitLookup != nil
+				)
+				if lookup {
+					key, limit, burst = o.limitLookup(r)
+				} else {
+					key = o.keyFunc(r)
+				}
+				if key == "" {
+					switch o.emptyKeyPolicy {
+					case EmptyKeyAllow:
+						next.ServeHTTP(w, r)
+						return
+					case EmptyKeyDeny:
+						o.reject(w, r, key)
+						return
+					}
+				}
+				if o.methodScoped {
+					key += "|" + r.Method
 				}
 
-				w.Header().Set("Content-Type", "application/json")
-				w.WriteHeader(http.StatusTooManyRequests)
-				w.Write([]byte(`{"error":"rate limit exceeded"}`))
+				// Get limiter for this key
+				if lookup {
+					l = limiter.getLimiterWithLimit(key, rate.Limit(limit), burst)
+				} else if o.limitFunc != nil {
+					limit, burst := o.limitFunc(r)
+					l = limiter.getLimiterWithLimit(key, rate.Limit(limit), burst)
+				} else {
+					l = limiter.getLimiter(key)
+				}
+			}
+
+			cost := 1
+			if o.costFunc != nil {
+				cost = o.costFunc(r)
+			}
+
+			// Check if request is allowed
+			if !allow(r, l, cost, o.maxWait) {
+				o.reject(w, r, key)
 				return
 			}
+			o.accept(r, key)
+
+			if o.annotateFunc != nil {
+				o.annotateFunc(w, l.Tokens())
+			}
 
 			next.ServeHTTP(w, r)
 		})
@@ -1,8 +1,12 @@
 package ratelimiter
 
 import (
+	"context"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"runtime"
+	"strings"
 	"testing"
 	"time"
 )
@@ -225,3 +229,881 @@ func TestRateLimiterXRealIP(t *testing.T) {
 		t.Errorf("Expected status 429, got %d", rr2.Code)
 	}
 }
+
+func TestRateLimiterWithLimitFunc(t *testing.T) {
+	middleware := New(
+		WithRate(1),
+		WithBurst(1),
+		WithLimitFunc(func(r *http.Request) (float64, int) {
+			if r.URL.Path == "/static" {
+				return 10, 3
+			}
+			return 1, 1
+		}),
+	)
+
+	handler := middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	do := func(path string) int {
+		req := httptest.NewRequest("GET", path, nil)
+		req.RemoteAddr = "192.168.1.1:1234"
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	// /search allows a single request
+	if code := do("/search"); code != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", code)
+	}
+	if code := do("/search"); code != http.StatusTooManyRequests {
+		t.Errorf("Expected status 429, got %d", code)
+	}
+
+	// /static uses its own limiter with a larger burst for the same client
+	for i := 0; i < 3; i++ {
+		if code := do("/static"); code != http.StatusOK {
+			t.Errorf("Request %d: Expected status 200, got %d", i+1, code)
+		}
+	}
+	if code := do("/static"); code != http.StatusTooManyRequests {
+		t.Errorf("Expected status 429, got %d", code)
+	}
+}
+
+func TestRateLimiterWithLimitLookup(t *testing.T) {
+	var keys []string
+	middleware := New(
+		WithRate(1),
+		WithBurst(2),
+		WithLimitLookup(func(r *http.Request) (string, float64, int) {
+			user := r.Header.Get("X-User-ID")
+			if r.Header.Get("X-Tier") == "premium" {
+				return user, 1, 5
+			}
+			return user, 1, 2
+		}),
+		WithObserver(func(allowed bool, key string, r *http.Request) {
+			keys = append(keys, key)
+		}),
+	)
+
+	handler := middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	allowed := func(user, tier string) int {
+		n := 0
+		for i := 0; i < 10; i++ {
+			req := httptest.NewRequest("GET", "/test", nil)
+			req.Header.Set("X-User-ID", user)
+			if tier != "" {
+				req.Header.Set("X-Tier", tier)
+			}
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+			if rr.Code == http.StatusOK {
+				n++
+			}
+		}
+		return n
+	}
+
+	if n := allowed("free-user", ""); n != 2 {
+		t.Errorf("Expected default burst of 2, got %d allowed", n)
+	}
+	if n := allowed("premium-user", "premium"); n != 5 {
+		t.Errorf("Expected premium burst of 5, got %d allowed", n)
+	}
+
+	// The looked-up key replaces the default IP key
+	if keys[0] != "free-user" || keys[len(keys)-1] != "premium-user" {
+		t.Errorf("Expected looked-up keys, got %v", keys)
+	}
+}
+
+func TestRateLimiterWithWait(t *testing.T) {
+	middleware := New(
+		WithRate(10),
+		WithBurst(1),
+		WithWait(500*time.Millisecond),
+	)
+
+	handler := middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	// Second request waits ~100ms for a token instead of being rejected
+	for i := 0; i < 2; i++ {
+		req := httptest.NewRequest("GET", "/test", nil)
+		req.RemoteAddr = "192.168.1.1:1234"
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		if rr.Code != http.StatusOK {
+			t.Errorf("Request %d: Expected status 200, got %d", i+1, rr.Code)
+		}
+	}
+}
+
+func TestRateLimiterWithWaitExceeded(t *testing.T) {
+	middleware := New(
+		WithRate(1),
+		WithBurst(1),
+		WithWait(100*time.Millisecond),
+	)
+
+	handler := middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req1 := httptest.NewRequest("GET", "/test", nil)
+	req1.RemoteAddr = "192.168.1.1:1234"
+	handler.ServeHTTP(httptest.NewRecorder(), req1)
+
+	// A token needs ~1s, which exceeds the max wait
+	start := time.Now()
+	req2 := httptest.NewRequest("GET", "/test", nil)
+	req2.RemoteAddr = "192.168.1.1:1234"
+	rr2 := httptest.NewRecorder()
+	handler.ServeHTTP(rr2, req2)
+
+	if rr2.Code != http.StatusTooManyRequests {
+		t.Errorf("Expected status 429, got %d", rr2.Code)
+	}
+	if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+		t.Errorf("Expected immediate rejection, waited %v", elapsed)
+	}
+}
+
+func TestRateLimiterWithContextStopsCleanup(t *testing.T) {
+	before := runtime.NumGoroutine()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	for i := 0; i < 10; i++ {
+		New(WithContext(ctx))
+	}
+
+	if n := runtime.NumGoroutine(); n < before+10 {
+		t.Fatalf("Expected cleanup goroutines to be running, got %d (before %d)", n, before)
+	}
+
+	cancel()
+
+	// Give the cleanup goroutines time to observe the cancellation
+	deadline := time.Now().Add(time.Second)
+	for runtime.NumGoroutine() > before && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if n := runtime.NumGoroutine(); n > before {
+		t.Errorf("Expected cleanup goroutines to exit, got %d (before %d)", n, before)
+	}
+}
+
+func TestRateLimiterMaxEntries(t *testing.T) {
+	limiter := newRateLimiter(1, 1)
+	limiter.maxEntries = 3
+
+	for i := 0; i < 10; i++ {
+		limiter.getLimiter(fmt.Sprintf("10.0.0.%d", i))
+
+		limiter.mu.RLock()
+		size := len(limiter.limiters)
+		limiter.mu.RUnlock()
+
+		if size > 3 {
+			t.Fatalf("Expected at most 3 entries, got %d", size)
+		}
+	}
+
+	if limiter.accessOrder.Len() != 3 {
+		t.Errorf("Expected access order to track 3 entries, got %d", limiter.accessOrder.Len())
+	}
+}
+
+func TestRateLimiterMaxEntriesEvictsLeastRecentlyUsed(t *testing.T) {
+	limiter := newRateLimiter(1, 1)
+	limiter.maxEntries = 2
+
+	a := limiter.getLimiter("a")
+	limiter.getLimiter("b")
+	// Touch "a" so "b" becomes the least recently used
+	limiter.getLimiter("a")
+	limiter.getLimiter("c")
+
+	if _, ok := limiter.limiters[limiterKey{key: "b", rate: 1, burst: 1}]; ok {
+		t.Error("Expected least recently used key to be evicted")
+	}
+	if limiter.getLimiter("a") != a {
+		t.Error("Expected recently used key to be kept")
+	}
+}
+
+func TestRateLimiterWithKeyHasher(t *testing.T) {
+	middleware := New(
+		WithRate(1),
+		WithBurst(1),
+		WithKeyHasher(FNVKeyHasher),
+		WithKeyFunc(func(r *http.Request) string {
+			return r.Header.Get("X-Fingerprint")
+		}),
+	)
+
+	handler := middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	do := func(fingerprint string) int {
+		req := httptest.NewRequest("GET", "/test", nil)
+		req.Header.Set("X-Fingerprint", fingerprint)
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	long1 := strings.Repeat("a", 1024)
+	long2 := strings.Repeat("a", 1023) + "b"
+
+	if code := do(long1); code != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", code)
+	}
+	if code := do(long1); code != http.StatusTooManyRequests {
+		t.Errorf("Expected status 429, got %d", code)
+	}
+	// A distinct key still gets its own bucket
+	if code := do(long2); code != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", code)
+	}
+}
+
+func TestRateLimiterKeyHasherStoresHashedKeys(t *testing.T) {
+	limiter := newRateLimiter(1, 1)
+	limiter.keyHasher = FNVKeyHasher
+
+	limiter.getLimiter(strings.Repeat("x", 4096))
+	limiter.getLimiter("short")
+
+	for key := range limiter.limiters {
+		if key.key != "" {
+			t.Errorf("Expected raw key not to be stored, got %d bytes", len(key.key))
+		}
+		if key.hash == 0 {
+			t.Error("Expected hashed key to be stored")
+		}
+	}
+}
+
+func TestRateLimiterWithCostFunc(t *testing.T) {
+	middleware := New(
+		WithRate(1),
+		WithBurst(3),
+		WithCostFunc(func(r *http.Request) int {
+			if r.URL.Path == "/export" {
+				return 3
+			}
+			return 1
+		}),
+	)
+
+	handler := middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	do := func(path string) int {
+		req := httptest.NewRequest("GET", path, nil)
+		req.RemoteAddr = "192.168.1.1:1234"
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	// A cost-3 request exhausts the burst
+	if code := do("/export"); code != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", code)
+	}
+	if code := do("/export"); code != http.StatusTooManyRequests {
+		t.Errorf("Expected status 429, got %d", code)
+	}
+}
+
+func TestRateLimiterCostExceedsBurst(t *testing.T) {
+	middleware := New(
+		WithRate(1),
+		WithBurst(2),
+		WithWait(time.Second),
+		WithCostFunc(func(r *http.Request) int { return 5 }),
+	)
+
+	handler := middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	req.RemoteAddr = "192.168.1.1:1234"
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusTooManyRequests {
+		t.Errorf("Expected status 429, got %d", rr.Code)
+	}
+}
+
+func TestRateLimiterWithSkipper(t *testing.T) {
+	var keyFuncCalls int
+
+	middleware := New(
+		WithRate(1),
+		WithBurst(1),
+		WithSkipper(func(r *http.Request) bool {
+			return r.URL.Path == "/health"
+		}),
+		WithKeyFunc(func(r *http.Request) string {
+			keyFuncCalls++
+			return r.RemoteAddr
+		}),
+	)
+
+	handler := middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	// Skipped path never exhausts the bucket
+	for i := 0; i < 5; i++ {
+		req := httptest.NewRequest("GET", "/health", nil)
+		req.RemoteAddr = "10.0.0.1:1234"
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		if rr.Code != http.StatusOK {
+			t.Errorf("Request %d: Expected status 200, got %d", i+1, rr.Code)
+		}
+	}
+
+	if keyFuncCalls != 0 {
+		t.Errorf("Expected limiter not to be consulted, key func called %d times", keyFuncCalls)
+	}
+
+	// Other paths are still limited
+	for i, expected := range []int{http.StatusOK, http.StatusTooManyRequests} {
+		req := httptest.NewRequest("GET", "/test", nil)
+		req.RemoteAddr = "10.0.0.1:1234"
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		if rr.Code != expected {
+			t.Errorf("Request %d: Expected status %d, got %d", i+1, expected, rr.Code)
+		}
+	}
+}
+
+func TestRateLimiterWithAnnotateFunc(t *testing.T) {
+	middleware := New(
+		WithRate(0.001),
+		WithBurst(4),
+		WithAnnotateFunc(func(w http.ResponseWriter, remaining float64) {
+			if remaining < 2 {
+				w.Header().Set("X-RateLimit-Warning", "quota nearly exhausted")
+			}
+		}),
+	)
+
+	handler := middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	// Remaining after each request: 3, 2, 1, 0
+	expected := []bool{false, false, true, true}
+	for i, warn := range expected {
+		req := httptest.NewRequest("GET", "/test", nil)
+		req.RemoteAddr = "192.168.1.1:1234"
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		if got := rr.Header().Get("X-RateLimit-Warning") != ""; got != warn {
+			t.Errorf("Request %d: Expected warning header=%v, got %v", i+1, warn, got)
+		}
+	}
+
+	// Rejected requests are not annotated
+	req := httptest.NewRequest("GET", "/test", nil)
+	req.RemoteAddr = "192.168.1.1:1234"
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusTooManyRequests {
+		t.Errorf("Expected status 429, got %d", rr.Code)
+	}
+	if rr.Header().Get("X-RateLimit-Warning") != "" {
+		t.Error("Expected rejected response not to be annotated")
+	}
+}
+
+func TestRateLimiterWithEmptyKeyPolicy(t *testing.T) {
+	tests := []struct {
+		name     string
+		policy   EmptyKeyPolicy
+		expected []int
+	}{
+		{"Shared", EmptyKeyShared, []int{http.StatusOK, http.StatusTooManyRequests, http.StatusTooManyRequests}},
+		{"Allow", EmptyKeyAllow, []int{http.StatusOK, http.StatusOK, http.StatusOK}},
+		{"Deny", EmptyKeyDeny, []int{http.StatusTooManyRequests, http.StatusTooManyRequests, http.StatusTooManyRequests}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			middleware := New(
+				WithRate(1),
+				WithBurst(1),
+				WithKeyFunc(func(r *http.Request) string {
+					return r.Header.Get("X-User-ID")
+				}),
+				WithEmptyKeyPolicy(tt.policy),
+			)
+
+			handler := middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			// Requests without X-User-ID have an empty key
+			for i, expected := range tt.expected {
+				rr := httptest.NewRecorder()
+				handler.ServeHTTP(rr, httptest.NewRequest("GET", "/test", nil))
+
+				if rr.Code != expected {
+					t.Errorf("Request %d: Expected status %d, got %d", i+1, expected, rr.Code)
+				}
+			}
+
+			// Non-empty keys are limited as usual
+			req := httptest.NewRequest("GET", "/test", nil)
+			req.Header.Set("X-User-ID", "alice")
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != http.StatusOK {
+				t.Errorf("Expected status 200 for non-empty key, got %d", rr.Code)
+			}
+		})
+	}
+}
+
+func TestRateLimiterWithMethodScoped(t *testing.T) {
+	middleware := New(
+		WithRate(1),
+		WithBurst(1),
+		WithMethodScoped(true),
+	)
+
+	handler := middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		method   string
+		expected int
+	}{
+		{"GET", http.StatusOK},
+		{"GET", http.StatusTooManyRequests},
+		{"POST", http.StatusOK}, // GET tokens exhausted, POST has its own bucket
+		{"POST", http.StatusTooManyRequests},
+	}
+
+	for i, tt := range tests {
+		req := httptest.NewRequest(tt.method, "/test", nil)
+		req.RemoteAddr = "192.168.1.1:12345"
+		rr := httptest.NewRecorder()
+
+		handler.ServeHTTP(rr, req)
+
+		if rr.Code != tt.expected {
+			t.Errorf("Request %d (%s): Expected status %d, got %d", i+1, tt.method, tt.expected, rr.Code)
+		}
+	}
+}
+
+func TestRateLimiterWithRejectResponse(t *testing.T) {
+	body := `{"code":"THROTTLED","retryable":true}`
+
+	tests := []struct {
+		name   string
+		opts   []Option
+		status int
+		body   string
+		ctype  string
+	}{
+		{"Custom status and body", nil, http.StatusServiceUnavailable, body, "application/problem+json"},
+		{"Error handler wins", []Option{WithErrorHandler(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusTeapot)
+		})}, http.StatusTeapot, "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := append([]Option{
+				WithRate(1),
+				WithBurst(1),
+				WithRejectStatus(http.StatusServiceUnavailable),
+				WithRejectBody("application/problem+json", body),
+			}, tt.opts...)
+
+			handler := New(opts...)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			var rr *httptest.ResponseRecorder
+			for i := 0; i < 2; i++ {
+				req := httptest.NewRequest("GET", "/test", nil)
+				req.RemoteAddr = "192.168.1.1:12345"
+				rr = httptest.NewRecorder()
+				handler.ServeHTTP(rr, req)
+			}
+
+			if rr.Code != tt.status {
+				t.Errorf("Expected status %d, got %d", tt.status, rr.Code)
+			}
+			if rr.Body.String() != tt.body {
+				t.Errorf("Expected body %q, got %q", tt.body, rr.Body.String())
+			}
+			if rr.Header().Get("Content-Type") != tt.ctype {
+				t.Errorf("Expected Content-Type %q, got %q", tt.ctype, rr.Header().Get("Content-Type"))
+			}
+		})
+	}
+}
+
+func TestRateLimiterStats(t *testing.T) {
+	var stats StatsHandle
+	middleware := New(
+		WithRate(1),
+		WithBurst(2),
+		WithStatsHandle(&stats),
+	)
+
+	handler := middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	// Unknown keys report a full bucket
+	s := stats.Stats("192.168.1.1")
+	if s.Keys != 0 || s.Tracked || s.Remaining != 2 {
+		t.Errorf("Expected 0 keys and a full untracked bucket, got %+v", s)
+	}
+
+	// Exhaust the bucket
+	for i := 0; i < 3; i++ {
+		req := httptest.NewRequest("GET", "/test", nil)
+		req.RemoteAddr = "192.168.1.1:12345"
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	s = stats.Stats("192.168.1.1")
+	if s.Keys != 1 {
+		t.Errorf("Expected 1 key, got %d", s.Keys)
+	}
+	if !s.Tracked {
+		t.Error("Expected key to be tracked")
+	}
+	if s.Remaining >= 1 {
+		t.Errorf("Expected no remaining tokens, got %f", s.Remaining)
+	}
+	if until := time.Until(s.Reset); until < time.Second || until > 2*time.Second {
+		t.Errorf("Expected reset in 1-2s, got %v", until)
+	}
+}
+
+func TestRateLimiterSetRate(t *testing.T) {
+	var handle StatsHandle
+	middleware := New(
+		WithRate(1000),
+		WithBurst(10),
+		WithStatsHandle(&handle),
+	)
+
+	handler := middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	send := func(addr string) int {
+		req := httptest.NewRequest("GET", "/test", nil)
+		req.RemoteAddr = addr
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	// Traffic well within the original limit
+	for i := 0; i < 5; i++ {
+		if code := send("192.168.1.1:12345"); code != http.StatusOK {
+			t.Fatalf("Request %d: expected status 200 before reload, got %d", i+1, code)
+		}
+	}
+
+	handle.SetRate(0.001, 2)
+
+	// The existing key is throttled at the new burst
+	rejected := 0
+	for i := 0; i < 5; i++ {
+		if send("192.168.1.1:12345") == http.StatusTooManyRequests {
+			rejected++
+		}
+	}
+	if rejected < 3 {
+		t.Errorf("Expected existing key to be throttled after reload, got %d rejections", rejected)
+	}
+	if s := handle.Stats("192.168.1.1"); s.Keys != 1 {
+		t.Errorf("Expected the existing key to be reused, got %d keys", s.Keys)
+	}
+
+	// New keys use the new limit too
+	for i := 0; i < 2; i++ {
+		if code := send("192.168.1.2:12345"); code != http.StatusOK {
+			t.Errorf("Request %d: expected status 200 for new key, got %d", i+1, code)
+		}
+	}
+	if code := send("192.168.1.2:12345"); code != http.StatusTooManyRequests {
+		t.Errorf("Expected new key limited at the new burst, got %d", code)
+	}
+}
+
+func TestRateLimiterWithGlobal(t *testing.T) {
+	var stats StatsHandle
+	middleware := New(
+		WithRate(1),
+		WithBurst(3),
+		WithGlobal(true),
+		WithStatsHandle(&stats),
+	)
+
+	handler := middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	// Every client draws from the same bucket
+	allowed := 0
+	for i := 0; i < 10; i++ {
+		req := httptest.NewRequest("GET", "/test", nil)
+		req.RemoteAddr = fmt.Sprintf("192.168.1.%d:12345", i+1)
+		rr := httptest.NewRecorder()
+
+		handler.ServeHTTP(rr, req)
+
+		switch rr.Code {
+		case http.StatusOK:
+			allowed++
+		case http.StatusTooManyRequests:
+			if rr.Body.String() != `{"error":"rate limit exceeded"}` {
+				t.Errorf("Expected default rejection body, got %q", rr.Body.String())
+			}
+		default:
+			t.Errorf("Unexpected status %d", rr.Code)
+		}
+	}
+
+	if allowed != 3 {
+		t.Errorf("Expected 3 requests allowed across all clients, got %d", allowed)
+	}
+
+	// No per-key state is kept
+	if s := stats.Stats("192.168.1.1"); s.Keys != 0 || s.Remaining >= 1 {
+		t.Errorf("Expected no tracked keys and an exhausted bucket, got %+v", s)
+	}
+}
+
+func TestRateLimiterDrain(t *testing.T) {
+	var handle StatsHandle
+	middleware := New(
+		WithRate(100),
+		WithBurst(100),
+		WithSkipper(func(r *http.Request) bool { return r.URL.Path == "/healthz" }),
+		WithStatsHandle(&handle),
+	)
+
+	handler := middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	send := func(path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", path, nil)
+		req.RemoteAddr = "192.168.1.1:12345"
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	if rr := send("/test"); rr.Code != http.StatusOK {
+		t.Fatalf("Expected status 200 before draining, got %d", rr.Code)
+	}
+
+	handle.Drain()
+
+	// Tokens are available, but nothing new is admitted
+	for _, path := range []string{"/test", "/healthz"} {
+		rr := send(path)
+		if rr.Code != http.StatusServiceUnavailable {
+			t.Errorf("%s: expected status 503 while draining, got %d", path, rr.Code)
+		}
+		if rr.Header().Get("Retry-After") != "5" {
+			t.Errorf("%s: expected Retry-After 5, got %q", path, rr.Header().Get("Retry-After"))
+		}
+	}
+}
+
+// countingMetrics counts rate limiter outcomes
+type countingMetrics struct {
+	allowed, rejected int
+}
+
+func (m *countingMetrics) IncAllowed()  { m.allowed++ }
+func (m *countingMetrics) IncRejected() { m.rejected++ }
+func (m *countingMetrics) SetKeys(int)  {}
+
+func TestRateLimiterDrainRecordsRejections(t *testing.T) {
+	var (
+		handle   StatsHandle
+		metrics  countingMetrics
+		observed []bool
+	)
+	middleware := New(
+		WithDrainRetryAfter(200*time.Millisecond),
+		WithMetrics(&metrics),
+		WithObserver(func(ok bool, key string, r *http.Request) {
+			if key != "" {
+				t.Errorf("Expected empty key while draining, got %q", key)
+			}
+			observed = append(observed, ok)
+		}),
+		WithStatsHandle(&handle),
+	)
+
+	handler := middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	handle.Drain()
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected status 503 while draining, got %d", rr.Code)
+	}
+	if rr.Header().Get("Retry-After") != "1" {
+		t.Errorf("Expected sub-second Retry-After rounded up to 1, got %q", rr.Header().Get("Retry-After"))
+	}
+	if metrics.rejected != 1 || metrics.allowed != 0 {
+		t.Errorf("Expected 1 rejection recorded, got %+v", metrics)
+	}
+	if len(observed) != 1 || observed[0] {
+		t.Errorf("Expected observer to see 1 rejection, got %v", observed)
+	}
+}
+
+func TestRateLimiterWithObserver(t *testing.T) {
+	var allowed, rejected int
+	var keys []string
+	middleware := New(
+		WithRate(1),
+		WithBurst(2),
+		WithObserver(func(ok bool, key string, r *http.Request) {
+			if ok {
+				allowed++
+			} else {
+				rejected++
+			}
+			keys = append(keys, key)
+		}),
+	)
+
+	handler := middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	for i := 0; i < 5; i++ {
+		req := httptest.NewRequest("GET", "/test", nil)
+		req.RemoteAddr = "192.168.1.1:12345"
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	if allowed != 2 {
+		t.Errorf("Expected 2 allowed events, got %d", allowed)
+	}
+	if rejected != 3 {
+		t.Errorf("Expected 3 rejected events, got %d", rejected)
+	}
+	for _, key := range keys {
+		if key != "192.168.1.1" {
+			t.Errorf("Expected key 192.168.1.1, got %q", key)
+		}
+	}
+}
+
+func TestRateLimiterIPPrefixLen(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     []Option
+		first    string
+		second   string
+		shared   bool
+		firstKey string
+	}{
+		{"Same IPv6 /64", nil, "[2001:db8:1:2::1]:1234", "[2001:db8:1:2:ffff::9]:1234", true, "2001:db8:1:2::/64"},
+		{"Different IPv6 /64", nil, "[2001:db8:1:2::1]:1234", "[2001:db8:1:3::1]:1234", false, "2001:db8:1:2::/64"},
+		{"Custom IPv6 prefix", []Option{WithIPv6PrefixLen(48)}, "[2001:db8:1:2::1]:1234", "[2001:db8:1:3::1]:1234", true, "2001:db8:1::/48"},
+		{"Whole IPv6 address", []Option{WithIPv6PrefixLen(128)}, "[2001:db8:1:2::1]:1234", "[2001:db8:1:2::2]:1234", false, "2001:db8:1:2::1"},
+		{"Whole IPv4 address", nil, "192.168.1.1:1234", "192.168.1.2:1234", false, "192.168.1.1"},
+		{"Custom IPv4 prefix", []Option{WithIPv4PrefixLen(24)}, "192.168.1.1:1234", "192.168.1.2:1234", true, "192.168.1.0/24"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var keys []string
+			opts := append([]Option{
+				WithRate(1),
+				WithBurst(1),
+				WithObserver(func(ok bool, key string, r *http.Request) {
+					keys = append(keys, key)
+				}),
+			}, tt.opts...)
+			handler := New(opts...)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			codes := make([]int, 0, 2)
+			for _, addr := range []string{tt.first, tt.second} {
+				req := httptest.NewRequest("GET", "/test", nil)
+				req.RemoteAddr = addr
+				rr := httptest.NewRecorder()
+				handler.ServeHTTP(rr, req)
+				codes = append(codes, rr.Code)
+			}
+
+			expected := http.StatusOK
+			if tt.shared {
+				expected = http.StatusTooManyRequests
+			}
+			if codes[1] != expected {
+				t.Errorf("Expected second request status %d, got %d", expected, codes[1])
+			}
+			if keys[0] != tt.firstKey {
+				t.Errorf("Expected key %q, got %q", tt.firstKey, keys[0])
+			}
+		})
+	}
+}
+
+func TestRateLimiterInvalidIPPrefixLenPanic(t *testing.T) {
+	for _, opt := range []Option{WithIPv4PrefixLen(33), WithIPv6PrefixLen(-1)} {
+		func() {
+			defer func() {
+				if r := recover(); r == nil {
+					t.Error("Expected panic for invalid prefix length")
+				}
+			}()
+			New(opt)
+		}()
+	}
+}
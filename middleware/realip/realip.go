@@ -0,0 +1,127 @@
+package realip
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// Option is realip option.
+type Option func(*options)
+
+// options defines the configuration for the realip middleware
+type options struct {
+	// TrustedProxies are the CIDRs of proxies whose forwarding headers are
+	// trusted. Without any, the headers are ignored.
+	trustedProxies []netip.Prefix
+}
+
+// WithTrustedProxies sets the CIDRs (or single IPs) of trusted proxies.
+// It panics on an invalid entry.
+func WithTrustedProxies(proxies []string) Option {
+	return func(o *options) {
+		for _, proxy := range proxies {
+			prefix, err := netip.ParsePrefix(proxy)
+			if err != nil {
+				addr, addrErr := netip.ParseAddr(proxy)
+				if addrErr != nil {
+					panic("realip: invalid trusted proxy " + proxy)
+				}
+				prefix = netip.PrefixFrom(addr, addr.BitLen())
+			}
+			o.trustedProxies = append(o.trustedProxies, prefix.Masked())
+		}
+	}
+}
+
+// trusted reports whether addr belongs to a trusted proxy
+func (o *options) trusted(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	for _, prefix := range o.trustedProxies {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// clientIP resolves the client IP for a request from the peer addr
+func (o *options) clientIP(r *http.Request, peer netip.Addr) netip.Addr {
+	if !o.trusted(peer) {
+		return peer
+	}
+
+	// Walk X-Forwarded-For from the nearest hop, skipping trusted proxies.
+	// Entries left of the first untrusted hop may be spoofed.
+	var hops []string
+	for _, value := range r.Header.Values("X-Forwarded-For") {
+		hops = append(hops, strings.Split(value, ",")...)
+	}
+	client := peer
+	for i := len(hops) - 1; i >= 0; i-- {
+		addr, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+		if err != nil {
+			break
+		}
+		client = addr.Unmap()
+		if !o.trusted(client) {
+			return client
+		}
+	}
+	if len(hops) > 0 {
+		return client
+	}
+
+	if addr, err := netip.ParseAddr(strings.TrimSpace(r.Header.Get("X-Real-IP"))); err == nil {
+		return addr.Unmap()
+	}
+
+	return peer
+}
+
+// contextKey is the type used for context keys
+type contextKey struct{}
+
+// FromContext returns the client IP resolved by the middleware
+func FromContext(ctx context.Context) (string, bool) {
+	ip, ok := ctx.Value(contextKey{}).(string)
+	return ip, ok
+}
+
+// New returns a middleware that resolves the client IP from forwarding
+// headers set by trusted proxies. It rewrites r.RemoteAddr to the client IP,
+// keeping the peer port, and stores the IP in the request context.
+func New(opts ...Option) func(http.Handler) http.Handler {
+	o := &options{}
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			host, port, err := net.SplitHostPort(r.RemoteAddr)
+			if err != nil {
+				host = r.RemoteAddr
+			}
+			peer, err := netip.ParseAddr(host)
+			if err != nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			ip := o.clientIP(r, peer.Unmap()).String()
+
+			r = r.WithContext(context.WithValue(r.Context(), contextKey{}, ip))
+			if port != "" {
+				r.RemoteAddr = net.JoinHostPort(ip, port)
+			} else {
+				r.RemoteAddr = ip
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,118 @@
+package realip
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRealIP(t *testing.T) {
+	middleware := New(WithTrustedProxies([]string{"10.0.0.0/8", "192.168.1.1"}))
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		xff        string
+		xRealIP    string
+		expected   string
+	}{
+		{
+			name:       "Spoofed XFF from untrusted peer is ignored",
+			remoteAddr: "203.0.113.5:1234",
+			xff:        "1.2.3.4",
+			expected:   "203.0.113.5",
+		},
+		{
+			name:       "Trusted proxy is honored",
+			remoteAddr: "10.0.0.1:1234",
+			xff:        "198.51.100.7",
+			expected:   "198.51.100.7",
+		},
+		{
+			name:       "Trusted single IP proxy",
+			remoteAddr: "192.168.1.1:1234",
+			xff:        "198.51.100.7",
+			expected:   "198.51.100.7",
+		},
+		{
+			name:       "Chain of trusted proxies",
+			remoteAddr: "10.0.0.1:1234",
+			xff:        "198.51.100.7, 10.0.0.2, 10.0.0.3",
+			expected:   "198.51.100.7",
+		},
+		{
+			name:       "Client-supplied entries before the first untrusted hop are ignored",
+			remoteAddr: "10.0.0.1:1234",
+			xff:        "1.2.3.4, 198.51.100.7",
+			expected:   "198.51.100.7",
+		},
+		{
+			name:       "X-Real-IP from trusted proxy",
+			remoteAddr: "10.0.0.1:1234",
+			xRealIP:    "198.51.100.8",
+			expected:   "198.51.100.8",
+		},
+		{
+			name:       "Trusted proxy without headers",
+			remoteAddr: "10.0.0.1:1234",
+			expected:   "10.0.0.1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var remoteAddr, ctxIP string
+
+			handler := middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				remoteAddr = r.RemoteAddr
+				ctxIP, _ = FromContext(r.Context())
+			}))
+
+			req := httptest.NewRequest("GET", "/test", nil)
+			req.RemoteAddr = tt.remoteAddr
+			if tt.xff != "" {
+				req.Header.Set("X-Forwarded-For", tt.xff)
+			}
+			if tt.xRealIP != "" {
+				req.Header.Set("X-Real-IP", tt.xRealIP)
+			}
+
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+
+			if ctxIP != tt.expected {
+				t.Errorf("Expected context IP %s, got %s", tt.expected, ctxIP)
+			}
+			if remoteAddr != tt.expected+":1234" {
+				t.Errorf("Expected RemoteAddr %s:1234, got %s", tt.expected, remoteAddr)
+			}
+		})
+	}
+}
+
+func TestRealIPNoTrustedProxies(t *testing.T) {
+	var ip string
+
+	handler := New()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ip, _ = FromContext(r.Context())
+	}))
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	req.RemoteAddr = "10.0.0.1:1234"
+	req.Header.Set("X-Forwarded-For", "1.2.3.4")
+
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	if ip != "10.0.0.1" {
+		t.Errorf("Expected headers to be ignored without trusted proxies, got %s", ip)
+	}
+}
+
+func TestRealIPInvalidProxyPanic(t *testing.T) {
+	defer func() {
+		if r := recover(); r == nil {
+			t.Error("Expected panic for invalid trusted proxy")
+		}
+	}()
+
+	New(WithTrustedProxies([]string{"not-an-ip"}))
+}
@@ -0,0 +1,105 @@
+package recovery
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"runtime"
+)
+
+// Option is recovery option.
+type Option func(*options)
+
+// options defines the configuration for the recovery middleware
+type options struct {
+	// Logger is called with the recovered value and the goroutine stack
+	// Default: logs an error record via slog.Default()
+	logger func(r *http.Request, recovered any, stack []byte)
+
+	// StackSize is the maximum number of stack trace bytes captured
+	// Default: 4KB
+	stackSize int
+
+	// PanicHandler writes the response after a panic
+	// Default: 500 JSON error
+	panicHandler func(w http.ResponseWriter, r *http.Request, recovered any)
+}
+
+// WithLogger sets the hook called with the recovered value and stack trace
+func WithLogger(f func(r *http.Request, recovered any, stack []byte)) Option {
+	return func(o *options) {
+		o.logger = f
+	}
+}
+
+// WithStackSize sets the maximum number of stack trace bytes captured
+func WithStackSize(size int) Option {
+	return func(o *options) {
+		o.stackSize = size
+	}
+}
+
+// WithPanicHandler sets the handler writing the response after a panic
+func WithPanicHandler(f func(w http.ResponseWriter, r *http.Request, recovered any)) Option {
+	return func(o *options) {
+		o.panicHandler = f
+	}
+}
+
+// defaultLogger logs the panic via slog.Default()
+func defaultLogger(r *http.Request, recovered any, stack []byte) {
+	slog.Default().ErrorContext(r.Context(), "panic recovered",
+		slog.String("method", r.Method),
+		slog.String("path", r.URL.Path),
+		slog.String("panic", fmt.Sprint(recovered)),
+		slog.String("stack", string(stack)),
+	)
+}
+
+// defaultPanicHandler writes a 500 JSON error response
+func defaultPanicHandler(w http.ResponseWriter, r *http.Request, recovered any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusInternalServerError)
+	w.Write([]byte(`{"error":"internal server error"}`))
+}
+
+// New returns a recovery middleware that turns handler panics into error responses
+func New(opts ...Option) func(http.Handler) http.Handler {
+	o := &options{
+		logger:       defaultLogger,
+		stackSize:    4 << 10, // 4KB
+		panicHandler: defaultPanicHandler,
+	}
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	if o.stackSize <= 0 {
+		panic("recovery stack size must be greater than 0")
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer func() {
+				recovered := recover()
+				if recovered == nil {
+					return
+				}
+
+				// net/http uses ErrAbortHandler to abort a response silently
+				if recovered == http.ErrAbortHandler {
+					panic(recovered)
+				}
+
+				stack := make([]byte, o.stackSize)
+				stack = stack[:runtime.Stack(stack, false)]
+				o.logger(r, recovered, stack)
+
+				o.panicHandler(w, r, recovered)
+			}()
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
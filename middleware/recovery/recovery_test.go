@@ -0,0 +1,132 @@
+package recovery
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRecovery(t *testing.T) {
+	var logged any
+	var stackTrace []byte
+
+	middleware := New(WithLogger(func(r *http.Request, recovered any, stack []byte) {
+		logged = recovered
+		stackTrace = stack
+	}))
+
+	handler := middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	}))
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusInternalServerError {
+		t.Errorf("Expected status 500, got %d", rr.Code)
+	}
+
+	if rr.Header().Get("Content-Type") != "application/json" {
+		t.Errorf("Expected Content-Type application/json, got %s", rr.Header().Get("Content-Type"))
+	}
+
+	expected := `{"error":"internal server error"}`
+	if rr.Body.String() != expected {
+		t.Errorf("Expected body %s, got %s", expected, rr.Body.String())
+	}
+
+	if logged != "boom" {
+		t.Errorf("Expected logger to receive 'boom', got %v", logged)
+	}
+
+	if !strings.Contains(string(stackTrace), "goroutine") {
+		t.Errorf("Expected stack trace, got %q", stackTrace)
+	}
+}
+
+func TestRecoveryWithPanicHandler(t *testing.T) {
+	var received any
+
+	middleware := New(
+		WithLogger(func(r *http.Request, recovered any, stack []byte) {}),
+		WithPanicHandler(func(w http.ResponseWriter, r *http.Request, recovered any) {
+			received = recovered
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}),
+	)
+
+	handler := middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic(42)
+	}))
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("GET", "/test", nil))
+
+	if received != 42 {
+		t.Errorf("Expected panic handler to receive 42, got %v", received)
+	}
+
+	if rr.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected status 503, got %d", rr.Code)
+	}
+}
+
+func TestRecoveryWithStackSize(t *testing.T) {
+	var stackTrace []byte
+
+	middleware := New(
+		WithStackSize(64),
+		WithLogger(func(r *http.Request, recovered any, stack []byte) {
+			stackTrace = stack
+		}),
+	)
+
+	handler := middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	}))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/test", nil))
+
+	if len(stackTrace) == 0 || len(stackTrace) > 64 {
+		t.Errorf("Expected stack trace of at most 64 bytes, got %d", len(stackTrace))
+	}
+}
+
+func TestRecoveryErrAbortHandler(t *testing.T) {
+	called := false
+
+	middleware := New(WithLogger(func(r *http.Request, recovered any, stack []byte) {
+		called = true
+	}))
+
+	handler := middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic(http.ErrAbortHandler)
+	}))
+
+	defer func() {
+		if r := recover(); r != http.ErrAbortHandler {
+			t.Errorf("Expected ErrAbortHandler to be re-panicked, got %v", r)
+		}
+		if called {
+			t.Error("Expected logger not to be called for ErrAbortHandler")
+		}
+	}()
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/test", nil))
+}
+
+func TestRecoveryNoPanic(t *testing.T) {
+	handler := New()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("GET", "/test", nil))
+
+	if rr.Code != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", rr.Code)
+	}
+}
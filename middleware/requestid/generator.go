@@ -0,0 +1,102 @@
+package requestid
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"sync"
+	"time"
+)
+
+// nanoIDAlphabet is the URL-safe alphabet used by NanoID
+const nanoIDAlphabet = "_-0123456789abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ"
+
+// crockfordAlphabet is the Crockford base32 alphabet used by ULID
+const crockfordAlphabet = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// WithNanoID generates request IDs with NanoID, see NanoID
+func WithNanoID(length int) Option {
+	return WithGenerator(NanoID(length))
+}
+
+// WithULID generates request IDs with ULID, see ULID
+func WithULID() Option {
+	return WithGenerator(ULID())
+}
+
+// NanoID returns a generator of random URL-safe IDs of the given length.
+// Each character carries 6 bits of entropy: the default length of 21 gives
+// 126 bits, so about 2.6e18 IDs are needed for a 50% chance of one
+// collision, comparable to UUID v4. Shorter IDs collide sooner, e.g. length
+// 10 reaches a 1% chance after about 150 million IDs.
+// Lengths below 1 use the default of 21. Safe for concurrent use.
+func NanoID(length int) func() string {
+	if length < 1 {
+		length = 21
+	}
+
+	return func() string {
+		b := make([]byte, length)
+		rand.Read(b)
+		for i := range b {
+			// 64 characters, so masking keeps the distribution uniform
+			b[i] = nanoIDAlphabet[b[i]&63]
+		}
+		return string(b)
+	}
+}
+
+// ULID returns a generator of 26 character, lexicographically sortable IDs
+// made of a 48-bit millisecond timestamp and 80 random bits. IDs from the
+// same generator within one millisecond increment the random part, so they
+// stay ordered and never collide; across generators, a collision needs two
+// IDs in the same millisecond sharing 80 random bits (about 1.1e12 IDs per
+// millisecond for a 50% chance). Safe for concurrent use.
+func ULID() func() string {
+	var (
+		mu       sync.Mutex
+		lastMS   uint64
+		lastHigh uint16 // top 16 random bits
+		lastLow  uint64 // bottom 64 random bits
+	)
+
+	return func() string {
+		mu.Lock()
+		ms := uint64(time.Now().UnixMilli())
+		if ms <= lastMS {
+			// Same millisecond (or clock going backwards): increment
+			ms = lastMS
+			lastLow++
+			if lastLow == 0 {
+				lastHigh++
+			}
+		} else {
+			var r [10]byte
+			rand.Read(r[:])
+			lastMS = ms
+			lastHigh = binary.BigEndian.Uint16(r[:2])
+			lastLow = binary.BigEndian.Uint64(r[2:])
+		}
+		high, low := lastHigh, lastLow
+		mu.Unlock()
+
+		var b [16]byte
+		binary.BigEndian.PutUint64(b[:8], ms<<16|uint64(high))
+		binary.BigEndian.PutUint64(b[8:], low)
+		return encodeCrockford(b)
+	}
+}
+
+// encodeCrockford encodes 128 bits as 26 Crockford base32 characters
+func encodeCrockford(b [16]byte) string {
+	hi := binary.BigEndian.Uint64(b[:8])
+	lo := binary.BigEndian.Uint64(b[8:])
+
+	// 26 characters hold 130 bits; the first character carries the top 3 bits
+	var out [26]byte
+	for i := 25; i >= 0; i-- {
+		out[i] = crockfordAlphabet[lo&31]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+	return string(out[:])
+}
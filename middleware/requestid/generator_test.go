@@ -0,0 +1,87 @@
+package requestid
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"sync"
+	"testing"
+)
+
+func TestGeneratorsUnique(t *testing.T) {
+	generators := map[string]func() string{
+		"NanoID": NanoID(21),
+		"ULID":   ULID(),
+	}
+
+	for name, generate := range generators {
+		t.Run(name, func(t *testing.T) {
+			const n = 100000
+			const workers = 4
+
+			ids := make(chan string, n)
+			var wg sync.WaitGroup
+			for w := 0; w < workers; w++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for i := 0; i < n/workers; i++ {
+						ids <- generate()
+					}
+				}()
+			}
+			wg.Wait()
+			close(ids)
+
+			seen := make(map[string]bool, n)
+			for id := range ids {
+				if seen[id] {
+					t.Fatalf("Duplicate ID generated: %s", id)
+				}
+				seen[id] = true
+			}
+
+			if len(seen) != n {
+				t.Errorf("Expected %d IDs, got %d", n, len(seen))
+			}
+		})
+	}
+}
+
+func TestNanoIDLength(t *testing.T) {
+	if id := NanoID(10)(); len(id) != 10 {
+		t.Errorf("Expected length 10, got %d (%s)", len(id), id)
+	}
+	if id := NanoID(0)(); len(id) != 21 {
+		t.Errorf("Expected default length 21, got %d (%s)", len(id), id)
+	}
+}
+
+func TestULIDSortable(t *testing.T) {
+	generate := ULID()
+
+	ids := make([]string, 1000)
+	for i := range ids {
+		ids[i] = generate()
+		if len(ids[i]) != 26 {
+			t.Fatalf("Expected length 26, got %d (%s)", len(ids[i]), ids[i])
+		}
+	}
+
+	if !sort.StringsAreSorted(ids) {
+		t.Error("Expected ULIDs to be generated in sorted order")
+	}
+}
+
+func TestRequestIDWithNanoID(t *testing.T) {
+	handler := New(WithNanoID(12))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("GET", "/test", nil))
+
+	if id := rr.Header().Get("X-Request-ID"); len(id) != 12 {
+		t.Errorf("Expected 12 character request ID, got %q", id)
+	}
+}
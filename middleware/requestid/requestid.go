@@ -23,6 +23,20 @@ type options struct {
 	// ContextKey is the key used to store request ID in context
 	// Default: requestID
 	contextKey string
+
+	// Validator reports whether an incoming request ID can be trusted.
+	// Invalid IDs are replaced with a generated one
+	// Default: DefaultValidator. nil trusts every incoming ID
+	validator func(string) bool
+
+	// TraceParent uses the trace-id of the W3C `traceparent` header as the
+	// request ID, generating a new traceparent when it is absent or malformed
+	// Default: false
+	traceParent bool
+
+	// Prefix is prepended to generated IDs, not to reused incoming ones
+	// Default: ""
+	prefix string
 }
 
 // WithGenerator sets the ID generator function
@@ -46,6 +60,48 @@ func WithRequestIDContextKey(key string) Option {
 	}
 }
 
+// WithValidator sets the incoming request ID validator. Passing nil
+// disables validation and trusts any incoming ID.
+func WithValidator(f func(string) bool) Option {
+	return func(o *options) {
+		o.validator = f
+	}
+}
+
+// WithTraceParent enables W3C Trace Context correlation. The trace-id of the
+// incoming `traceparent` header becomes the request ID, and the traceparent
+// is written to the response and stored in context.
+func WithTraceParent(enabled bool) Option {
+	return func(o *options) {
+		o.traceParent = enabled
+	}
+}
+
+// WithPrefix sets a prefix prepended to generated request IDs, e.g. a short
+// service code. Incoming IDs and trace-ids are used unchanged.
+func WithPrefix(prefix string) Option {
+	return func(o *options) {
+		o.prefix = prefix
+	}
+}
+
+// maxRequestIDLength is the maximum length accepted by DefaultValidator
+const maxRequestIDLength = 128
+
+// DefaultValidator accepts IDs of at most 128 printable, non-whitespace
+// ASCII characters, protecting against header injection and log forging
+func DefaultValidator(id string) bool {
+	if len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
 // RequestID returns a RequestID middleware with optional configuration
 func New(opts ...Option) func(http.Handler) http.Handler {
 	o := &options{
@@ -54,6 +110,7 @@ func New(opts ...Option) func(http.Handler) http.Handler {
 		},
 		requestIDHeader: "X-Request-ID",
 		contextKey:      "requestID",
+		validator:       DefaultValidator,
 	}
 
 	for _, opt := range opts {
@@ -62,17 +119,37 @@ func New(opts ...Option) func(http.Handler) http.Handler {
 
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			// Check if request ID already exists
-			requestID := r.Header.Get(o.requestIDHeader)
-			if requestID == "" {
-				requestID = o.generator()
+			ctx := r.Context()
+
+			var requestID string
+			if o.traceParent {
+				// Use the trace-id so IDs line up with spans
+				tp := r.Header.Get(traceParentHeader)
+				traceID, ok := parseTraceParent(tp)
+				if !ok {
+					tp, traceID = newTraceParent()
+				}
+				requestID = traceID
+
+				w.Header().Set(traceParentHeader, tp)
+				ctx = context.WithValue(ctx, traceParentKey{}, tp)
+			} else {
+				// Check if request ID already exists
+				requestID = r.Header.Get(o.requestIDHeader)
+				if requestID != "" && o.validator != nil && !o.validator(requestID) {
+					requestID = ""
+				}
+				if requestID == "" {
+					requestID = o.prefix + o.generator()
+				}
 			}
 
 			// Set request ID in response header
 			w.Header().Set(o.requestIDHeader, requestID)
 
 			// Store request ID in context
-			ctx := context.WithValue(r.Context(), contextKey(o.contextKey), requestID)
+			ctx = context.WithValue(ctx, contextKey(o.contextKey), requestID)
+			ctx = context.WithValue(ctx, requestIDKey{}, requestID)
 			r = r.WithContext(ctx)
 
 			next.ServeHTTP(w, r)
@@ -82,3 +159,26 @@ func New(opts ...Option) func(http.Handler) http.Handler {
 
 // contextKey is the type used for context keys
 type contextKey string
+
+// requestIDKey stores the request ID independently of the configured context key
+type requestIDKey struct{}
+
+// FromContext returns the request ID stored by the middleware, whatever
+// context key it was configured with. Middleware running inside requestid,
+// such as a recovery handler, can use it to log the ID.
+func FromContext(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(requestIDKey{}).(string)
+	return id, ok
+}
+
+// GetRequestID extracts the request ID from context
+func GetRequestID(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(contextKey("requestID")).(string)
+	return id, ok
+}
+
+// GetRequestIDWithKey extracts the request ID from context with custom key
+func GetRequestIDWithKey(ctx context.Context, key string) (string, bool) {
+	id, ok := ctx.Value(contextKey(key)).(string)
+	return id, ok
+}
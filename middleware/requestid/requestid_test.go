@@ -3,6 +3,7 @@ package requestid
 import (
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 )
 
@@ -163,3 +164,191 @@ func TestRequestIDMultipleRequests(t *testing.T) {
 		t.Errorf("Expected 10 unique IDs, got %d", len(ids))
 	}
 }
+
+func TestGetRequestID(t *testing.T) {
+	var id, customID string
+	var ok, customOK bool
+
+	handler := New()(New(WithRequestIDContextKey("rid"))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, ok = GetRequestID(r.Context())
+		customID, customOK = GetRequestIDWithKey(r.Context(), "rid")
+	})))
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	req.Header.Set("X-Request-ID", "abc-123")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	if !ok || id != "abc-123" {
+		t.Errorf("Expected request ID 'abc-123', got %q (ok=%v)", id, ok)
+	}
+	if !customOK || customID != "abc-123" {
+		t.Errorf("Expected request ID 'abc-123' with custom key, got %q (ok=%v)", customID, customOK)
+	}
+}
+
+func TestFromContext(t *testing.T) {
+	for _, opts := range [][]Option{nil, {WithRequestIDContextKey("rid")}} {
+		var id string
+		var ok bool
+
+		handler := New(opts...)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id, ok = FromContext(r.Context())
+		}))
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest("GET", "/test", nil))
+
+		if !ok || id == "" {
+			t.Fatal("Expected request ID in context")
+		}
+		if id != rr.Header().Get("X-Request-ID") {
+			t.Errorf("Expected FromContext to return %q, got %q", rr.Header().Get("X-Request-ID"), id)
+		}
+	}
+}
+
+func TestRequestIDRejectsInvalidIncoming(t *testing.T) {
+	tests := []struct {
+		name string
+		id   string
+	}{
+		{name: "Too long", id: strings.Repeat("a", 129)},
+		{name: "Contains space", id: "abc 123"},
+		{name: "Contains control character", id: "abc\x01123"},
+		{name: "Contains non-ASCII", id: "abcé"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := New(WithGenerator(func() string { return "generated" }))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			req := httptest.NewRequest("GET", "/test", nil)
+			req.Header.Set("X-Request-ID", tt.id)
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			if rr.Header().Get("X-Request-ID") != "generated" {
+				t.Errorf("Expected invalid ID to be replaced, got %q", rr.Header().Get("X-Request-ID"))
+			}
+		})
+	}
+}
+
+func TestRequestIDValidatorDisabled(t *testing.T) {
+	handler := New(WithValidator(nil))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	id := strings.Repeat("a", 200)
+	req := httptest.NewRequest("GET", "/test", nil)
+	req.Header.Set("X-Request-ID", id)
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if rr.Header().Get("X-Request-ID") != id {
+		t.Error("Expected incoming ID to be trusted when validation is disabled")
+	}
+}
+
+func TestRequestIDCustomValidator(t *testing.T) {
+	handler := New(
+		WithGenerator(func() string { return "generated" }),
+		WithValidator(func(id string) bool { return strings.HasPrefix(id, "req-") }),
+	)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	for id, expected := range map[string]string{"req-1": "req-1", "other": "generated"} {
+		req := httptest.NewRequest("GET", "/test", nil)
+		req.Header.Set("X-Request-ID", id)
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		if rr.Header().Get("X-Request-ID") != expected {
+			t.Errorf("Incoming %q: Expected %q, got %q", id, expected, rr.Header().Get("X-Request-ID"))
+		}
+	}
+}
+
+func TestRequestIDWithPrefix(t *testing.T) {
+	middleware := New(WithPrefix("api-"))
+
+	var capturedID string
+	handler := middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		capturedID, _ = FromContext(r.Context())
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	// Generated IDs carry the prefix
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("GET", "/test", nil))
+
+	if !strings.HasPrefix(rr.Header().Get("X-Request-ID"), "api-") {
+		t.Errorf("Expected generated ID to start with 'api-', got %q", rr.Header().Get("X-Request-ID"))
+	}
+	if capturedID != rr.Header().Get("X-Request-ID") {
+		t.Errorf("Expected context ID %q to match header", capturedID)
+	}
+
+	// Reused incoming IDs are left unchanged
+	req := httptest.NewRequest("GET", "/test", nil)
+	req.Header.Set("X-Request-ID", "web-123")
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if rr.Header().Get("X-Request-ID") != "web-123" || capturedID != "web-123" {
+		t.Errorf("Expected incoming ID to be unchanged, got %q", rr.Header().Get("X-Request-ID"))
+	}
+}
+
+func TestRequestIDSurvivesPanic(t *testing.T) {
+	var loggedID string
+
+	// recoverer stands in for a recovery middleware that logs the request ID
+	recoverer := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer func() {
+				if recover() != nil {
+					loggedID, _ = FromContext(r.Context())
+					w.WriteHeader(http.StatusInternalServerError)
+				}
+			}()
+			next.ServeHTTP(w, r)
+		})
+	}
+	panicking := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	})
+
+	t.Run("recovery outside requestid", func(t *testing.T) {
+		handler := recoverer(New()(panicking))
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest("GET", "/test", nil))
+
+		if rr.Code != http.StatusInternalServerError {
+			t.Errorf("Expected status 500, got %d", rr.Code)
+		}
+		if rr.Header().Get("X-Request-ID") == "" {
+			t.Error("Expected X-Request-ID header on recovered response")
+		}
+	})
+
+	t.Run("recovery inside requestid", func(t *testing.T) {
+		loggedID = ""
+		handler := New()(recoverer(panicking))
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest("GET", "/test", nil))
+
+		id := rr.Header().Get("X-Request-ID")
+		if id == "" {
+			t.Fatal("Expected X-Request-ID header on recovered response")
+		}
+		if loggedID != id {
+			t.Errorf("Expected recovery to see request ID %q, got %q", id, loggedID)
+		}
+	})
+}
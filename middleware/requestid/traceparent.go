@@ -0,0 +1,74 @@
+package requestid
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"strings"
+)
+
+// traceParentHeader is the W3C Trace Context header name
+const traceParentHeader = "traceparent"
+
+// traceParentKey stores the traceparent in context
+type traceParentKey struct{}
+
+// TraceParentFromContext returns the traceparent stored by the middleware
+// when WithTraceParent is enabled
+func TraceParentFromContext(ctx context.Context) (string, bool) {
+	tp, ok := ctx.Value(traceParentKey{}).(string)
+	return tp, ok
+}
+
+// parseTraceParent returns the trace-id of a valid traceparent value of
+// the form "00-<32 hex trace-id>-<16 hex parent-id>-<2 hex flags>"
+func parseTraceParent(tp string) (string, bool) {
+	parts := strings.Split(strings.TrimSpace(tp), "-")
+	if len(parts) < 4 {
+		return "", false
+	}
+
+	version, traceID, parentID, flags := parts[0], parts[1], parts[2], parts[3]
+	if !isLowerHex(version, 2) || version == "ff" {
+		return "", false
+	}
+	// Version 00 has exactly four fields; later versions may append more
+	if version == "00" && len(parts) != 4 {
+		return "", false
+	}
+	if !isLowerHex(traceID, 32) || traceID == strings.Repeat("0", 32) {
+		return "", false
+	}
+	if !isLowerHex(parentID, 16) || parentID == strings.Repeat("0", 16) {
+		return "", false
+	}
+	if !isLowerHex(flags, 2) {
+		return "", false
+	}
+
+	return traceID, true
+}
+
+// isLowerHex reports whether s is n lowercase hex characters
+func isLowerHex(s string, n int) bool {
+	if len(s) != n {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if !(c >= '0' && c <= '9' || c >= 'a' && c <= 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// newTraceParent generates a version 00 traceparent, returning it and its trace-id
+func newTraceParent() (string, string) {
+	var b [24]byte
+	rand.Read(b[:])
+
+	traceID := hex.EncodeToString(b[:16])
+	parentID := hex.EncodeToString(b[16:])
+	return "00-" + traceID + "-" + parentID + "-00", traceID
+}
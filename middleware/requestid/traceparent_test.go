@@ -0,0 +1,80 @@
+package requestid
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRequestIDTraceParent(t *testing.T) {
+	middleware := New(WithTraceParent(true))
+
+	var capturedID, capturedTP string
+	handler := middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		capturedID, _ = FromContext(r.Context())
+		capturedTP, _ = TraceParentFromContext(r.Context())
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tp := "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	req := httptest.NewRequest("GET", "/test", nil)
+	req.Header.Set("traceparent", tp)
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	if capturedID != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("Expected trace-id as request ID, got %q", capturedID)
+	}
+	if rr.Header().Get("X-Request-ID") != capturedID {
+		t.Errorf("Expected X-Request-ID %q, got %q", capturedID, rr.Header().Get("X-Request-ID"))
+	}
+	if capturedTP != tp {
+		t.Errorf("Expected traceparent %q in context, got %q", tp, capturedTP)
+	}
+	if rr.Header().Get("traceparent") != tp {
+		t.Errorf("Expected traceparent %q in response, got %q", tp, rr.Header().Get("traceparent"))
+	}
+}
+
+func TestRequestIDTraceParentGenerated(t *testing.T) {
+	tests := []struct {
+		name        string
+		traceparent string
+	}{
+		{name: "Missing", traceparent: ""},
+		{name: "Malformed", traceparent: "not-a-traceparent"},
+		{name: "Uppercase hex", traceparent: "00-4BF92F3577B34DA6A3CE929D0E0E4736-00F067AA0BA902B7-01"},
+		{name: "Zero trace-id", traceparent: "00-00000000000000000000000000000000-00f067aa0ba902b7-01"},
+		{name: "Invalid version", traceparent: "ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			middleware := New(WithTraceParent(true))
+
+			var capturedID string
+			handler := middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				capturedID, _ = FromContext(r.Context())
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			req := httptest.NewRequest("GET", "/test", nil)
+			if tt.traceparent != "" {
+				req.Header.Set("traceparent", tt.traceparent)
+			}
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			tp := rr.Header().Get("traceparent")
+			traceID, ok := parseTraceParent(tp)
+			if !ok {
+				t.Fatalf("Expected a valid generated traceparent, got %q", tp)
+			}
+			if capturedID != traceID {
+				t.Errorf("Expected request ID %q to match trace-id %q", capturedID, traceID)
+			}
+		})
+	}
+}
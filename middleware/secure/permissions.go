@@ -0,0 +1,104 @@
+package secure
+
+import (
+	"strconv"
+	"strings"
+)
+
+// PermissionsPolicyBuilder builds a Permissions-Policy header value for
+// WithPermissionsPolicy. Each allowlist entry is "self", "*" or an origin;
+// an empty allowlist disables the feature.
+//
+//	policy := secure.NewPermissionsPolicy().
+//		Geolocation("self", "https://maps.example.com").
+//		Camera().
+//		String()
+//	// geolocation=(self "https://maps.example.com"), camera=()
+type PermissionsPolicyBuilder struct {
+	features   []string
+	allowlists map[string]string
+}
+
+// NewPermissionsPolicy returns an empty Permissions-Policy builder
+func NewPermissionsPolicy() *PermissionsPolicyBuilder {
+	return &PermissionsPolicyBuilder{allowlists: make(map[string]string)}
+}
+
+// Feature sets the allowlist for a policy-controlled feature. Setting a
+// feature again replaces its allowlist.
+func (b *PermissionsPolicyBuilder) Feature(name string, allowlist ...string) *PermissionsPolicyBuilder {
+	if _, ok := b.allowlists[name]; !ok {
+		b.features = append(b.features, name)
+	}
+
+	members := make([]string, 0, len(allowlist))
+	for _, member := range allowlist {
+		switch member {
+		case "self", "*", "src":
+			members = append(members, member)
+		default:
+			members = append(members, strconv.Quote(member))
+		}
+	}
+	b.allowlists[name] = "(" + strings.Join(members, " ") + ")"
+	return b
+}
+
+// Accelerometer sets the accelerometer allowlist
+func (b *PermissionsPolicyBuilder) Accelerometer(allowlist ...string) *PermissionsPolicyBuilder {
+	return b.Feature("accelerometer", allowlist...)
+}
+
+// Autoplay sets the autoplay allowlist
+func (b *PermissionsPolicyBuilder) Autoplay(allowlist ...string) *PermissionsPolicyBuilder {
+	return b.Feature("autoplay", allowlist...)
+}
+
+// Camera sets the camera allowlist
+func (b *PermissionsPolicyBuilder) Camera(allowlist ...string) *PermissionsPolicyBuilder {
+	return b.Feature("camera", allowlist...)
+}
+
+// Fullscreen sets the fullscreen allowlist
+func (b *PermissionsPolicyBuilder) Fullscreen(allowlist ...string) *PermissionsPolicyBuilder {
+	return b.Feature("fullscreen", allowlist...)
+}
+
+// Geolocation sets the geolocation allowlist
+func (b *PermissionsPolicyBuilder) Geolocation(allowlist ...string) *PermissionsPolicyBuilder {
+	return b.Feature("geolocation", allowlist...)
+}
+
+// Gyroscope sets the gyroscope allowlist
+func (b *PermissionsPolicyBuilder) Gyroscope(allowlist ...string) *PermissionsPolicyBuilder {
+	return b.Feature("gyroscope", allowlist...)
+}
+
+// Magnetometer sets the magnetometer allowlist
+func (b *PermissionsPolicyBuilder) Magnetometer(allowlist ...string) *PermissionsPolicyBuilder {
+	return b.Feature("magnetometer", allowlist...)
+}
+
+// Microphone sets the microphone allowlist
+func (b *PermissionsPolicyBuilder) Microphone(allowlist ...string) *PermissionsPolicyBuilder {
+	return b.Feature("microphone", allowlist...)
+}
+
+// Payment sets the payment allowlist
+func (b *PermissionsPolicyBuilder) Payment(allowlist ...string) *PermissionsPolicyBuilder {
+	return b.Feature("payment", allowlist...)
+}
+
+// USB sets the usb allowlist
+func (b *PermissionsPolicyBuilder) USB(allowlist ...string) *PermissionsPolicyBuilder {
+	return b.Feature("usb", allowlist...)
+}
+
+// String returns the header value, with features in the order first set
+func (b *PermissionsPolicyBuilder) String() string {
+	directives := make([]string, 0, len(b.features))
+	for _, name := range b.features {
+		directives = append(directives, name+"="+b.allowlists[name])
+	}
+	return strings.Join(directives, ", ")
+}
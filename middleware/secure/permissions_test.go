@@ -0,0 +1,63 @@
+package secure
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestPermissionsPolicyBuilder(t *testing.T) {
+	tests := []struct {
+		name     string
+		builder  *PermissionsPolicyBuilder
+		expected string
+	}{
+		{"Empty", NewPermissionsPolicy(), ""},
+		{"Deny", NewPermissionsPolicy().Camera(), "camera=()"},
+		{"Self", NewPermissionsPolicy().Geolocation("self"), "geolocation=(self)"},
+		{"Wildcard", NewPermissionsPolicy().Fullscreen("*"), "fullscreen=(*)"},
+		{
+			"Quoted origins",
+			NewPermissionsPolicy().Geolocation("self", "https://maps.example.com"),
+			`geolocation=(self "https://maps.example.com")`,
+		},
+		{
+			"Multiple features",
+			NewPermissionsPolicy().Geolocation("self").Microphone().Camera().Payment(),
+			"geolocation=(self), microphone=(), camera=(), payment=()",
+		},
+		{
+			"Replaced feature keeps position",
+			NewPermissionsPolicy().Camera().USB().Camera("self"),
+			"camera=(self), usb=()",
+		},
+		{"Custom feature", NewPermissionsPolicy().Feature("display-capture", "self"), "display-capture=(self)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.builder.String(); got != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestPermissionsPolicyBuilderWithMiddleware(t *testing.T) {
+	policy := NewPermissionsPolicy().Geolocation("self").Microphone().String()
+	middleware := New(WithPermissionsPolicy(policy))
+
+	handler := middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	// Matches the hand-written policy in TestSecurePermissionsPolicy
+	if rr.Header().Get("Permissions-Policy") != "geolocation=(self), microphone=()" {
+		t.Errorf("Expected Permissions-Policy='geolocation=(self), microphone=()', got %s", rr.Header().Get("Permissions-Policy"))
+	}
+}
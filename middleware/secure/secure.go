@@ -1,8 +1,12 @@
 package secure
 
 import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
 	"net/http"
 	"strconv"
+	"strings"
 )
 
 // Option is secure option.
@@ -36,6 +40,12 @@ type options struct {
 	// Default: false
 	hstsExcludeSubdomains bool
 
+	// HSTSPreload adds the preload directive to the `Strict-Transport-Security`
+	// header, signalling consent to be included in browser preload lists.
+	// Requires a max-age of at least one year and includeSubDomains
+	// Default: false
+	hstsPreload bool
+
 	// ContentSecurityPolicy sets the `Content-Security-Policy` header providing
 	// security against cross-site scripting (XSS), clickjacking and other code
 	// injection attacks.
@@ -49,6 +59,11 @@ type options struct {
 	// Default: false
 	cspReportOnly bool
 
+	// SeparateStyleNonce generates a style nonce distinct from the script
+	// nonce instead of reusing one nonce for both placeholders.
+	// Default: false
+	separateStyleNonce bool
+
 	// ReferrerPolicy sets the `Referrer-Policy` header providing security against
 	// leaking potentially sensitive request paths to third parties.
 	// Default: ""
@@ -58,6 +73,91 @@ type options struct {
 	// against using browser features in documents or iframes.
 	// Default: ""
 	permissionsPolicy string
+
+	// FrameAllowFrom is the origin allowed to frame the page. It is emitted as
+	// a CSP `frame-ancestors` directive, the modern replacement for the
+	// deprecated `X-Frame-Options: ALLOW-FROM`.
+	// Default: ""
+	frameAllowFrom string
+
+	// FrameAllowFromLegacy also emits `X-Frame-Options: ALLOW-FROM <origin>`
+	// for old browsers. Modern browsers ignore ALLOW-FROM and rely on the CSP.
+	// Default: false
+	frameAllowFromLegacy bool
+
+	// CrossOriginOpenerPolicy sets the `Cross-Origin-Opener-Policy` header
+	// Default: ""
+	crossOriginOpenerPolicy string
+
+	// CrossOriginEmbedderPolicy sets the `Cross-Origin-Embedder-Policy` header
+	// Default: ""
+	crossOriginEmbedderPolicy string
+
+	// CrossOriginResourcePolicy sets the `Cross-Origin-Resource-Policy` header
+	// Default: ""
+	crossOriginResourcePolicy string
+
+	// ForceHTTPS redirects plain HTTP requests to HTTPS before calling the
+	// next handler. Requests are considered secure when served over TLS or
+	// when `X-Forwarded-Proto: https` or `X-Forwarded-Ssl: on` is set by a proxy.
+	// Default: false
+	forceHTTPS bool
+
+	// HTTPSHost is the host used in HTTPS redirects when the public host
+	// differs from the request host.
+	// Default: "" (request host)
+	httpsHost string
+
+	// Skipper defines a function to skip the middleware, e.g. for local
+	// development over plain HTTP. Skipped requests get no headers.
+	// Default: nil
+	skipper func(*http.Request) bool
+
+	// HideServerHeader removes the `Server` header from responses, hiding
+	// version information leaked by the framework or upstream handlers.
+	// Default: false
+	hideServerHeader bool
+
+	// ServerHeader replaces the `Server` header on responses.
+	// Default: ""
+	serverHeader string
+
+	// ExpectCT sets the `Expect-CT` header for certificate transparency
+	// enforcement and reporting.
+	// Default: ""
+	expectCT string
+
+	// ReportTo sets the `Report-To` header configuring reporting API endpoint
+	// groups, e.g. `{"group":"default","max_age":10886400,"endpoints":[{"url":"https://example.com/reports"}]}`.
+	// Default: ""
+	reportTo string
+
+	// PermittedCrossDomainPolicies sets the `X-Permitted-Cross-Domain-Policies`
+	// header restricting Adobe Flash and PDF clients from loading data cross
+	// domain, e.g. CrossDomainPoliciesNone.
+	// Default: ""
+	permittedCrossDomainPolicies string
+
+	// DownloadOptions sets the legacy Internet Explorer `X-Download-Options`
+	// header, e.g. DownloadOptionsNoOpen to stop downloads opening in the
+	// site's context.
+	// Default: ""
+	downloadOptions string
+
+	// CacheControl sets the `Cache-Control` header unless the handler sets
+	// its own, e.g. `no-store` for pages behind authentication.
+	// Default: ""
+	cacheControl string
+
+	// PragmaNoCache sets `Pragma: no-cache` for HTTP/1.0 caches alongside
+	// CacheControl, unless the handler replaces CacheControl. Enabled by
+	// WithNoStore.
+	// Default: false
+	pragmaNoCache bool
+
+	// ForceCacheControl overrides a `Cache-Control` header set by the handler.
+	// Default: false
+	forceCacheControl bool
 }
 
 // WithXSSProtection sets the X-XSS-Protection header
@@ -95,6 +195,15 @@ func WithHSTSExcludeSubdomains(exclude bool) Option {
 	}
 }
 
+// WithHSTSPreload adds the preload directive to HSTS. The preload list also
+// requires WithHSTSMaxAge of at least 31536000 and subdomains included; New
+// panics otherwise.
+func WithHSTSPreload(preload bool) Option {
+	return func(o *options) {
+		o.hstsPreload = preload
+	}
+}
+
 // WithContentSecurityPolicy sets the Content-Security-Policy header
 func WithContentSecurityPolicy(policy string) Option {
 	return func(o *options) {
@@ -109,6 +218,14 @@ func WithCSPReportOnly(reportOnly bool) Option {
 	}
 }
 
+// WithSeparateStyleNonce sets whether %STYLE_NONCE% gets its own nonce
+// rather than the %SCRIPT_NONCE% value
+func WithSeparateStyleNonce(separate bool) Option {
+	return func(o *options) {
+		o.separateStyleNonce = separate
+	}
+}
+
 // WithReferrerPolicy sets the Referrer-Policy header
 func WithReferrerPolicy(policy string) Option {
 	return func(o *options) {
@@ -123,6 +240,223 @@ func WithPermissionsPolicy(policy string) Option {
 	}
 }
 
+// WithFrameAllowFrom allows framing by origin via CSP `frame-ancestors`
+func WithFrameAllowFrom(origin string) Option {
+	return func(o *options) {
+		o.frameAllowFrom = origin
+	}
+}
+
+// WithFrameAllowFromLegacy also emits the deprecated `X-Frame-Options: ALLOW-FROM`
+// header for old browsers, replacing any other X-Frame-Options value
+func WithFrameAllowFromLegacy(legacy bool) Option {
+	return func(o *options) {
+		o.frameAllowFromLegacy = legacy
+	}
+}
+
+// WithCrossOriginOpenerPolicy sets the Cross-Origin-Opener-Policy header
+func WithCrossOriginOpenerPolicy(policy string) Option {
+	return func(o *options) {
+		o.crossOriginOpenerPolicy = policy
+	}
+}
+
+// WithCrossOriginEmbedderPolicy sets the Cross-Origin-Embedder-Policy header
+func WithCrossOriginEmbedderPolicy(policy string) Option {
+	return func(o *options) {
+		o.crossOriginEmbedderPolicy = policy
+	}
+}
+
+// WithCrossOriginResourcePolicy sets the Cross-Origin-Resource-Policy header
+func WithCrossOriginResourcePolicy(policy string) Option {
+	return func(o *options) {
+		o.crossOriginResourcePolicy = policy
+	}
+}
+
+// WithForceHTTPS enables redirecting plain HTTP requests to HTTPS
+func WithForceHTTPS(force bool) Option {
+	return func(o *options) {
+		o.forceHTTPS = force
+	}
+}
+
+// WithHTTPSHost sets the host used in HTTPS redirects
+func WithHTTPSHost(host string) Option {
+	return func(o *options) {
+		o.httpsHost = host
+	}
+}
+
+// WithSkipper sets a function to skip the middleware for a request
+func WithSkipper(f func(r *http.Request) bool) Option {
+	return func(o *options) {
+		o.skipper = f
+	}
+}
+
+// WithHideServerHeader removes the Server header from responses
+func WithHideServerHeader(hide bool) Option {
+	return func(o *options) {
+		o.hideServerHeader = hide
+	}
+}
+
+// WithServerHeader replaces the Server header on responses
+func WithServerHeader(value string) Option {
+	return func(o *options) {
+		o.serverHeader = value
+	}
+}
+
+// WithExpectCT sets the Expect-CT header. Without enforce, violations are
+// only reported to reportURI. An empty reportURI omits the report-uri directive.
+func WithExpectCT(maxAge int, enforce bool, reportURI string) Option {
+	return func(o *options) {
+		value := "max-age=" + strconv.Itoa(maxAge)
+		if enforce {
+			value += ", enforce"
+		}
+		if reportURI != "" {
+			value += `, report-uri="` + reportURI + `"`
+		}
+		o.expectCT = value
+	}
+}
+
+// WithReportTo sets the Report-To header
+func WithReportTo(value string) Option {
+	return func(o *options) {
+		o.reportTo = value
+	}
+}
+
+// X-Permitted-Cross-Domain-Policies values
+const (
+	CrossDomainPoliciesNone          = "none"
+	CrossDomainPoliciesMasterOnly    = "master-only"
+	CrossDomainPoliciesByContentType = "by-content-type"
+	CrossDomainPoliciesAll           = "all"
+)
+
+// DownloadOptionsNoOpen is the X-Download-Options value preventing
+// downloads from being opened directly
+const DownloadOptionsNoOpen = "noopen"
+
+// WithPermittedCrossDomainPolicies sets the X-Permitted-Cross-Domain-Policies header
+func WithPermittedCrossDomainPolicies(policy string) Option {
+	return func(o *options) {
+		o.permittedCrossDomainPolicies = policy
+	}
+}
+
+// WithDownloadOptions sets the X-Download-Options header
+func WithDownloadOptions(value string) Option {
+	return func(o *options) {
+		o.downloadOptions = value
+	}
+}
+
+// WithNoStore sets `Cache-Control: no-store` and `Pragma: no-cache` so
+// responses aren't cached by browsers or proxies
+func WithNoStore(enabled bool) Option {
+	return func(o *options) {
+		if enabled {
+			o.cacheControl = "no-store"
+		} else {
+			o.cacheControl = ""
+		}
+		o.pragmaNoCache = enabled
+	}
+}
+
+// WithCacheControl sets the default Cache-Control header
+func WithCacheControl(value string) Option {
+	return func(o *options) {
+		o.cacheControl = value
+	}
+}
+
+// WithForceCacheControl overrides a Cache-Control header set by the handler
+func WithForceCacheControl(force bool) Option {
+	return func(o *options) {
+		o.forceCacheControl = force
+	}
+}
+
+// overrideHeaderWriter adjusts the Server, Cache-Control and Pragma headers
+// just before the status is written, after handlers had their chance to set
+// them
+type overrideHeaderWriter struct {
+	http.ResponseWriter
+	hideServer   bool
+	server       string
+	cacheControl string
+	// pragmaCacheControl is the Cache-Control value `Pragma: no-cache` goes
+	// with. Pragma is left out if the handler replaced it.
+	pragmaCacheControl string
+	wroteHeader        bool
+}
+
+// override adjusts the headers once, before they are sent
+func (w *overrideHeaderWriter) override() {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+	if w.server != "" {
+		w.Header().Set("Server", w.server)
+	} else if w.hideServer {
+		w.Header().Del("Server")
+	}
+	if w.cacheControl != "" {
+		w.Header().Set("Cache-Control", w.cacheControl)
+	}
+	if w.pragmaCacheControl != "" && w.Header().Get("Cache-Control") == w.pragmaCacheControl {
+		w.Header().Set("Pragma", "no-cache")
+	}
+}
+
+// WriteHeader implements http.ResponseWriter
+func (w *overrideHeaderWriter) WriteHeader(code int) {
+	w.override()
+	w.ResponseWriter.WriteHeader(code)
+}
+
+// Write implements http.ResponseWriter
+func (w *overrideHeaderWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush implements http.Flusher
+func (w *overrideHeaderWriter) Flush() {
+	w.override()
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap returns the underlying http.ResponseWriter for http.ResponseController
+func (w *overrideHeaderWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// isHTTPS reports whether the request was received over HTTPS
+func isHTTPS(r *http.Request) bool {
+	if r.TLS != nil {
+		return true
+	}
+	if strings.EqualFold(r.Header.Get("X-Forwarded-Proto"), "https") {
+		return true
+	}
+	return strings.EqualFold(r.Header.Get("X-Forwarded-Ssl"), "on")
+}
+
 // New returns a middleware that sets security headers
 func New(opts ...Option) func(http.Handler) http.Handler {
 	o := &options{
@@ -132,12 +466,99 @@ func New(opts ...Option) func(http.Handler) http.Handler {
 		hstsMaxAge:         0,
 	}
 
+	return newSecure(o, opts)
+}
+
+// NewStrict returns a middleware that sets a strict, modern set of security
+// headers suited to high-security apps served only over HTTPS: HSTS with
+// preload, CSP `default-src 'none'`, COOP/COEP/CORP, `X-Frame-Options: DENY`,
+// `Referrer-Policy: no-referrer` and nosniff. Options override the preset.
+func NewStrict(opts ...Option) func(http.Handler) http.Handler {
+	o := &options{
+		xssProtection:             "0",
+		contentTypeNosniff:        "nosniff",
+		xFrameOptions:             "DENY",
+		hstsMaxAge:                63072000, // 2 years
+		hstsPreload:               true,
+		contentSecurityPolicy:     "default-src 'none'; frame-ancestors 'none'; base-uri 'none'; form-action 'none'",
+		referrerPolicy:            "no-referrer",
+		crossOriginOpenerPolicy:   "same-origin",
+		crossOriginEmbedderPolicy: "require-corp",
+		crossOriginResourcePolicy: "same-origin",
+	}
+
+	return newSecure(o, opts)
+}
+
+// hstsPreloadMinMaxAge is the minimum HSTS max-age accepted by the preload list
+const hstsPreloadMinMaxAge = 31536000
+
+// newSecure applies opts on top of the preset o and builds the middleware
+func newSecure(o *options, opts []Option) func(http.Handler) http.Handler {
 	for _, opt := range opts {
 		opt(o)
 	}
 
+	// A preload directive the preload list would refuse is a misconfiguration
+	if o.hstsPreload {
+		if o.hstsMaxAge < hstsPreloadMinMaxAge {
+			panic("secure: HSTS preload requires max-age of at least 31536000")
+		}
+		if o.hstsExcludeSubdomains {
+			panic("secure: HSTS preload requires includeSubDomains")
+		}
+	}
+
+	// Compose the enforced and report-only policies up front
+	var csp, cspReportOnly string
+	if o.cspReportOnly {
+		cspReportOnly = o.contentSecurityPolicy
+	} else {
+		csp = o.contentSecurityPolicy
+	}
+	if o.frameAllowFrom != "" {
+		// frame-ancestors is always enforced, even in report-only mode. It
+		// replaces any frame-ancestors in the policy, such as NewStrict's,
+		// since browsers only apply the first one.
+		directive := "frame-ancestors " + o.frameAllowFrom
+		csp = setDirective(csp, directive)
+		if hasDirective(cspReportOnly, "frame-ancestors") {
+			cspReportOnly = setDirective(cspReportOnly, directive)
+		}
+		if o.frameAllowFromLegacy {
+			o.xFrameOptions = "ALLOW-FROM " + o.frameAllowFrom
+		}
+	}
+
+	// Nonces are only generated for policies using a placeholder
+	useNonce := strings.Contains(csp+cspReportOnly, ScriptNoncePlaceholder) ||
+		strings.Contains(csp+cspReportOnly, StyleNoncePlaceholder)
+
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if o.skipper != nil && o.skipper(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			// Redirect to HTTPS. HSTS is only honoured over HTTPS, so it is
+			// sent on the redirected request rather than this one.
+			if o.forceHTTPS && !isHTTPS(r) {
+				host := o.httpsHost
+				if host == "" {
+					host = r.Host
+				}
+
+				// 308 preserves the method and body of non-idempotent requests
+				code := http.StatusMovedPermanently
+				if r.Method != http.MethodGet && r.Method != http.MethodHead {
+					code = http.StatusPermanentRedirect
+				}
+
+				http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), code)
+				return
+			}
+
 			// X-XSS-Protection
 			if o.xssProtection != "" {
 				w.Header().Set("X-XSS-Protection", o.xssProtection)
@@ -159,16 +580,29 @@ func New(opts ...Option) func(http.Handler) http.Handler {
 				if !o.hstsExcludeSubdomains {
 					hstsValue += "; includeSubDomains"
 				}
+				if o.hstsPreload {
+					hstsValue += "; preload"
+				}
 				w.Header().Set("Strict-Transport-Security", hstsValue)
 			}
 
 			// Content-Security-Policy
-			if o.contentSecurityPolicy != "" {
-				if o.cspReportOnly {
-					w.Header().Set("Content-Security-Policy-Report-Only", o.contentSecurityPolicy)
-				} else {
-					w.Header().Set("Content-Security-Policy", o.contentSecurityPolicy)
+			csp, cspReportOnly := csp, cspReportOnly
+			if useNonce {
+				n := nonces{script: newNonce()}
+				n.style = n.script
+				if o.separateStyleNonce {
+					n.style = newNonce()
 				}
+				replacer := strings.NewReplacer(ScriptNoncePlaceholder, n.script, StyleNoncePlaceholder, n.style)
+				csp, cspReportOnly = replacer.Replace(csp), replacer.Replace(cspReportOnly)
+				r = r.WithContext(context.WithValue(r.Context(), nonceKey{}, n))
+			}
+			if csp != "" {
+				w.Header().Set("Content-Security-Policy", csp)
+			}
+			if cspReportOnly != "" {
+				w.Header().Set("Content-Security-Policy-Report-Only", cspReportOnly)
 			}
 
 			// Referrer-Policy
@@ -181,7 +615,148 @@ func New(opts ...Option) func(http.Handler) http.Handler {
 				w.Header().Set("Permissions-Policy", o.permissionsPolicy)
 			}
 
+			// Cross-Origin-Opener-Policy
+			if o.crossOriginOpenerPolicy != "" {
+				w.Header().Set("Cross-Origin-Opener-Policy", o.crossOriginOpenerPolicy)
+			}
+
+			// Cross-Origin-Embedder-Policy
+			if o.crossOriginEmbedderPolicy != "" {
+				w.Header().Set("Cross-Origin-Embedder-Policy", o.crossOriginEmbedderPolicy)
+			}
+
+			// Cross-Origin-Resource-Policy
+			if o.crossOriginResourcePolicy != "" {
+				w.Header().Set("Cross-Origin-Resource-Policy", o.crossOriginResourcePolicy)
+			}
+
+			// Expect-CT
+			if o.expectCT != "" {
+				w.Header().Set("Expect-CT", o.expectCT)
+			}
+
+			// Report-To
+			if o.reportTo != "" {
+				w.Header().Set("Report-To", o.reportTo)
+			}
+
+			// X-Permitted-Cross-Domain-Policies
+			if o.permittedCrossDomainPolicies != "" {
+				w.Header().Set("X-Permitted-Cross-Domain-Policies", o.permittedCrossDomainPolicies)
+			}
+
+			// X-Download-Options
+			if o.downloadOptions != "" {
+				w.Header().Set("X-Download-Options", o.downloadOptions)
+			}
+
+			// Cache-Control, as a default the handler may replace
+			defaultCacheControl := o.cacheControl != "" && !o.forceCacheControl && w.Header().Get("Cache-Control") == ""
+			if defaultCacheControl {
+				w.Header().Set("Cache-Control", o.cacheControl)
+			}
+
+			// Server, forced Cache-Control, and Pragma once the final
+			// Cache-Control is known
+			forceCacheControl := o.cacheControl != "" && o.forceCacheControl
+			pragmaNoCache := o.pragmaNoCache && (defaultCacheControl || forceCacheControl)
+			if o.hideServerHeader || o.serverHeader != "" || forceCacheControl || pragmaNoCache {
+				ow := &overrideHeaderWriter{ResponseWriter: w, hideServer: o.hideServerHeader, server: o.serverHeader}
+				if forceCacheControl {
+					ow.cacheControl = o.cacheControl
+				}
+				if pragmaNoCache {
+					ow.pragmaCacheControl = o.cacheControl
+				}
+				// Handlers returning without writing get an implicit 200 from
+				// net/http that bypasses the wrapper
+				defer ow.override()
+				w = ow
+			}
+
 			next.ServeHTTP(w, r)
 		})
 	}
 }
+
+// CSP placeholders replaced with a fresh nonce on every request, e.g.
+// "script-src 'nonce-%SCRIPT_NONCE%'; style-src 'nonce-%STYLE_NONCE%'".
+// Both receive the same nonce unless WithSeparateStyleNonce is set. Sharing
+// one nonce costs a single random read per request, but a nonce leaked
+// through injected markup then unlocks scripts as well as styles.
+const (
+	ScriptNoncePlaceholder = "%SCRIPT_NONCE%"
+	StyleNoncePlaceholder  = "%STYLE_NONCE%"
+)
+
+// nonces holds the CSP nonces of a request
+type nonces struct {
+	script string
+	style  string
+}
+
+// nonceKey is the context key for the request nonces
+type nonceKey struct{}
+
+// newNonce returns a base64-encoded 128-bit random nonce
+func newNonce() string {
+	var b [16]byte
+	rand.Read(b[:])
+	return base64.StdEncoding.EncodeToString(b[:])
+}
+
+// ScriptNonce returns the nonce substituted for %SCRIPT_NONCE% in the
+// request's CSP, for use in <script nonce="..."> tags, or "" if none
+func ScriptNonce(ctx context.Context) string {
+	n, _ := ctx.Value(nonceKey{}).(nonces)
+	return n.script
+}
+
+// StyleNonce returns the nonce substituted for %STYLE_NONCE% in the
+// request's CSP, for use in <style nonce="..."> tags, or "" if none
+func StyleNonce(ctx context.Context) string {
+	n, _ := ctx.Value(nonceKey{}).(nonces)
+	return n.style
+}
+
+// directiveName returns the lowercased name of a CSP directive
+func directiveName(directive string) string {
+	name, _, _ := strings.Cut(strings.TrimSpace(directive), " ")
+	return strings.ToLower(name)
+}
+
+// hasDirective reports whether a CSP policy contains the named directive
+func hasDirective(policy, name string) bool {
+	for _, directive := range strings.Split(policy, ";") {
+		if directiveName(directive) == name {
+			return true
+		}
+	}
+	return false
+}
+
+// setDirective sets a directive in a CSP policy, replacing every directive
+// of the same name in place or appending it if there is none
+func setDirective(policy, directive string) string {
+	name := directiveName(directive)
+
+	var directives []string
+	replaced := false
+	for _, d := range strings.Split(policy, ";") {
+		d = strings.TrimSpace(d)
+		if d == "" {
+			continue
+		}
+		if directiveName(d) == name {
+			if replaced {
+				continue
+			}
+			d, replaced = directive, true
+		}
+		directives = append(directives, d)
+	}
+	if !replaced {
+		directives = append(directives, directive)
+	}
+	return strings.Join(directives, "; ")
+}
@@ -1,8 +1,10 @@
 package secure
 
 import (
+	"crypto/tls"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 )
 
@@ -253,3 +255,763 @@ func TestSecureDisableHeaders(t *testing.T) {
 		t.Error("Expected X-Frame-Options to not be set")
 	}
 }
+
+func TestSecureFrameAllowFrom(t *testing.T) {
+	middleware := New(WithFrameAllowFrom("https://partner.example.com"))
+
+	handler := middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	expected := "frame-ancestors https://partner.example.com"
+	if rr.Header().Get("Content-Security-Policy") != expected {
+		t.Errorf("Expected CSP=%q, got %q", expected, rr.Header().Get("Content-Security-Policy"))
+	}
+
+	// Legacy header is not emitted unless requested
+	if rr.Header().Get("X-Frame-Options") != "SAMEORIGIN" {
+		t.Errorf("Expected X-Frame-Options='SAMEORIGIN', got %s", rr.Header().Get("X-Frame-Options"))
+	}
+}
+
+func TestSecureFrameAllowFromLegacy(t *testing.T) {
+	middleware := New(
+		WithContentSecurityPolicy("default-src 'self';"),
+		WithFrameAllowFrom("https://partner.example.com"),
+		WithFrameAllowFromLegacy(true),
+	)
+
+	handler := middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	expected := "default-src 'self'; frame-ancestors https://partner.example.com"
+	if rr.Header().Get("Content-Security-Policy") != expected {
+		t.Errorf("Expected CSP=%q, got %q", expected, rr.Header().Get("Content-Security-Policy"))
+	}
+
+	if rr.Header().Get("X-Frame-Options") != "ALLOW-FROM https://partner.example.com" {
+		t.Errorf("Expected legacy X-Frame-Options, got %s", rr.Header().Get("X-Frame-Options"))
+	}
+}
+
+func TestSecureFrameAllowFromWithReportOnlyCSP(t *testing.T) {
+	middleware := New(
+		WithContentSecurityPolicy("default-src 'self'"),
+		WithCSPReportOnly(true),
+		WithFrameAllowFrom("https://partner.example.com"),
+	)
+
+	handler := middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	if rr.Header().Get("Content-Security-Policy-Report-Only") != "default-src 'self'" {
+		t.Errorf("Expected report-only CSP to be kept, got %q", rr.Header().Get("Content-Security-Policy-Report-Only"))
+	}
+	if rr.Header().Get("Content-Security-Policy") != "frame-ancestors https://partner.example.com" {
+		t.Errorf("Expected enforced frame-ancestors, got %q", rr.Header().Get("Content-Security-Policy"))
+	}
+}
+
+func TestSecureStrict(t *testing.T) {
+	middleware := NewStrict()
+
+	handler := middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	expected := map[string]string{
+		"X-Content-Type-Options":       "nosniff",
+		"X-Frame-Options":              "DENY",
+		"Strict-Transport-Security":    "max-age=63072000; includeSubDomains; preload",
+		"Content-Security-Policy":      "default-src 'none'; frame-ancestors 'none'; base-uri 'none'; form-action 'none'",
+		"Referrer-Policy":              "no-referrer",
+		"Cross-Origin-Opener-Policy":   "same-origin",
+		"Cross-Origin-Embedder-Policy": "require-corp",
+		"Cross-Origin-Resource-Policy": "same-origin",
+	}
+
+	for header, value := range expected {
+		if rr.Header().Get(header) != value {
+			t.Errorf("Expected %s=%q, got %q", header, value, rr.Header().Get(header))
+		}
+	}
+}
+
+func TestSecureStrictFrameAllowFrom(t *testing.T) {
+	middleware := NewStrict(
+		WithFrameAllowFrom("https://partner.example.com"),
+		WithFrameAllowFromLegacy(true),
+	)
+
+	handler := middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	csp := rr.Header().Get("Content-Security-Policy")
+	if n := strings.Count(csp, "frame-ancestors"); n != 1 {
+		t.Errorf("Expected exactly one frame-ancestors directive, got %d in %q", n, csp)
+	}
+	expected := "default-src 'none'; frame-ancestors https://partner.example.com; base-uri 'none'; form-action 'none'"
+	if csp != expected {
+		t.Errorf("Expected CSP=%q, got %q", expected, csp)
+	}
+	if rr.Header().Get("X-Frame-Options") != "ALLOW-FROM https://partner.example.com" {
+		t.Errorf("Expected legacy X-Frame-Options, got %s", rr.Header().Get("X-Frame-Options"))
+	}
+
+	// The report-only preset is rewritten too, so it doesn't report the
+	// allowed framing
+	handler = NewStrict(WithCSPReportOnly(true), WithFrameAllowFrom("https://partner.example.com"))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	reportOnly := rr.Header().Get("Content-Security-Policy-Report-Only")
+	if strings.Contains(reportOnly, "frame-ancestors 'none'") {
+		t.Errorf("Expected report-only frame-ancestors to be replaced, got %q", reportOnly)
+	}
+	if rr.Header().Get("Content-Security-Policy") != "frame-ancestors https://partner.example.com" {
+		t.Errorf("Expected enforced frame-ancestors, got %q", rr.Header().Get("Content-Security-Policy"))
+	}
+}
+
+func TestSecureStrictOverride(t *testing.T) {
+	middleware := NewStrict(
+		WithXFrameOptions("SAMEORIGIN"),
+		WithReferrerPolicy("same-origin"),
+	)
+
+	handler := middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	if rr.Header().Get("X-Frame-Options") != "SAMEORIGIN" {
+		t.Errorf("Expected X-Frame-Options='SAMEORIGIN', got %s", rr.Header().Get("X-Frame-Options"))
+	}
+
+	if rr.Header().Get("Referrer-Policy") != "same-origin" {
+		t.Errorf("Expected Referrer-Policy='same-origin', got %s", rr.Header().Get("Referrer-Policy"))
+	}
+
+	// Other strict headers are kept
+	if rr.Header().Get("Cross-Origin-Opener-Policy") != "same-origin" {
+		t.Errorf("Expected Cross-Origin-Opener-Policy='same-origin', got %s", rr.Header().Get("Cross-Origin-Opener-Policy"))
+	}
+}
+
+func TestSecureHSTSPreload(t *testing.T) {
+	middleware := New(WithHSTSMaxAge(31536000), WithHSTSPreload(true))
+
+	handler := middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	expected := "max-age=31536000; includeSubDomains; preload"
+	if rr.Header().Get("Strict-Transport-Security") != expected {
+		t.Errorf("Expected Strict-Transport-Security=%q, got %q", expected, rr.Header().Get("Strict-Transport-Security"))
+	}
+}
+
+func TestSecureHSTSPreloadInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []Option
+	}{
+		{"Max age too low", []Option{WithHSTSMaxAge(86400), WithHSTSPreload(true)}},
+		{"HSTS disabled", []Option{WithHSTSPreload(true)}},
+		{"Subdomains excluded", []Option{WithHSTSMaxAge(31536000), WithHSTSExcludeSubdomains(true), WithHSTSPreload(true)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				if r := recover(); r == nil {
+					t.Error("Expected panic for invalid HSTS preload config")
+				}
+			}()
+
+			New(tt.opts...)
+		})
+	}
+
+	// The strict preset preloads too, so it must reject the same mistakes
+	t.Run("Strict subdomains excluded", func(t *testing.T) {
+		defer func() {
+			if r := recover(); r == nil {
+				t.Error("Expected panic for invalid HSTS preload config")
+			}
+		}()
+
+		NewStrict(WithHSTSExcludeSubdomains(true))
+	})
+}
+
+func TestSecureCrossOriginPolicies(t *testing.T) {
+	middleware := New(
+		WithCrossOriginOpenerPolicy("same-origin"),
+		WithCrossOriginEmbedderPolicy("require-corp"),
+		WithCrossOriginResourcePolicy("same-site"),
+	)
+
+	handler := middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	if rr.Header().Get("Cross-Origin-Opener-Policy") != "same-origin" {
+		t.Errorf("Expected Cross-Origin-Opener-Policy='same-origin', got %s", rr.Header().Get("Cross-Origin-Opener-Policy"))
+	}
+
+	if rr.Header().Get("Cross-Origin-Embedder-Policy") != "require-corp" {
+		t.Errorf("Expected Cross-Origin-Embedder-Policy='require-corp', got %s", rr.Header().Get("Cross-Origin-Embedder-Policy"))
+	}
+
+	if rr.Header().Get("Cross-Origin-Resource-Policy") != "same-site" {
+		t.Errorf("Expected Cross-Origin-Resource-Policy='same-site', got %s", rr.Header().Get("Cross-Origin-Resource-Policy"))
+	}
+}
+
+func TestSecureCrossOriginPoliciesDefault(t *testing.T) {
+	middleware := New()
+
+	handler := middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	for _, header := range []string{"Cross-Origin-Opener-Policy", "Cross-Origin-Embedder-Policy", "Cross-Origin-Resource-Policy"} {
+		if rr.Header().Get(header) != "" {
+			t.Errorf("Expected %s to not be set by default", header)
+		}
+	}
+}
+
+func TestSecureStrictDisableEmbedderPolicy(t *testing.T) {
+	middleware := NewStrict(WithCrossOriginEmbedderPolicy(""))
+
+	handler := middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	if rr.Header().Get("Cross-Origin-Embedder-Policy") != "" {
+		t.Error("Expected empty value to skip Cross-Origin-Embedder-Policy")
+	}
+}
+
+func TestSecureForceHTTPS(t *testing.T) {
+	middleware := New(WithForceHTTPS(true), WithHSTSMaxAge(31536000))
+
+	handler := middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		name           string
+		method         string
+		setup          func(*http.Request)
+		expectedStatus int
+		expectedURL    string
+	}{
+		{
+			name:           "Plain HTTP GET",
+			method:         "GET",
+			setup:          func(r *http.Request) {},
+			expectedStatus: http.StatusMovedPermanently,
+			expectedURL:    "https://example.com/path?q=1",
+		},
+		{
+			name:           "Plain HTTP POST",
+			method:         "POST",
+			setup:          func(r *http.Request) {},
+			expectedStatus: http.StatusPermanentRedirect,
+			expectedURL:    "https://example.com/path?q=1",
+		},
+		{
+			name:           "TLS",
+			method:         "GET",
+			setup:          func(r *http.Request) { r.TLS = &tls.ConnectionState{} },
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "X-Forwarded-Proto",
+			method:         "GET",
+			setup:          func(r *http.Request) { r.Header.Set("X-Forwarded-Proto", "https") },
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "X-Forwarded-Ssl",
+			method:         "GET",
+			setup:          func(r *http.Request) { r.Header.Set("X-Forwarded-Ssl", "on") },
+			expectedStatus: http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "http://example.com/path?q=1", nil)
+			tt.setup(req)
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d", tt.expectedStatus, rr.Code)
+			}
+
+			if tt.expectedURL != "" {
+				if rr.Header().Get("Location") != tt.expectedURL {
+					t.Errorf("Expected Location=%q, got %q", tt.expectedURL, rr.Header().Get("Location"))
+				}
+			} else if rr.Header().Get("Strict-Transport-Security") == "" {
+				t.Error("Expected HSTS header on HTTPS response")
+			}
+		})
+	}
+}
+
+func TestSecureForceHTTPSWithHost(t *testing.T) {
+	middleware := New(WithForceHTTPS(true), WithHTTPSHost("www.example.com"))
+
+	handler := middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest("GET", "http://internal:8080/login", nil)
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	if rr.Header().Get("Location") != "https://www.example.com/login" {
+		t.Errorf("Expected redirect to public host, got %q", rr.Header().Get("Location"))
+	}
+}
+
+func TestSecureWithSkipper(t *testing.T) {
+	middleware := NewStrict(
+		WithForceHTTPS(true),
+		WithSkipper(func(r *http.Request) bool {
+			return strings.HasPrefix(r.Host, "localhost")
+		}),
+	)
+
+	handler := middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest("GET", "http://localhost:8080/test", nil)
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", rr.Code)
+	}
+
+	for header := range rr.Header() {
+		t.Errorf("Expected no headers on skipped request, got %s", header)
+	}
+
+	// Other hosts are still protected
+	req = httptest.NewRequest("GET", "https://example.com/test", nil)
+	rr = httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	if rr.Header().Get("X-Content-Type-Options") != "nosniff" {
+		t.Error("Expected security headers on non-skipped request")
+	}
+}
+
+func TestSecureHideServerHeader(t *testing.T) {
+	middleware := New(WithHideServerHeader(true))
+
+	handler := middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Server", "ares/1.2.3")
+		w.Write([]byte("ok"))
+	}))
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	if _, ok := rr.Result().Header["Server"]; ok {
+		t.Errorf("Expected Server header to be removed, got %s", rr.Result().Header.Get("Server"))
+	}
+}
+
+func TestSecureServerHeader(t *testing.T) {
+	middleware := New(WithServerHeader("webserver"))
+
+	handler := middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Server", "ares/1.2.3")
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	if rr.Result().Header.Get("Server") != "webserver" {
+		t.Errorf("Expected Server='webserver', got %s", rr.Result().Header.Get("Server"))
+	}
+}
+
+func TestSecureServerHeaderEmptyBody(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     []Option
+		expected string
+	}{
+		{"Hidden", []Option{WithHideServerHeader(true)}, ""},
+		{"Replaced", []Option{WithServerHeader("webserver")}, "webserver"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The handler returns without writing, leaving net/http to send
+			// an implicit 200
+			handler := New(tt.opts...)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Server", "ares/1.2.3")
+			}))
+
+			req := httptest.NewRequest("GET", "/test", nil)
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			if got := rr.Result().Header.Get("Server"); got != tt.expected {
+				t.Errorf("Expected Server=%q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestSecureServerHeaderFlush(t *testing.T) {
+	middleware := New(WithServerHeader("webserver"))
+
+	handler := middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Server", "ares/1.2.3")
+		f, ok := w.(http.Flusher)
+		if !ok {
+			t.Fatal("Expected ResponseWriter to implement http.Flusher")
+		}
+		f.Flush()
+	}))
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	if !rr.Flushed {
+		t.Error("Expected response to be flushed")
+	}
+	if rr.Result().Header.Get("Server") != "webserver" {
+		t.Errorf("Expected Server='webserver', got %s", rr.Result().Header.Get("Server"))
+	}
+}
+
+func TestSecureExpectCT(t *testing.T) {
+	tests := []struct {
+		name     string
+		option   Option
+		expected string
+	}{
+		{
+			name:     "Enforce",
+			option:   WithExpectCT(86400, true, "https://example.com/ct"),
+			expected: `max-age=86400, enforce, report-uri="https://example.com/ct"`,
+		},
+		{
+			name:     "Report only",
+			option:   WithExpectCT(86400, false, "https://example.com/ct"),
+			expected: `max-age=86400, report-uri="https://example.com/ct"`,
+		},
+		{
+			name:     "Without report URI",
+			option:   WithExpectCT(3600, true, ""),
+			expected: `max-age=3600, enforce`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := New(tt.option)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			req := httptest.NewRequest("GET", "/test", nil)
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			if rr.Header().Get("Expect-CT") != tt.expected {
+				t.Errorf("Expected Expect-CT=%q, got %q", tt.expected, rr.Header().Get("Expect-CT"))
+			}
+		})
+	}
+}
+
+func TestSecureReportTo(t *testing.T) {
+	value := `{"group":"default","max_age":10886400,"endpoints":[{"url":"https://example.com/reports"}]}`
+	middleware := New(WithReportTo(value))
+
+	handler := middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	if rr.Header().Get("Report-To") != value {
+		t.Errorf("Expected Report-To=%q, got %q", value, rr.Header().Get("Report-To"))
+	}
+
+	// Neither header is set by default
+	rr = httptest.NewRecorder()
+	New()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})).ServeHTTP(rr, req)
+
+	if rr.Header().Get("Expect-CT") != "" || rr.Header().Get("Report-To") != "" {
+		t.Error("Expected Expect-CT and Report-To to not be set by default")
+	}
+}
+
+func TestSecureLegacyHeaders(t *testing.T) {
+	middleware := New(
+		WithPermittedCrossDomainPolicies(CrossDomainPoliciesNone),
+		WithDownloadOptions(DownloadOptionsNoOpen),
+	)
+
+	handler := middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	if rr.Header().Get("X-Permitted-Cross-Domain-Policies") != "none" {
+		t.Errorf("Expected X-Permitted-Cross-Domain-Policies=none, got %q", rr.Header().Get("X-Permitted-Cross-Domain-Policies"))
+	}
+	if rr.Header().Get("X-Download-Options") != "noopen" {
+		t.Errorf("Expected X-Download-Options=noopen, got %q", rr.Header().Get("X-Download-Options"))
+	}
+
+	// Neither header is set by default
+	rr = httptest.NewRecorder()
+	New()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})).ServeHTTP(rr, req)
+
+	if _, ok := rr.Header()["X-Permitted-Cross-Domain-Policies"]; ok {
+		t.Error("Expected X-Permitted-Cross-Domain-Policies to not be set by default")
+	}
+	if _, ok := rr.Header()["X-Download-Options"]; ok {
+		t.Error("Expected X-Download-Options to not be set by default")
+	}
+}
+
+func TestSecureCacheControl(t *testing.T) {
+	tests := []struct {
+		name           string
+		opts           []Option
+		handlerValue   string
+		expectedCache  string
+		expectedPragma string
+	}{
+		{
+			name: "Default sets nothing",
+		},
+		{
+			name:           "No-store",
+			opts:           []Option{WithNoStore(true)},
+			expectedCache:  "no-store",
+			expectedPragma: "no-cache",
+		},
+		{
+			name:          "Custom Cache-Control",
+			opts:          []Option{WithCacheControl("private, max-age=0")},
+			expectedCache: "private, max-age=0",
+		},
+		{
+			name:          "Handler value is kept without Pragma",
+			opts:          []Option{WithNoStore(true)},
+			handlerValue:  "public, max-age=60",
+			expectedCache: "public, max-age=60",
+		},
+		{
+			name:           "Forced over handler value",
+			opts:           []Option{WithNoStore(true), WithForceCacheControl(true)},
+			handlerValue:   "public, max-age=60",
+			expectedCache:  "no-store",
+			expectedPragma: "no-cache",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := New(tt.opts...)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tt.handlerValue != "" {
+					w.Header().Set("Cache-Control", tt.handlerValue)
+				}
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, httptest.NewRequest("GET", "/test", nil))
+
+			if rr.Header().Get("Cache-Control") != tt.expectedCache {
+				t.Errorf("Expected Cache-Control %q, got %q", tt.expectedCache, rr.Header().Get("Cache-Control"))
+			}
+			if rr.Header().Get("Pragma") != tt.expectedPragma {
+				t.Errorf("Expected Pragma %q, got %q", tt.expectedPragma, rr.Header().Get("Pragma"))
+			}
+		})
+	}
+}
+
+func TestSecureNoStoreImplicitStatus(t *testing.T) {
+	// The handler returns without writing, so net/http sends the implicit 200
+	handler := New(WithNoStore(true))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("GET", "/test", nil))
+
+	if rr.Header().Get("Cache-Control") != "no-store" {
+		t.Errorf("Expected Cache-Control no-store, got %q", rr.Header().Get("Cache-Control"))
+	}
+	if rr.Header().Get("Pragma") != "no-cache" {
+		t.Errorf("Expected Pragma no-cache, got %q", rr.Header().Get("Pragma"))
+	}
+}
+
+func TestSecureCSPNonce(t *testing.T) {
+	policy := "default-src 'self'; script-src 'nonce-%SCRIPT_NONCE%'; style-src 'nonce-%STYLE_NONCE%'"
+
+	tests := []struct {
+		name     string
+		opts     []Option
+		separate bool
+	}{
+		{"Shared nonce", nil, false},
+		{"Separate style nonce", []Option{WithSeparateStyleNonce(true)}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var script, style string
+			handler := New(append([]Option{WithContentSecurityPolicy(policy)}, tt.opts...)...)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				script, style = ScriptNonce(r.Context()), StyleNonce(r.Context())
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			seen := make(map[string]bool)
+			for i := 0; i < 3; i++ {
+				rr := httptest.NewRecorder()
+				handler.ServeHTTP(rr, httptest.NewRequest("GET", "/test", nil))
+
+				if script == "" || style == "" {
+					t.Fatalf("Expected script and style nonces, got %q and %q", script, style)
+				}
+				if (script != style) != tt.separate {
+					t.Errorf("Expected separate nonces %v, got script %q and style %q", tt.separate, script, style)
+				}
+
+				expected := "default-src 'self'; script-src 'nonce-" + script + "'; style-src 'nonce-" + style + "'"
+				if got := rr.Header().Get("Content-Security-Policy"); got != expected {
+					t.Errorf("Expected CSP %q, got %q", expected, got)
+				}
+
+				// A fresh nonce is generated per request
+				if seen[script] {
+					t.Errorf("Expected a new nonce per request, got %q again", script)
+				}
+				seen[script] = true
+			}
+		})
+	}
+}
+
+func TestSecureCSPNonceReportOnly(t *testing.T) {
+	var script string
+	handler := New(
+		WithContentSecurityPolicy("script-src 'nonce-%SCRIPT_NONCE%'"),
+		WithCSPReportOnly(true),
+	)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		script = ScriptNonce(r.Context())
+	}))
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("GET", "/test", nil))
+
+	if got := rr.Header().Get("Content-Security-Policy-Report-Only"); got != "script-src 'nonce-"+script+"'" {
+		t.Errorf("Expected nonce in report-only policy, got %q", got)
+	}
+}
+
+func TestSecureNoNonceWithoutPlaceholder(t *testing.T) {
+	var script string
+	handler := New(WithContentSecurityPolicy("default-src 'self'"))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		script = ScriptNonce(r.Context())
+	}))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/test", nil))
+
+	if script != "" {
+		t.Errorf("Expected no nonce without a placeholder, got %q", script)
+	}
+}
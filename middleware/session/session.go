@@ -0,0 +1,252 @@
+package session
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+var ErrNoSession = errors.New("no session in request context")
+
+// Option is session option.
+type Option func(*options)
+
+// options defines the configuration for the session middleware
+type options struct {
+	// SigningKey is the HMAC key signing the session ID cookie
+	// Required
+	signingKey []byte
+
+	// Store persists session values
+	// Default: NewMemoryStore()
+	store Store
+
+	// CookieName is the name of the session ID cookie
+	// Default: "session_id"
+	cookieName string
+
+	// MaxAge is the lifetime of the cookie and the stored session
+	// Default: 24 hours
+	maxAge time.Duration
+
+	// Path is the cookie path
+	// Default: "/"
+	path string
+
+	// Secure restricts the cookie to HTTPS
+	// Default: false
+	secure bool
+
+	// SameSite is the cookie SameSite attribute
+	// Default: http.SameSiteLaxMode
+	sameSite http.SameSite
+}
+
+// WithSigningKey sets the HMAC key signing the session ID cookie
+func WithSigningKey(key []byte) Option {
+	return func(o *options) {
+		o.signingKey = key
+	}
+}
+
+// WithStore sets the session store
+func WithStore(store Store) Option {
+	return func(o *options) {
+		o.store = store
+	}
+}
+
+// WithCookieName sets the session ID cookie name
+func WithCookieName(name string) Option {
+	return func(o *options) {
+		o.cookieName = name
+	}
+}
+
+// WithMaxAge sets the lifetime of the cookie and the stored session
+func WithMaxAge(d time.Duration) Option {
+	return func(o *options) {
+		o.maxAge = d
+	}
+}
+
+// WithPath sets the cookie path
+func WithPath(path string) Option {
+	return func(o *options) {
+		o.path = path
+	}
+}
+
+// WithSecure sets whether the cookie is restricted to HTTPS
+func WithSecure(secure bool) Option {
+	return func(o *options) {
+		o.secure = secure
+	}
+}
+
+// WithSameSite sets the cookie SameSite attribute
+func WithSameSite(sameSite http.SameSite) Option {
+	return func(o *options) {
+		o.sameSite = sameSite
+	}
+}
+
+// Session holds the values of a session. It is safe for concurrent use.
+type Session struct {
+	mu     sync.Mutex
+	id     string
+	values map[string]any
+	opts   *options
+}
+
+// ID returns the session ID
+func (s *Session) ID() string {
+	return s.id
+}
+
+// Get returns the value stored under key
+func (s *Session) Get(key string) (any, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	v, ok := s.values[key]
+	return v, ok
+}
+
+// Set stores value under key. Call Save to persist it.
+func (s *Session) Set(key string, value any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.values[key] = value
+}
+
+// Delete removes the value stored under key. Call Save to persist it.
+func (s *Session) Delete(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.values, key)
+}
+
+// contextKey is the type used for context keys
+type contextKey struct{}
+
+// Get returns the session stored in ctx by the middleware
+func Get(ctx context.Context) (*Session, bool) {
+	s, ok := ctx.Value(contextKey{}).(*Session)
+	return s, ok
+}
+
+// Save persists the request session and sets the session cookie. It must be
+// called before the response header is written.
+func Save(w http.ResponseWriter, r *http.Request) error {
+	s, ok := Get(r.Context())
+	if !ok {
+		return ErrNoSession
+	}
+
+	s.mu.Lock()
+	values := copyValues(s.values)
+	s.mu.Unlock()
+
+	if err := s.opts.store.Save(s.id, values, s.opts.maxAge); err != nil {
+		return err
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     s.opts.cookieName,
+		Value:    s.opts.sign(s.id),
+		Path:     s.opts.path,
+		MaxAge:   int(s.opts.maxAge.Seconds()),
+		Secure:   s.opts.secure,
+		HttpOnly: true,
+		SameSite: s.opts.sameSite,
+	})
+	return nil
+}
+
+// sign returns the cookie value "<id>.<signature>"
+func (o *options) sign(id string) string {
+	mac := hmac.New(sha256.New, o.signingKey)
+	mac.Write([]byte(id))
+	return id + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// verify returns the session ID from a signed cookie value
+func (o *options) verify(value string) (string, bool) {
+	id, _, ok := strings.Cut(value, ".")
+	if !ok || id == "" {
+		return "", false
+	}
+	return id, hmac.Equal([]byte(o.sign(id)), []byte(value))
+}
+
+// newID returns a random session ID
+func newID() string {
+	b := make([]byte, 32)
+	rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// New returns a session middleware loading the session identified by a
+// signed cookie into the request context. Tampered or unknown cookies start
+// a new session.
+func New(opts ...Option) func(http.Handler) http.Handler {
+	o := &options{
+		cookieName: "session_id",
+		maxAge:     24 * time.Hour,
+		path:       "/",
+		sameSite:   http.SameSiteLaxMode,
+	}
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	if len(o.signingKey) == 0 {
+		panic("session signing key is empty")
+	}
+	if o.maxAge <= 0 {
+		panic("session max age must be greater than 0")
+	}
+	if o.store == nil {
+		o.store = NewMemoryStore()
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			s := &Session{opts: o}
+
+			if cookie, err := r.Cookie(o.cookieName); err == nil {
+				if id, ok := o.verify(cookie.Value); ok {
+					values, found, err := o.store.Load(id)
+					if err != nil {
+						w.Header().Set("Content-Type", "application/json")
+						w.WriteHeader(http.StatusInternalServerError)
+						w.Write([]byte(`{"error":"failed to load session"}`))
+						return
+					}
+					if found {
+						s.id = id
+						s.values = values
+					}
+				}
+			}
+
+			if s.id == "" {
+				s.id = newID()
+				s.values = make(map[string]any)
+			}
+
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), contextKey{}, s)))
+		})
+	}
+}
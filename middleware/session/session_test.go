@@ -0,0 +1,200 @@
+package session
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+var testKey = []byte("test-signing-key")
+
+// newTestHandler sets "user" from the query on /login and echoes it elsewhere
+func newTestHandler(t *testing.T, opts ...Option) http.Handler {
+	return New(append([]Option{WithSigningKey(testKey)}, opts...)...)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s, ok := Get(r.Context())
+		if !ok {
+			t.Fatal("Expected session in context")
+		}
+
+		if r.URL.Path == "/login" {
+			s.Set("user", r.URL.Query().Get("user"))
+			if err := Save(w, r); err != nil {
+				t.Fatalf("Failed to save session: %v", err)
+			}
+			return
+		}
+
+		user, _ := s.Get("user")
+		if user != nil {
+			w.Write([]byte(user.(string)))
+		}
+	}))
+}
+
+// sessionCookie returns the session cookie set on rr
+func sessionCookie(t *testing.T, rr *httptest.ResponseRecorder) *http.Cookie {
+	t.Helper()
+	for _, c := range rr.Result().Cookies() {
+		if c.Name == "session_id" {
+			return c
+		}
+	}
+	t.Fatal("Expected session cookie")
+	return nil
+}
+
+func TestSessionRoundTrip(t *testing.T) {
+	handler := newTestHandler(t)
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("GET", "/login?user=alice", nil))
+	cookie := sessionCookie(t, rr)
+
+	if !cookie.HttpOnly {
+		t.Error("Expected HttpOnly cookie")
+	}
+	if cookie.SameSite != http.SameSiteLaxMode {
+		t.Errorf("Expected SameSite=Lax, got %v", cookie.SameSite)
+	}
+	if cookie.MaxAge != int((24 * time.Hour).Seconds()) {
+		t.Errorf("Expected MaxAge of 24h, got %d", cookie.MaxAge)
+	}
+
+	req := httptest.NewRequest("GET", "/profile", nil)
+	req.AddCookie(cookie)
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if rr.Body.String() != "alice" {
+		t.Errorf("Expected session value 'alice', got %q", rr.Body.String())
+	}
+}
+
+func TestSessionTamperedCookie(t *testing.T) {
+	handler := newTestHandler(t)
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("GET", "/login?user=alice", nil))
+	cookie := sessionCookie(t, rr)
+
+	tests := []struct {
+		name  string
+		value string
+	}{
+		{"Modified signature", cookie.Value + "x"},
+		{"Unsigned ID", cookie.Value[:len(cookie.Value)-44]},
+		{"Missing signature", "not-signed"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/profile", nil)
+			req.AddCookie(&http.Cookie{Name: "session_id", Value: tt.value})
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			if rr.Body.String() != "" {
+				t.Errorf("Expected tampered cookie to start a new session, got %q", rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestSessionDifferentKey(t *testing.T) {
+	rr := httptest.NewRecorder()
+	newTestHandler(t).ServeHTTP(rr, httptest.NewRequest("GET", "/login?user=alice", nil))
+	cookie := sessionCookie(t, rr)
+
+	// A cookie signed with another key is rejected even if the ID exists
+	other := newTestHandler(t, WithSigningKey([]byte("other-key")))
+	req := httptest.NewRequest("GET", "/profile", nil)
+	req.AddCookie(cookie)
+	rr = httptest.NewRecorder()
+
+	other.ServeHTTP(rr, req)
+
+	if rr.Body.String() != "" {
+		t.Errorf("Expected cookie signed with another key to be rejected, got %q", rr.Body.String())
+	}
+}
+
+func TestSessionCookieOptions(t *testing.T) {
+	handler := newTestHandler(t,
+		WithCookieName("sid"),
+		WithMaxAge(time.Hour),
+		WithSecure(true),
+		WithSameSite(http.SameSiteStrictMode),
+	)
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("GET", "/login?user=alice", nil))
+
+	cookies := rr.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != "sid" {
+		t.Fatalf("Expected cookie named sid, got %v", cookies)
+	}
+	if !cookies[0].Secure {
+		t.Error("Expected Secure cookie")
+	}
+	if cookies[0].SameSite != http.SameSiteStrictMode {
+		t.Errorf("Expected SameSite=Strict, got %v", cookies[0].SameSite)
+	}
+	if cookies[0].MaxAge != 3600 {
+		t.Errorf("Expected MaxAge 3600, got %d", cookies[0].MaxAge)
+	}
+}
+
+func TestSessionDelete(t *testing.T) {
+	s := &Session{values: map[string]any{"a": 1}}
+	s.Delete("a")
+
+	if _, ok := s.Get("a"); ok {
+		t.Error("Expected value to be deleted")
+	}
+}
+
+func TestSaveWithoutMiddleware(t *testing.T) {
+	err := Save(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	if !errors.Is(err, ErrNoSession) {
+		t.Errorf("Expected ErrNoSession, got %v", err)
+	}
+}
+
+func TestMemoryStoreTTL(t *testing.T) {
+	store := NewMemoryStore()
+
+	if err := store.Save("id", map[string]any{"a": 1}, 50*time.Millisecond); err != nil {
+		t.Fatalf("Failed to save: %v", err)
+	}
+
+	values, ok, _ := store.Load("id")
+	if !ok || values["a"] != 1 {
+		t.Errorf("Expected stored values, got %v", values)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+
+	if _, ok, _ := store.Load("id"); ok {
+		t.Error("Expected session to expire")
+	}
+
+	store.Save("id", map[string]any{"a": 1}, time.Minute)
+	store.Delete("id")
+
+	if _, ok, _ := store.Load("id"); ok {
+		t.Error("Expected session to be deleted")
+	}
+}
+
+func TestSessionPanic(t *testing.T) {
+	defer func() {
+		if r := recover(); r == nil {
+			t.Error("Expected panic without signing key")
+		}
+	}()
+
+	New()
+}
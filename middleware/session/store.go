@@ -0,0 +1,99 @@
+package session
+
+import (
+	"sync"
+	"time"
+)
+
+// Store persists session values by session ID
+type Store interface {
+	// Load returns the values stored for id, or false if there are none
+	Load(id string) (map[string]any, bool, error)
+
+	// Save stores values for id, expiring after ttl
+	Save(id string, values map[string]any, ttl time.Duration) error
+
+	// Delete removes the values stored for id
+	Delete(id string) error
+}
+
+// memoryEntry is a session stored in memory
+type memoryEntry struct {
+	values  map[string]any
+	expires time.Time
+}
+
+// MemoryStore is an in-memory Store. Expired sessions are dropped when
+// loaded and swept periodically on Save.
+type MemoryStore struct {
+	mu        sync.Mutex
+	sessions  map[string]memoryEntry
+	lastSweep time.Time
+}
+
+// sweepInterval is the minimum time between sweeps of expired sessions
+const sweepInterval = time.Minute
+
+// NewMemoryStore creates an empty in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		sessions:  make(map[string]memoryEntry),
+		lastSweep: time.Now(),
+	}
+}
+
+// Load implements Store
+func (s *MemoryStore) Load(id string) (map[string]any, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.sessions[id]
+	if !ok {
+		return nil, false, nil
+	}
+	if time.Now().After(entry.expires) {
+		delete(s.sessions, id)
+		return nil, false, nil
+	}
+	return copyValues(entry.values), true, nil
+}
+
+// Save implements Store
+func (s *MemoryStore) Save(id string, values map[string]any, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(s.lastSweep) >= sweepInterval {
+		for key, entry := range s.sessions {
+			if now.After(entry.expires) {
+				delete(s.sessions, key)
+			}
+		}
+		s.lastSweep = now
+	}
+
+	s.sessions[id] = memoryEntry{
+		values:  copyValues(values),
+		expires: now.Add(ttl),
+	}
+	return nil
+}
+
+// Delete implements Store
+func (s *MemoryStore) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.sessions, id)
+	return nil
+}
+
+// copyValues returns a shallow copy of values
+func copyValues(values map[string]any) map[string]any {
+	c := make(map[string]any, len(values))
+	for k, v := range values {
+		c[k] = v
+	}
+	return c
+}
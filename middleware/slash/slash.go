@@ -0,0 +1,122 @@
+package slash
+
+import (
+	"net/http"
+	"strings"
+)
+
+// Option is slash option.
+type Option func(*options)
+
+// options defines the configuration for the trailing slash middlewares
+type options struct {
+	// Skipper leaves matching requests untouched. The root path "/" is
+	// never changed.
+	skipper func(r *http.Request) bool
+
+	// RedirectCode is the status used to redirect GET and HEAD requests.
+	// Other methods always use 308 Permanent Redirect to keep the body.
+	// Default: 301 Moved Permanently
+	redirectCode int
+}
+
+// WithSkipper sets a function to skip the middleware for a request
+func WithSkipper(f func(r *http.Request) bool) Option {
+	return func(o *options) {
+		o.skipper = f
+	}
+}
+
+// WithRedirectCode sets the status used to redirect GET and HEAD requests
+func WithRedirectCode(code int) Option {
+	return func(o *options) {
+		o.redirectCode = code
+	}
+}
+
+// newOptions applies opts over the defaults
+func newOptions(opts []Option) *options {
+	o := &options{
+		redirectCode: http.StatusMovedPermanently,
+	}
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	if o.redirectCode < 300 || o.redirectCode > 399 {
+		panic("slash redirect code must be a 3xx status")
+	}
+	return o
+}
+
+// hasTrailingSlash reports whether the request path should be changed
+func (o *options) hasTrailingSlash(r *http.Request) bool {
+	if r.URL.Path == "/" || !strings.HasSuffix(r.URL.Path, "/") {
+		return false
+	}
+	return o.skipper == nil || !o.skipper(r)
+}
+
+// trimPath removes trailing slashes, keeping at least "/"
+func trimPath(path string) string {
+	trimmed := strings.TrimRight(path, "/")
+	if trimmed == "" {
+		return "/"
+	}
+	return trimmed
+}
+
+// RedirectTrailingSlash returns a middleware redirecting paths with a
+// trailing slash to the canonical path without it
+func RedirectTrailingSlash(opts ...Option) func(http.Handler) http.Handler {
+	o := newOptions(opts)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !o.hasTrailingSlash(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			u := *r.URL
+			u.Path = trimPath(u.Path)
+			if u.RawPath != "" {
+				u.RawPath = trimPath(u.RawPath)
+			}
+
+			// Collapse leading slashes so "//host/" can't become a
+			// protocol-relative redirect to another host
+			target := u.RequestURI()
+			if strings.HasPrefix(target, "//") {
+				target = "/" + strings.TrimLeft(target, "/")
+			}
+
+			code := o.redirectCode
+			if r.Method != http.MethodGet && r.Method != http.MethodHead {
+				code = http.StatusPermanentRedirect
+			}
+
+			http.Redirect(w, r, target, code)
+		})
+	}
+}
+
+// StripTrailingSlash returns a middleware removing a trailing slash from the
+// request path in place, before routing
+func StripTrailingSlash(opts ...Option) func(http.Handler) http.Handler {
+	o := newOptions(opts)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if o.hasTrailingSlash(r) {
+				r.URL.Path = trimPath(r.URL.Path)
+				if r.URL.RawPath != "" {
+					r.URL.RawPath = trimPath(r.URL.RawPath)
+				}
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,117 @@
+package slash
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+var okHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	w.Write([]byte(r.URL.Path))
+})
+
+func TestRedirectTrailingSlash(t *testing.T) {
+	handler := RedirectTrailingSlash()(okHandler)
+
+	tests := []struct {
+		name             string
+		method           string
+		target           string
+		expectedStatus   int
+		expectedLocation string
+	}{
+		{"GET redirects", "GET", "/users/", http.StatusMovedPermanently, "/users"},
+		{"HEAD redirects", "HEAD", "/users/", http.StatusMovedPermanently, "/users"},
+		{"POST keeps method", "POST", "/users/", http.StatusPermanentRedirect, "/users"},
+		{"Query string is kept", "GET", "/users/?page=2", http.StatusMovedPermanently, "/users?page=2"},
+		{"Multiple slashes", "GET", "/users//", http.StatusMovedPermanently, "/users"},
+		{"No open redirect", "GET", "//evil.com/", http.StatusMovedPermanently, "/evil.com"},
+		{"Canonical path", "GET", "/users", http.StatusOK, ""},
+		{"Root path", "GET", "/", http.StatusOK, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.target, nil)
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d", tt.expectedStatus, rr.Code)
+			}
+			if rr.Header().Get("Location") != tt.expectedLocation {
+				t.Errorf("Expected Location %q, got %q", tt.expectedLocation, rr.Header().Get("Location"))
+			}
+		})
+	}
+}
+
+func TestRedirectTrailingSlashWithRedirectCode(t *testing.T) {
+	handler := RedirectTrailingSlash(WithRedirectCode(http.StatusFound))(okHandler)
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("GET", "/users/", nil))
+
+	if rr.Code != http.StatusFound {
+		t.Errorf("Expected status 302, got %d", rr.Code)
+	}
+}
+
+func TestStripTrailingSlash(t *testing.T) {
+	handler := StripTrailingSlash()(okHandler)
+
+	tests := []struct {
+		target   string
+		expected string
+	}{
+		{"/users/", "/users"},
+		{"/users", "/users"},
+		{"/", "/"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.target, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, httptest.NewRequest("GET", tt.target, nil))
+
+			if rr.Code != http.StatusOK {
+				t.Errorf("Expected status 200, got %d", rr.Code)
+			}
+			if rr.Body.String() != tt.expected {
+				t.Errorf("Expected path %s, got %s", tt.expected, rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestSlashWithSkipper(t *testing.T) {
+	skipper := WithSkipper(func(r *http.Request) bool {
+		return strings.HasPrefix(r.URL.Path, "/static/")
+	})
+
+	rr := httptest.NewRecorder()
+	RedirectTrailingSlash(skipper)(okHandler).ServeHTTP(rr, httptest.NewRequest("GET", "/static/", nil))
+
+	if rr.Code != http.StatusOK {
+		t.Errorf("Expected skipped redirect to reach handler, got %d", rr.Code)
+	}
+
+	rr = httptest.NewRecorder()
+	StripTrailingSlash(skipper)(okHandler).ServeHTTP(rr, httptest.NewRequest("GET", "/static/", nil))
+
+	if rr.Body.String() != "/static/" {
+		t.Errorf("Expected skipped path to be unchanged, got %s", rr.Body.String())
+	}
+}
+
+func TestSlashInvalidRedirectCodePanic(t *testing.T) {
+	defer func() {
+		if r := recover(); r == nil {
+			t.Error("Expected panic for non-3xx redirect code")
+		}
+	}()
+
+	RedirectTrailingSlash(WithRedirectCode(http.StatusOK))
+}
@@ -0,0 +1,114 @@
+package sse
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// lastEventIDHeader is the header sent by EventSource clients on reconnect
+const lastEventIDHeader = "Last-Event-ID"
+
+// Option is SSE option.
+type Option func(*options)
+
+// options defines the configuration for SSE middleware
+type options struct {
+	// ContextKey is the key used to store Last-Event-ID in context
+	// Default: lastEventID
+	contextKey string
+}
+
+// WithContextKey sets the context key for storing Last-Event-ID
+func WithContextKey(key string) Option {
+	return func(o *options) {
+		o.contextKey = key
+	}
+}
+
+// Event is a single server-sent event
+type Event struct {
+	// ID is the event ID; clients echo the last received ID in Last-Event-ID
+	ID string
+
+	// Event is the event type. Empty means the default "message" type
+	Event string
+
+	// Data is the event payload. Multi-line data is split into several data fields
+	Data string
+
+	// Retry tells the client how long to wait before reconnecting
+	Retry time.Duration
+}
+
+// WriteEvent writes e to w in the text/event-stream format and flushes it
+func WriteEvent(w http.ResponseWriter, e Event) error {
+	var b strings.Builder
+	if e.ID != "" {
+		b.WriteString("id: " + sanitize(e.ID) + "\n")
+	}
+	if e.Event != "" {
+		b.WriteString("event: " + sanitize(e.Event) + "\n")
+	}
+	if e.Retry > 0 {
+		b.WriteString("retry: " + strconv.FormatInt(e.Retry.Milliseconds(), 10) + "\n")
+	}
+	for _, line := range strings.Split(e.Data, "\n") {
+		b.WriteString("data: " + strings.TrimSuffix(line, "\r") + "\n")
+	}
+	b.WriteString("\n")
+
+	if _, err := w.Write([]byte(b.String())); err != nil {
+		return err
+	}
+
+	if f, ok := w.(http.Flusher); ok {
+		f.Flush()
+	}
+	return nil
+}
+
+// sanitize strips line breaks which would otherwise end the field early
+func sanitize(s string) string {
+	return strings.NewReplacer("\r", "", "\n", "").Replace(s)
+}
+
+// New returns a middleware that exposes the Last-Event-ID request header
+// through the request context so handlers can resume streams
+func New(opts ...Option) func(http.Handler) http.Handler {
+	o := &options{
+		contextKey: "lastEventID",
+	}
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if id := r.Header.Get(lastEventIDHeader); id != "" {
+				ctx := context.WithValue(r.Context(), contextKey(o.contextKey), id)
+				r = r.WithContext(ctx)
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// contextKey is the type used for context keys
+type contextKey string
+
+// GetLastEventID extracts the Last-Event-ID from context
+func GetLastEventID(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(contextKey("lastEventID")).(string)
+	return id, ok
+}
+
+// GetLastEventIDWithKey extracts the Last-Event-ID from context with custom key
+func GetLastEventIDWithKey(ctx context.Context, key string) (string, bool) {
+	id, ok := ctx.Value(contextKey(key)).(string)
+	return id, ok
+}
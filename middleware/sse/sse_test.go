@@ -0,0 +1,90 @@
+package sse
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestLastEventID(t *testing.T) {
+	var got string
+	var found bool
+
+	handler := New()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, found = GetLastEventID(r.Context())
+	}))
+
+	req := httptest.NewRequest("GET", "/events", nil)
+	req.Header.Set("Last-Event-ID", "42")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	if !found || got != "42" {
+		t.Errorf("Expected Last-Event-ID '42', got %q (found=%v)", got, found)
+	}
+}
+
+func TestLastEventIDMissing(t *testing.T) {
+	found := true
+
+	handler := New()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, found = GetLastEventID(r.Context())
+	}))
+
+	req := httptest.NewRequest("GET", "/events", nil)
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	if found {
+		t.Error("Expected no Last-Event-ID in context")
+	}
+}
+
+func TestLastEventIDWithContextKey(t *testing.T) {
+	var got string
+
+	handler := New(WithContextKey("resume"))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, _ = GetLastEventIDWithKey(r.Context(), "resume")
+	}))
+
+	req := httptest.NewRequest("GET", "/events", nil)
+	req.Header.Set("Last-Event-ID", "7")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	if got != "7" {
+		t.Errorf("Expected Last-Event-ID '7', got %q", got)
+	}
+}
+
+func TestWriteEvent(t *testing.T) {
+	rr := httptest.NewRecorder()
+
+	err := WriteEvent(rr, Event{
+		ID:    "43",
+		Event: "update",
+		Data:  "line1\nline2",
+		Retry: 3 * time.Second,
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := "id: 43\nevent: update\nretry: 3000\ndata: line1\ndata: line2\n\n"
+	if rr.Body.String() != expected {
+		t.Errorf("Expected %q, got %q", expected, rr.Body.String())
+	}
+
+	if !rr.Flushed {
+		t.Error("Expected event to be flushed")
+	}
+}
+
+func TestWriteEventSanitizesID(t *testing.T) {
+	rr := httptest.NewRecorder()
+
+	WriteEvent(rr, Event{ID: "1\nevent: injected", Data: "x"})
+
+	expected := "id: 1event: injected\ndata: x\n\n"
+	if rr.Body.String() != expected {
+		t.Errorf("Expected %q, got %q", expected, rr.Body.String())
+	}
+}
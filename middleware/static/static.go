@@ -0,0 +1,243 @@
+package static
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// Option is static option.
+type Option func(*options)
+
+// options defines the configuration for the static file middleware
+type options struct {
+	// Index is the file served for directory requests
+	// Default: "index.html"
+	index string
+
+	// Fallback is the file served, relative to the root, for GET and HEAD
+	// requests that match no file, e.g. a single-page app's index.html
+	// Default: "" (such requests are passed to the next handler)
+	fallback string
+}
+
+// WithIndex sets the file served for directory requests
+func WithIndex(name string) Option {
+	return func(o *options) {
+		o.index = name
+	}
+}
+
+// WithFallback sets the file served for requests that match no file, so
+// client-side routes of a single-page app load the app
+func WithFallback(name string) Option {
+	return func(o *options) {
+		o.fallback = name
+	}
+}
+
+// sidecars lists the precompressed sidecar files in order of preference on
+// equal q-values
+var sidecars = []struct {
+	encoding string
+	ext      string
+}{
+	{"br", ".br"},
+	{"gzip", ".gz"},
+}
+
+// acceptedEncodings returns the q-value of each coding in an Accept-Encoding
+// header value
+func acceptedEncodings(acceptEncoding string) map[string]float64 {
+	accepted := make(map[string]float64)
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		name, params, _ := strings.Cut(part, ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "x-gzip" {
+			name = "gzip"
+		}
+
+		q := 1.0
+		for _, param := range strings.Split(params, ";") {
+			key, value, ok := strings.Cut(strings.TrimSpace(param), "=")
+			if ok && strings.EqualFold(key, "q") {
+				if v, err := strconv.ParseFloat(value, 64); err == nil {
+					q = v
+				}
+			}
+		}
+		accepted[name] = max(accepted[name], q)
+	}
+	return accepted
+}
+
+// hasDotDot reports whether a URL path contains a ".." segment
+func hasDotDot(p string) bool {
+	for _, segment := range strings.FieldsFunc(p, func(r rune) bool { return r == '/' || r == '\\' }) {
+		if segment == ".." {
+			return true
+		}
+	}
+	return false
+}
+
+// server serves files from a root directory
+type server struct {
+	root *os.Root
+	o    *options
+}
+
+// stat returns the info of a regular file, or false if name is missing or
+// not a regular file
+func (s *server) stat(name string) (fs.FileInfo, bool) {
+	info, err := s.root.Stat(name)
+	if err != nil || !info.Mode().IsRegular() {
+		return nil, false
+	}
+	return info, true
+}
+
+// resolve maps a cleaned URL path to a regular file, using the index file
+// for directories
+func (s *server) resolve(urlPath string) (string, bool) {
+	name := strings.TrimPrefix(urlPath, "/")
+	if name == "" {
+		name = "."
+	}
+
+	info, err := s.root.Stat(name)
+	if err != nil {
+		return "", false
+	}
+	if info.IsDir() {
+		name = path.Join(name, s.o.index)
+	}
+	_, ok := s.stat(name)
+	return name, ok
+}
+
+// serve writes name, preferring a precompressed sidecar the client accepts
+func (s *server) serve(w http.ResponseWriter, r *http.Request, name string) {
+	accepted := acceptedEncodings(r.Header.Get("Accept-Encoding"))
+
+	serveName, encoding := name, ""
+	var best float64
+	for _, sidecar := range sidecars {
+		if _, ok := s.stat(name + sidecar.ext); !ok {
+			continue
+		}
+		// Responses differ by Accept-Encoding whenever a sidecar exists
+		w.Header().Set("Vary", "Accept-Encoding")
+		if q := accepted[sidecar.encoding]; q > best {
+			serveName, encoding, best = name+sidecar.ext, sidecar.encoding, q
+		}
+	}
+
+	if encoding != "" {
+		if ctype := s.contentType(name); ctype != "" {
+			w.Header().Set("Content-Type", ctype)
+		}
+		w.Header().Set("Content-Encoding", encoding)
+	}
+
+	f, err := s.root.Open(serveName)
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, "failed to open file")
+		return
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, "failed to open file")
+		return
+	}
+
+	// ServeContent detects the Content-Type from the original name unless
+	// it was set above
+	http.ServeContent(w, r, name, info.ModTime(), f)
+}
+
+// contentType returns the Content-Type of the original file, by extension
+// or by sniffing it if it exists
+func (s *server) contentType(name string) string {
+	if ctype := mime.TypeByExtension(path.Ext(name)); ctype != "" {
+		return ctype
+	}
+
+	f, err := s.root.Open(name)
+	if err != nil {
+		return "application/octet-stream"
+	}
+	defer f.Close()
+
+	var buf [512]byte
+	n, err := io.ReadFull(f, buf[:])
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return "application/octet-stream"
+	}
+	return http.DetectContentType(buf[:n])
+}
+
+// jsonError writes a JSON error response
+func jsonError(w http.ResponseWriter, code int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	w.Write([]byte(`{"error":"` + message + `"}`))
+}
+
+// New returns a middleware that serves GET and HEAD requests from files
+// under root, preferring precompressed .br and .gz sidecar files when the
+// client accepts them. Requests matching no file are passed to the next
+// handler unless a fallback is set. Paths can't escape root, including
+// through symlinks. It panics if root can't be opened.
+func New(root string, opts ...Option) func(http.Handler) http.Handler {
+	o := &options{
+		index: "index.html",
+	}
+
+	for _, opt := range opts {
+		opt(o)
+	}
+	o.fallback = strings.TrimPrefix(o.fallback, "/")
+
+	dir, err := os.OpenRoot(root)
+	if err != nil {
+		panic("static: failed to open root: " + err.Error())
+	}
+	s := &server{root: dir, o: o}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodGet && r.Method != http.MethodHead {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if hasDotDot(r.URL.Path) {
+				jsonError(w, http.StatusBadRequest, "invalid path")
+				return
+			}
+
+			if name, ok := s.resolve(path.Clean("/" + r.URL.Path)); ok {
+				s.serve(w, r, name)
+				return
+			}
+
+			if o.fallback != "" {
+				if _, ok := s.stat(o.fallback); ok {
+					s.serve(w, r, o.fallback)
+					return
+				}
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
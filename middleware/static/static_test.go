@@ -0,0 +1,229 @@
+package static
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// newRoot creates a site directory with files and returns its path
+func newRoot(t *testing.T, files map[string]string) string {
+	t.Helper()
+	root := filepath.Join(t.TempDir(), "site")
+	for name, content := range files {
+		p := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+	}
+	return root
+}
+
+func notFoundHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("next"))
+	})
+}
+
+func TestStaticSidecarNegotiation(t *testing.T) {
+	root := newRoot(t, map[string]string{
+		"app.js":    "plain",
+		"app.js.gz": "gzipped",
+		"app.js.br": "brotli",
+		"style.css": "plain css",
+	})
+	handler := New(root)(notFoundHandler())
+
+	tests := []struct {
+		name           string
+		path           string
+		acceptEncoding string
+		body           string
+		encoding       string
+		vary           string
+	}{
+		{"No Accept-Encoding", "/app.js", "", "plain", "", "Accept-Encoding"},
+		{"gzip", "/app.js", "gzip", "gzipped", "gzip", "Accept-Encoding"},
+		{"br preferred on tie", "/app.js", "gzip, br", "brotli", "br", "Accept-Encoding"},
+		{"Higher q wins", "/app.js", "br;q=0.5, gzip", "gzipped", "gzip", "Accept-Encoding"},
+		{"Refused codings", "/app.js", "gzip;q=0, br;q=0", "plain", "", "Accept-Encoding"},
+		{"No sidecar", "/style.css", "gzip, br", "plain css", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", tt.path, nil)
+			if tt.acceptEncoding != "" {
+				req.Header.Set("Accept-Encoding", tt.acceptEncoding)
+			}
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != http.StatusOK {
+				t.Fatalf("Expected status 200, got %d", rr.Code)
+			}
+			if rr.Body.String() != tt.body {
+				t.Errorf("Expected body %q, got %q", tt.body, rr.Body.String())
+			}
+			if got := rr.Header().Get("Content-Encoding"); got != tt.encoding {
+				t.Errorf("Expected Content-Encoding %q, got %q", tt.encoding, got)
+			}
+			if got := rr.Header().Get("Vary"); got != tt.vary {
+				t.Errorf("Expected Vary %q, got %q", tt.vary, got)
+			}
+			if ctype := rr.Header().Get("Content-Type"); !strings.Contains(ctype, "css") && !strings.Contains(ctype, "javascript") {
+				t.Errorf("Expected Content-Type of the original file, got %q", ctype)
+			}
+		})
+	}
+}
+
+func TestStaticIndex(t *testing.T) {
+	root := newRoot(t, map[string]string{
+		"index.html":      "home",
+		"docs/start.html": "docs",
+	})
+
+	tests := []struct {
+		name     string
+		opts     []Option
+		path     string
+		expected int
+		body     string
+	}{
+		{"Root index", nil, "/", http.StatusOK, "home"},
+		{"Directory without index", nil, "/docs/", http.StatusNotFound, "next"},
+		{"Custom index", []Option{WithIndex("start.html")}, "/docs/", http.StatusOK, "docs"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := New(root, tt.opts...)(notFoundHandler())
+
+			req := httptest.NewRequest("GET", tt.path, nil)
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != tt.expected {
+				t.Errorf("Expected status %d, got %d", tt.expected, rr.Code)
+			}
+			if rr.Body.String() != tt.body {
+				t.Errorf("Expected body %q, got %q", tt.body, rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestStaticFallback(t *testing.T) {
+	root := newRoot(t, map[string]string{
+		"index.html":    "app",
+		"index.html.gz": "gzipped app",
+		"app.js":        "js",
+	})
+
+	tests := []struct {
+		name     string
+		opts     []Option
+		method   string
+		path     string
+		expected int
+		body     string
+	}{
+		{"Client route", []Option{WithFallback("index.html")}, "GET", "/users/42", http.StatusOK, "app"},
+		{"Existing file", []Option{WithFallback("/index.html")}, "GET", "/app.js", http.StatusOK, "js"},
+		{"No fallback", nil, "GET", "/users/42", http.StatusNotFound, "next"},
+		{"Non-GET passes through", []Option{WithFallback("index.html")}, "POST", "/users/42", http.StatusNotFound, "next"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := New(root, tt.opts...)(notFoundHandler())
+
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != tt.expected {
+				t.Errorf("Expected status %d, got %d", tt.expected, rr.Code)
+			}
+			if rr.Body.String() != tt.body {
+				t.Errorf("Expected body %q, got %q", tt.body, rr.Body.String())
+			}
+		})
+	}
+
+	// The fallback also uses sidecars
+	handler := New(root, WithFallback("index.html"))(notFoundHandler())
+	req := httptest.NewRequest("GET", "/users/42", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	if rr.Body.String() != "gzipped app" || rr.Header().Get("Content-Encoding") != "gzip" {
+		t.Errorf("Expected gzip sidecar for fallback, got %q with encoding %q", rr.Body.String(), rr.Header().Get("Content-Encoding"))
+	}
+	if !strings.HasPrefix(rr.Header().Get("Content-Type"), "text/html") {
+		t.Errorf("Expected text/html Content-Type, got %q", rr.Header().Get("Content-Type"))
+	}
+}
+
+func TestStaticTraversal(t *testing.T) {
+	root := newRoot(t, map[string]string{"index.html": "home"})
+	secret := filepath.Join(filepath.Dir(root), "secret.txt")
+	if err := os.WriteFile(secret, []byte("secret"), 0o644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	handler := New(root, WithFallback("index.html"))(notFoundHandler())
+
+	for _, p := range []string{"/../secret.txt", "/%2e%2e/secret.txt", "/docs/../../secret.txt"} {
+		t.Run(p, func(t *testing.T) {
+			req := httptest.NewRequest("GET", p, nil)
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != http.StatusBadRequest {
+				t.Errorf("Expected status 400, got %d", rr.Code)
+			}
+			if strings.Contains(rr.Body.String(), "secret") {
+				t.Error("Expected file outside root not to be served")
+			}
+		})
+	}
+
+	// Symlinks can't escape the root either
+	if err := os.Symlink(secret, filepath.Join(root, "link.txt")); err != nil {
+		t.Skipf("Symlinks unsupported: %v", err)
+	}
+
+	req := httptest.NewRequest("GET", "/link.txt", nil)
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	if strings.Contains(rr.Body.String(), "secret") {
+		t.Error("Expected symlink outside root not to be followed")
+	}
+}
+
+func TestStaticInvalidRootPanic(t *testing.T) {
+	defer func() {
+		if r := recover(); r == nil {
+			t.Error("Expected panic for missing root")
+		}
+	}()
+
+	New(filepath.Join(t.TempDir(), "missing"))
+}
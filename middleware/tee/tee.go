@@ -0,0 +1,108 @@
+package tee
+
+import (
+	"io"
+	"net/http"
+	"sync"
+	"sync/atomic"
+)
+
+// Option is tee option.
+type Option func(*options)
+
+// options defines the configuration for the tee middleware
+type options struct {
+	// MaxBytes caps the number of body bytes copied to the sink per request.
+	// The handler still reads the whole body.
+	// Default: 0 (no cap)
+	maxBytes int64
+}
+
+// WithMaxBytes sets the maximum number of body bytes copied to the sink per
+// request
+func WithMaxBytes(n int64) Option {
+	return func(o *options) {
+		o.maxBytes = n
+	}
+}
+
+// teeBody copies the bytes read from the request body to the sink. readMu
+// serializes reads of the body, and mu keeps the sink from being written by
+// two goroutines reading the same body. mu is taken before readMu is
+// released, so the sink receives chunks in the order they were read. Close
+// takes neither lock, so it can abort a blocked Read.
+type teeBody struct {
+	readMu    sync.Mutex
+	mu        sync.Mutex
+	body      io.ReadCloser
+	sink      io.Writer
+	remaining int64 // bytes left to copy, negative for no cap
+	closed    atomic.Bool
+}
+
+// Read implements io.Reader
+func (b *teeBody) Read(p []byte) (int, error) {
+	b.readMu.Lock()
+	n, err := b.body.Read(p)
+	b.mu.Lock()
+	b.readMu.Unlock()
+	defer b.mu.Unlock()
+
+	if n > 0 && b.remaining != 0 && !b.closed.Load() {
+		chunk := p[:n]
+		if b.remaining > 0 && int64(len(chunk)) > b.remaining {
+			chunk = chunk[:b.remaining]
+		}
+		// A failing sink stops the copy without failing the handler's read
+		if _, werr := b.sink.Write(chunk); werr != nil {
+			b.remaining = 0
+		} else if b.remaining > 0 {
+			b.remaining -= int64(len(chunk))
+		}
+	}
+	return n, err
+}
+
+// Close implements io.Closer
+func (b *teeBody) Close() error {
+	b.closed.Store(true)
+	return b.body.Close()
+}
+
+// New returns a middleware that copies request bodies to sink as the handler
+// reads them. Unlike the dump middleware nothing is buffered: the sink
+// receives exactly the bytes the handler read, in order, and sink errors
+// never reach the handler. Writes for one request are serialized, but
+// concurrent requests write to sink concurrently, so sink must be safe for
+// concurrent use when requests overlap.
+func New(sink io.Writer, opts ...Option) func(http.Handler) http.Handler {
+	o := &options{}
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	if sink == nil {
+		panic("tee sink must not be nil")
+	}
+	if o.maxBytes < 0 {
+		panic("max bytes must not be negative")
+	}
+
+	remaining := o.maxBytes
+	if remaining == 0 {
+		remaining = -1
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Body == nil || r.Body == http.NoBody {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			r.Body = &teeBody{body: r.Body, sink: sink, remaining: remaining}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,217 @@
+package tee
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// readHandler reads up to n bytes of the body, or all of it if n < 0, and
+// responds with what it read
+func readHandler(n int64) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body io.Reader = r.Body
+		if n >= 0 {
+			body = io.LimitReader(r.Body, n)
+		}
+		data, err := io.ReadAll(body)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Write(data)
+	})
+}
+
+func TestTee(t *testing.T) {
+	payload := strings.Repeat("0123456789", 1000)
+
+	tests := []struct {
+		name     string
+		opts     []Option
+		read     int64
+		expected string
+	}{
+		{"Whole body", nil, -1, payload},
+		{"Partial read", nil, 1234, payload[:1234]},
+		{"Capped", []Option{WithMaxBytes(100)}, -1, payload[:100]},
+		{"Cap above read", []Option{WithMaxBytes(5000)}, 1234, payload[:1234]},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var sink bytes.Buffer
+			handler := New(&sink, tt.opts...)(readHandler(tt.read))
+
+			req := httptest.NewRequest("POST", "/audit", strings.NewReader(payload))
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			// The handler always sees its reads in full
+			if tt.read < 0 && rr.Body.String() != payload {
+				t.Errorf("Expected handler to read the whole body, got %d bytes", rr.Body.Len())
+			}
+			if sink.String() != tt.expected {
+				t.Errorf("Expected sink to receive %d bytes, got %d", len(tt.expected), sink.Len())
+			}
+		})
+	}
+}
+
+// failingWriter fails every write
+type failingWriter struct{ writes int }
+
+func (w *failingWriter) Write(p []byte) (int, error) {
+	w.writes++
+	return 0, errors.New("sink unavailable")
+}
+
+func TestTeeSinkErrorDoesNotAffectHandler(t *testing.T) {
+	sink := &failingWriter{}
+	handler := New(sink)(readHandler(-1))
+
+	payload := strings.Repeat("x", 100000)
+	req := httptest.NewRequest("POST", "/audit", strings.NewReader(payload))
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK || rr.Body.String() != payload {
+		t.Errorf("Expected handler to read the body despite the sink error, got status %d", rr.Code)
+	}
+	if sink.writes != 1 {
+		t.Errorf("Expected copying to stop after the first failed write, got %d writes", sink.writes)
+	}
+}
+
+// orderedWriter fails the test if written concurrently
+type orderedWriter struct {
+	t       *testing.T
+	mu      sync.Mutex
+	writing bool
+	buf     bytes.Buffer
+}
+
+func (w *orderedWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	if w.writing {
+		w.t.Error("Expected sink not to be written concurrently")
+	}
+	w.writing = true
+	w.mu.Unlock()
+
+	n, _ := w.buf.Write(p)
+
+	w.mu.Lock()
+	w.writing = false
+	w.mu.Unlock()
+	return n, nil
+}
+
+func TestTeeConcurrentReads(t *testing.T) {
+	sink := &orderedWriter{t: t}
+	payload := strings.Repeat("y", 64<<10)
+
+	handler := New(sink)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Two goroutines drain the same body in small reads
+		var wg sync.WaitGroup
+		for range 2 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				buf := make([]byte, 7)
+				for {
+					if _, err := r.Body.Read(buf); err != nil {
+						return
+					}
+				}
+			}()
+		}
+		wg.Wait()
+	}))
+
+	req := httptest.NewRequest("POST", "/audit", strings.NewReader(payload))
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	if sink.buf.String() != payload {
+		t.Errorf("Expected sink to receive %d bytes, got %d", len(payload), sink.buf.Len())
+	}
+}
+
+// blockingBody blocks every Read until it is closed
+type blockingBody struct {
+	reading chan struct{}
+	done    chan struct{}
+	once    sync.Once
+}
+
+func (b *blockingBody) Read(p []byte) (int, error) {
+	b.reading <- struct{}{}
+	<-b.done
+	return 0, io.ErrUnexpectedEOF
+}
+
+func (b *blockingBody) Close() error {
+	b.once.Do(func() { close(b.done) })
+	return nil
+}
+
+func TestTeeCloseDuringBlockedRead(t *testing.T) {
+	var sink bytes.Buffer
+	body := &blockingBody{reading: make(chan struct{}), done: make(chan struct{})}
+
+	handler := New(&sink)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		go io.ReadAll(r.Body)
+		<-body.reading
+
+		// Aborting the stalled read must not wait for it to return
+		closed := make(chan struct{})
+		go func() {
+			r.Body.Close()
+			close(closed)
+		}()
+		select {
+		case <-closed:
+		case <-time.After(2 * time.Second):
+			t.Error("Expected Close not to block on a pending Read")
+		}
+	}))
+
+	req := httptest.NewRequest("POST", "/audit", nil)
+	req.Body = body
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+}
+
+func TestTeeNoBody(t *testing.T) {
+	var sink bytes.Buffer
+	called := false
+	handler := New(&sink)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		if r.Body != http.NoBody {
+			t.Error("Expected body to be left unchanged")
+		}
+	}))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+
+	if !called || sink.Len() != 0 {
+		t.Errorf("Expected handler called with nothing teed, got called=%v sink=%d bytes", called, sink.Len())
+	}
+}
+
+func TestTeeNilSinkPanic(t *testing.T) {
+	defer func() {
+		if r := recover(); r == nil {
+			t.Error("Expected panic for nil sink")
+		}
+	}()
+
+	New(nil)
+}
@@ -0,0 +1,252 @@
+package webhookverify
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"hash"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrMissingSignature = errors.New("signature is missing")
+	ErrMissingTimestamp = errors.New("timestamp is missing")
+	ErrInvalidTimestamp = errors.New("timestamp is invalid")
+	ErrStaleTimestamp   = errors.New("timestamp is outside the tolerance window")
+	ErrInvalidSignature = errors.New("signature is invalid")
+	ErrBodyTooLarge     = errors.New("request body too large")
+	ErrReadBody         = errors.New("failed to read body")
+)
+
+// Option is webhook verify option.
+type Option func(*options)
+
+// options defines the configuration for webhook verify middleware
+type options struct {
+	// Secrets is the list of secrets accepted for signature verification
+	// The first secret is the one passed to New, others allow rotation
+	secrets [][]byte
+
+	// SignatureHeader is the header carrying the hex encoded signature
+	// Default: X-Signature
+	signatureHeader string
+
+	// TimestampHeader is the header carrying the unix timestamp in seconds
+	// Default: X-Timestamp
+	timestampHeader string
+
+	// Tolerance is the maximum allowed age of the timestamp. It must be
+	// positive, as it is what rejects replayed requests.
+	// Default: 5 minutes
+	tolerance time.Duration
+
+	// Hash is the hash function used for the HMAC
+	// Default: sha256.New
+	hash func() hash.Hash
+
+	// ErrorHandler defines a function which is executed when verification fails
+	// Optional. Default value returns 401 Unauthorized, or 413 and 400 for
+	// ErrBodyTooLarge and ErrReadBody
+	errorHandler func(http.ResponseWriter, *http.Request, error)
+
+	// MaxBytes caps the size of the body read for verification. Larger bodies
+	// are rejected with 413.
+	// Default: 1MB
+	maxBytes int64
+}
+
+// WithSecrets adds secrets accepted during rotation
+func WithSecrets(secrets ...[]byte) Option {
+	return func(o *options) {
+		o.secrets = append(o.secrets, secrets...)
+	}
+}
+
+// WithSignatureHeader sets the signature header name
+func WithSignatureHeader(header string) Option {
+	return func(o *options) {
+		o.signatureHeader = header
+	}
+}
+
+// WithTimestampHeader sets the timestamp header name
+func WithTimestampHeader(header string) Option {
+	return func(o *options) {
+		o.timestampHeader = header
+	}
+}
+
+// WithTolerance sets the timestamp freshness window. It must be positive.
+func WithTolerance(d time.Duration) Option {
+	return func(o *options) {
+		o.tolerance = d
+	}
+}
+
+// WithHash sets the hash function used for the HMAC
+func WithHash(h func() hash.Hash) Option {
+	return func(o *options) {
+		o.hash = h
+	}
+}
+
+// WithErrorHandler sets the error handler
+func WithErrorHandler(h func(http.ResponseWriter, *http.Request, error)) Option {
+	return func(o *options) {
+		o.errorHandler = h
+	}
+}
+
+// WithMaxBytes sets the maximum body size read for verification
+func WithMaxBytes(n int64) Option {
+	return func(o *options) {
+		o.maxBytes = n
+	}
+}
+
+// Sign computes the hex encoded signature over the timestamp and body.
+// The signed payload is "<timestamp>.<body>".
+func Sign(secret []byte, timestamp string, body []byte, h func() hash.Hash) string {
+	if h == nil {
+		h = sha256.New
+	}
+	return hex.EncodeToString(computeMAC(h, secret, timestamp, body))
+}
+
+// computeMAC returns the HMAC over the timestamp and body
+func computeMAC(h func() hash.Hash, secret []byte, timestamp string, body []byte) []byte {
+	mac := hmac.New(h, secret)
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return mac.Sum(nil)
+}
+
+// jsonError writes a JSON error response
+func jsonError(w http.ResponseWriter, code int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	w.Write([]byte(`{"error":"` + message + `"}`))
+}
+
+// New returns a middleware that verifies HMAC signed requests
+func New(secret []byte, opts ...Option) func(http.Handler) http.Handler {
+	o := &options{
+		secrets:         [][]byte{secret},
+		signatureHeader: "X-Signature",
+		timestampHeader: "X-Timestamp",
+		tolerance:       5 * time.Minute,
+		hash:            sha256.New,
+		maxBytes:        1 << 20, // 1MB
+	}
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	// Validate secrets
+	for _, s := range o.secrets {
+		if len(s) == 0 {
+			panic("webhook secret is empty")
+		}
+	}
+	if o.tolerance <= 0 {
+		panic("tolerance must be greater than 0")
+	}
+	if o.maxBytes <= 0 {
+		panic("max bytes must be greater than 0")
+	}
+
+	reject := func(w http.ResponseWriter, r *http.Request, err error) {
+		if o.errorHandler != nil {
+			o.errorHandler(w, r, err)
+			return
+		}
+
+		status := http.StatusUnauthorized
+		switch err {
+		case ErrBodyTooLarge:
+			status = http.StatusRequestEntityTooLarge
+		case ErrReadBody:
+			status = http.StatusBadRequest
+		}
+		jsonError(w, status, err.Error())
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			signature := r.Header.Get(o.signatureHeader)
+			if signature == "" {
+				reject(w, r, ErrMissingSignature)
+				return
+			}
+			// Accept the common "sha256=<hex>" form
+			if i := strings.IndexByte(signature, '='); i >= 0 {
+				signature = signature[i+1:]
+			}
+			expected, err := hex.DecodeString(signature)
+			if err != nil {
+				reject(w, r, ErrInvalidSignature)
+				return
+			}
+
+			timestamp := r.Header.Get(o.timestampHeader)
+			if timestamp == "" {
+				reject(w, r, ErrMissingTimestamp)
+				return
+			}
+			ts, err := strconv.ParseInt(timestamp, 10, 64)
+			if err != nil {
+				reject(w, r, ErrInvalidTimestamp)
+				return
+			}
+
+			// Reject stale or future timestamps to prevent replays
+			age := time.Since(time.Unix(ts, 0))
+			if age < 0 {
+				age = -age
+			}
+			if age > o.tolerance {
+				reject(w, r, ErrStaleTimestamp)
+				return
+			}
+
+			// Read the raw body and restore it for the next handler
+			var body []byte
+			if r.Body != nil {
+				body, err = io.ReadAll(http.MaxBytesReader(w, r.Body, o.maxBytes))
+				r.Body.Close()
+				if err != nil {
+					var maxBytesErr *http.MaxBytesError
+					if errors.As(err, &maxBytesErr) {
+						reject(w, r, ErrBodyTooLarge)
+						return
+					}
+					reject(w, r, ErrReadBody)
+					return
+				}
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			valid := false
+			for _, secret := range o.secrets {
+				if hmac.Equal(computeMAC(o.hash, secret, timestamp, body), expected) {
+					valid = true
+					break
+				}
+			}
+			if !valid {
+				reject(w, r, ErrInvalidSignature)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,209 @@
+package webhookverify
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newSignedRequest(secret []byte, body string, ts time.Time) *http.Request {
+	timestamp := strconv.FormatInt(ts.Unix(), 10)
+	req := httptest.NewRequest("POST", "/webhook", strings.NewReader(body))
+	req.Header.Set("X-Timestamp", timestamp)
+	req.Header.Set("X-Signature", "sha256="+Sign(secret, timestamp, []byte(body), nil))
+	return req
+}
+
+func echoHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.Write(body)
+	})
+}
+
+func TestWebhookVerifyValidSignature(t *testing.T) {
+	secret := []byte("test-secret")
+	handler := New(secret)(echoHandler())
+
+	body := `{"event":"push"}`
+	req := newSignedRequest(secret, body, time.Now())
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", rr.Code)
+	}
+
+	// Body must be restored for the next handler
+	if rr.Body.String() != body {
+		t.Errorf("Expected body %q, got %q", body, rr.Body.String())
+	}
+}
+
+func TestWebhookVerifyTamperedBody(t *testing.T) {
+	secret := []byte("test-secret")
+	handler := New(secret)(echoHandler())
+
+	req := newSignedRequest(secret, `{"amount":1}`, time.Now())
+	req.Body = io.NopCloser(strings.NewReader(`{"amount":1000}`))
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status 401, got %d", rr.Code)
+	}
+}
+
+func TestWebhookVerifyStaleTimestamp(t *testing.T) {
+	secret := []byte("test-secret")
+	handler := New(secret, WithTolerance(time.Minute))(echoHandler())
+
+	req := newSignedRequest(secret, `{"event":"push"}`, time.Now().Add(-10*time.Minute))
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status 401, got %d", rr.Code)
+	}
+}
+
+func TestWebhookVerifyMissingSignature(t *testing.T) {
+	handler := New([]byte("test-secret"))(echoHandler())
+
+	req := httptest.NewRequest("POST", "/webhook", strings.NewReader("{}"))
+	req.Header.Set("X-Timestamp", strconv.FormatInt(time.Now().Unix(), 10))
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status 401, got %d", rr.Code)
+	}
+}
+
+func TestWebhookVerifySecretRotation(t *testing.T) {
+	oldSecret := []byte("old-secret")
+	newSecret := []byte("new-secret")
+	handler := New(newSecret, WithSecrets(oldSecret))(echoHandler())
+
+	for _, secret := range [][]byte{oldSecret, newSecret} {
+		req := newSignedRequest(secret, `{"event":"push"}`, time.Now())
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		if rr.Code != http.StatusOK {
+			t.Errorf("Secret %s: Expected status 200, got %d", secret, rr.Code)
+		}
+	}
+
+	req := newSignedRequest([]byte("unknown-secret"), `{"event":"push"}`, time.Now())
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status 401, got %d", rr.Code)
+	}
+}
+
+func TestWebhookVerifyMaxBytes(t *testing.T) {
+	secret := []byte("webhook-secret")
+	handler := New(secret, WithMaxBytes(16))(echoHandler())
+
+	tests := []struct {
+		name     string
+		body     string
+		expected int
+	}{
+		{"Within limit", `{"event":"a"}`, http.StatusOK},
+		{"Over limit", `{"event":"push","ref":"main"}`, http.StatusRequestEntityTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := newSignedRequest(secret, tt.body, time.Now())
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != tt.expected {
+				t.Errorf("Expected status %d, got %d", tt.expected, rr.Code)
+			}
+		})
+	}
+}
+
+func TestWebhookVerifyJSONErrors(t *testing.T) {
+	secret := []byte("webhook-secret")
+	handler := New(secret, WithMaxBytes(4))(echoHandler())
+
+	req := newSignedRequest(secret, `{"event":"push"}`, time.Now())
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if ct := rr.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Expected Content-Type application/json, got %q", ct)
+	}
+	if expected := `{"error":"request body too large"}`; rr.Body.String() != expected {
+		t.Errorf("Expected body %s, got %s", expected, rr.Body.String())
+	}
+
+	req = httptest.NewRequest("POST", "/webhook", strings.NewReader("{}"))
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if expected := `{"error":"signature is missing"}`; rr.Body.String() != expected {
+		t.Errorf("Expected body %s, got %s", expected, rr.Body.String())
+	}
+}
+
+func TestWebhookVerifyBodyErrorHandler(t *testing.T) {
+	secret := []byte("webhook-secret")
+	var got error
+	handler := New(secret,
+		WithMaxBytes(4),
+		WithErrorHandler(func(w http.ResponseWriter, r *http.Request, err error) {
+			got = err
+			w.WriteHeader(http.StatusTeapot)
+		}),
+	)(echoHandler())
+
+	req := newSignedRequest(secret, `{"event":"push"}`, time.Now())
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if got != ErrBodyTooLarge {
+		t.Errorf("Expected error handler to receive ErrBodyTooLarge, got %v", got)
+	}
+	if rr.Code != http.StatusTeapot {
+		t.Errorf("Expected status 418, got %d", rr.Code)
+	}
+}
+
+func TestWebhookVerifyPanics(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []Option
+	}{
+		{"Zero max bytes", []Option{WithMaxBytes(0)}},
+		{"Zero tolerance", []Option{WithTolerance(0)}},
+		{"Negative tolerance", []Option{WithTolerance(-time.Minute)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				if r := recover(); r == nil {
+					t.Error("Expected panic")
+				}
+			}()
+
+			New([]byte("webhook-secret"), tt.opts...)
+		})
+	}
+}